@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mijailr/swaggergo/pkg/swaggerhub"
+)
+
+type applyOptions struct {
+	Dir         string `flag:"dir" default:"registry"`
+	Owner       string `flag:"owner"`
+	Prune       bool   `flag:"prune"`
+	Plan        bool   `flag:"plan"`
+	AutoApprove bool   `flag:"auto-approve"`
+	Output      string `flag:"output" default:"text"`
+	connectionOptions
+}
+
+// applyChange is a step of the plan, run in order.
+type applyChange struct {
+	Description string
+	run         func() error
+}
+
+// changesResult is what apply and retention apply print with --output json:
+// the changes of the plan, and whether they were applied.
+type changesResult struct {
+	Status  string   `json:"status"`
+	Target  string   `json:"target"`
+	Changes []string `json:"changes"`
+}
+
+// applyCommand reconciles SwaggerHub with a directory written by export
+// gitops: definitions that are new or differ are published, settings and
+// default versions are updated and, with --prune, versions and APIs missing
+// from the directory are deleted. The plan is shown first and needs to be
+// confirmed unless --auto-approve is given.
+func applyCommand(args []string) {
+	options := applyOptions{}
+	parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+	environment := options.useConnection(new(string))
+	if options.SwaggerHubAccessToken == "" {
+		exitAndError("missing access-token")
+	}
+
+	manifest, err := loadRegistryManifest(options.Dir)
+	if err != nil {
+		exitAndError(fmt.Errorf("can't read the registry in %s: %w", options.Dir, err))
+	}
+	for _, owner := range []string{options.Owner, environment.Owner} {
+		if owner != "" {
+			manifest.Owner = owner
+			break
+		}
+	}
+	if manifest.Owner == "" {
+		exitAndError("missing owner")
+	}
+
+	// a cached copy would hide what SwaggerHub stores now
+	swaggerHubCache = nil
+	changes := planRegistry(swaggerHubUrls, "apis", "swagger.yaml", manifest.Apis, manifest, &options)
+	changes = append(changes, planRegistry(swaggerHubDomainUrls(), "domains", "domain.yaml", manifest.Domains, manifest, &options)...)
+
+	if len(changes) == 0 {
+		log.Printf("%s already matches %s", manifest.Owner, options.Dir)
+		printChangesResult(manifest.Owner, changes, false)
+		return
+	}
+	applied := runChanges(manifest.Owner, changes, options.Plan, options.AutoApprove)
+	if applied {
+		log.Printf("%s matches %s", manifest.Owner, options.Dir)
+	}
+	printChangesResult(manifest.Owner, changes, applied)
+}
+
+// runChanges prints the plan and runs it once confirmed, returning false when
+// only the plan was asked for.
+func runChanges(target string, changes []applyChange, plan bool, autoApprove bool) bool {
+	fmt.Fprintf(os.Stderr, "%d changes to %s:\n", len(changes), target)
+	for _, change := range changes {
+		fmt.Fprintf(os.Stderr, "  %s\n", change.Description)
+	}
+	if plan {
+		return false
+	}
+	if !autoApprove {
+		if !isTerminal(os.Stdin) {
+			exitAndError("--auto-approve is needed when the changes can't be confirmed on a terminal")
+		}
+		if !confirmed("apply these changes?") {
+			exitAndError("nothing was applied")
+		}
+	}
+
+	for i, change := range changes {
+		if err := change.run(); err != nil {
+			exitAndError(fmt.Errorf("%s failed after %d of %d changes: %w", change.Description, i, len(changes), err))
+		}
+		log.Print(change.Description)
+	}
+	return true
+}
+
+// printChangesResult prints the changes with --output json, as planned or
+// applied, or unchanged when there are none.
+func printChangesResult(target string, changes []applyChange, applied bool) {
+	if !outputJson {
+		return
+	}
+	result := changesResult{Status: "unchanged", Target: target, Changes: []string{}}
+	for _, change := range changes {
+		result.Changes = append(result.Changes, change.Description)
+	}
+	if len(changes) > 0 {
+		result.Status = "planned"
+		if applied {
+			result.Status = "applied"
+		}
+	}
+	printJson(result)
+}
+
+// confirmed asks a yes or no question on the terminal.
+func confirmed(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.ToLower(strings.TrimSpace(answer)) == "y"
+}
+
+// planRegistry compares the APIs or domains of the manifest with the ones of
+// the owner.
+func planRegistry(urls []string, kind string, definitionName string, entries []registryEntry, manifest *registryManifest, options *applyOptions) []applyChange {
+	token := options.SwaggerHubAccessToken
+	names, err := listOwner(urls, manifest.Owner, token)
+	if err != nil {
+		exitAndError(fmt.Errorf("can't list the %s of %s: %w", kind, manifest.Owner, err))
+	}
+	remoteNames := map[string]bool{}
+	for _, name := range names {
+		remoteNames[name] = true
+	}
+
+	var changes []applyChange
+	change := func(description string, method string, path string, body []byte) {
+		changes = append(changes, applyChange{description, func() error {
+			_, err := requestSwaggerHub(urls, token, method, path, body)
+			return err
+		}})
+	}
+	setting := func(value interface{}) []byte {
+		body, _ := json.Marshal(value)
+		return body
+	}
+
+	localNames := map[string]bool{}
+	for _, entry := range entries {
+		localNames[entry.Name] = true
+		item := manifest.Owner + "/" + entry.Name
+		remote := map[string]swaggerhub.Version{}
+		remoteDefault := ""
+		if remoteNames[entry.Name] {
+			versions, err := listVersions(urls, item, token)
+			if err != nil {
+				exitAndError(fmt.Errorf("can't list the versions of %s: %w", item, err))
+			}
+			for _, version := range versions {
+				remote[version.Version] = version
+				if version.Default {
+					remoteDefault = version.Version
+				}
+			}
+		}
+
+		localVersions := map[string]bool{}
+		for _, version := range entry.Versions {
+			localVersions[version.Version] = true
+			label := fmt.Sprintf("%s/%s %s", kind, entry.Name, version.Version)
+			path := filepath.Join(options.Dir, filepath.FromSlash(version.File))
+			definition, err := ioutil.ReadFile(path)
+			if err != nil {
+				exitAndError(fmt.Sprintf("can't read the file %s", path))
+			}
+
+			query := neturl.Values{}
+			query.Set("version", version.Version)
+			query.Set("isPrivate", fmt.Sprint(version.Private))
+			current, exists := remote[version.Version]
+			if !exists {
+				change("+ create "+label, "POST", fmt.Sprintf("%s?%s", item, query.Encode()), definition)
+				if version.Published {
+					change("~ publish "+label, "PUT", fmt.Sprintf("%s/%s/settings/lifecycle", item, version.Version), setting(map[string]bool{"published": true}))
+				}
+				continue
+			}
+
+			differs, err := definitionDiffers(urls, fmt.Sprintf("%s/%s/%s", item, version.Version, definitionName), token, path, definition)
+			if err != nil {
+				exitAndError(fmt.Errorf("can't compare %s: %w", label, err))
+			}
+			if differs {
+				query.Set("force", "true")
+				change("~ update "+label, "POST", fmt.Sprintf("%s?%s", item, query.Encode()), definition)
+			} else if current.Private != version.Private {
+				change(fmt.Sprintf("~ make %s %s", label, visibilityName(version.Private)), "PUT", fmt.Sprintf("%s/%s/settings/private", item, version.Version), setting(map[string]bool{"private": version.Private}))
+			}
+			if current.Published != version.Published {
+				action := "publish"
+				if !version.Published {
+					action = "unpublish"
+				}
+				change(fmt.Sprintf("~ %s %s", action, label), "PUT", fmt.Sprintf("%s/%s/settings/lifecycle", item, version.Version), setting(map[string]bool{"published": version.Published}))
+			}
+		}
+
+		if entry.Default != "" && entry.Default != remoteDefault {
+			change(fmt.Sprintf("~ make %s the default version of %s/%s", entry.Default, kind, entry.Name), "PUT", item+"/settings/default", setting(map[string]string{"version": entry.Default}))
+		}
+		if options.Prune {
+			for _, version := range sortedVersions(remote) {
+				if !localVersions[version] {
+					change(fmt.Sprintf("- delete %s/%s %s", kind, entry.Name, version), "DELETE", fmt.Sprintf("%s/%s", item, version), nil)
+				}
+			}
+		}
+	}
+
+	if options.Prune {
+		for _, name := range names {
+			if !localNames[name] {
+				change(fmt.Sprintf("- delete %s/%s", kind, name), "DELETE", manifest.Owner+"/"+name, nil)
+			}
+		}
+	}
+	return changes
+}
+
+// definitionDiffers compares the stored definition with the local one the
+// way verify does.
+func definitionDiffers(urls []string, path string, accessToken string, localPath string, definition []byte) (bool, error) {
+	published, err := getFromSwaggerHubAt(urls, path, accessToken)
+	if err != nil {
+		return false, err
+	}
+	local, err := parseOpenApiDocument(localPath, definition)
+	if err != nil {
+		return false, err
+	}
+	remote, err := parseOpenApiDocument(path, published)
+	if err != nil {
+		return false, err
+	}
+	return len(semanticDifferences(withoutMockServers(nodeValue(local.Root)), withoutMockServers(nodeValue(remote.Root)), "")) > 0, nil
+}
+
+func visibilityName(private bool) string {
+	if private {
+		return "private"
+	}
+	return "public"
+}
+
+func sortedVersions(versions map[string]swaggerhub.Version) []string {
+	var sorted []string
+	for version := range versions {
+		sorted = append(sorted, version)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
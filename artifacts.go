@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// artifactMetadata is stored next to every archived definition.
+type artifactMetadata struct {
+	Api         string `json:"api"`
+	Version     string `json:"version"`
+	Sha256      string `json:"sha256"`
+	MediaType   string `json:"mediaType"`
+	File        string `json:"file"`
+	PublishedAt string `json:"publishedAt"`
+}
+
+// storeArtifact archives the published bytes under
+// <prefix>/<owner>/<api>/<version>/<sha256><ext>, with the metadata in a
+// .json object beside them. Keys are content addressed so an archived
+// definition is never overwritten by a different one.
+func storeArtifact(store *neturl.URL, openApiPath string, openApi []byte, mediaType string, options *commandLineOptions) error {
+	digest := sha256.Sum256(openApi)
+	metadata := artifactMetadata{
+		Api:         options.SwaggerHubApi,
+		Version:     publicationVersion(openApi, options),
+		Sha256:      hex.EncodeToString(digest[:]),
+		MediaType:   mediaType,
+		File:        filepath.Base(openApiPath),
+		PublishedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	metadataJson, _ := json.MarshalIndent(metadata, "", "  ")
+
+	key := path.Join(strings.Trim(store.Path, "/"), options.SwaggerHubApi, metadata.Version, metadata.Sha256)
+	objects := []struct {
+		key         string
+		content     []byte
+		contentType string
+	}{
+		{key + filepath.Ext(openApiPath), openApi, mediaType},
+		{key + ".json", metadataJson, "application/json"},
+	}
+
+	for _, object := range objects {
+		var err error
+		switch store.Scheme {
+		case "s3":
+			err = putS3Object(store.Host, object.key, object.content, object.contentType)
+		case "gs":
+			err = putGcsObject(store.Host, object.key, object.content, object.contentType)
+		}
+		if err != nil {
+			return fmt.Errorf("can't store %s://%s/%s: %w", store.Scheme, store.Host, object.key, err)
+		}
+	}
+
+	log.Printf("archived into %s://%s/%s", store.Scheme, store.Host, objects[0].key)
+	return nil
+}
+
+// artifactStore parses --artifact-store and checks the credentials its
+// scheme needs are there, before the upload.
+func artifactStore(location string) (*neturl.URL, error) {
+	store, err := neturl.Parse(location)
+	if err != nil || store.Host == "" {
+		return nil, fmt.Errorf("invalid artifact store %s, use s3://bucket/prefix or gs://bucket/prefix", location)
+	}
+	switch store.Scheme {
+	case "s3":
+		if os.Getenv("AWS_ACCESS_KEY_ID") == "" || os.Getenv("AWS_SECRET_ACCESS_KEY") == "" {
+			return nil, fmt.Errorf("the artifact store %s needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY", location)
+		}
+	case "gs":
+		if os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN") == "" {
+			return nil, fmt.Errorf("the artifact store %s needs GOOGLE_OAUTH_ACCESS_TOKEN", location)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported artifact store %s, use s3:// or gs://", store.Scheme)
+	}
+	return store, nil
+}
+
+// putS3Object uploads with a SigV4 signed request using the standard AWS
+// environment variables. AWS_ENDPOINT_URL points to S3 compatible stores.
+func putS3Object(bucket string, key string, content []byte, contentType string) error {
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	objectUrl := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, escapeObjectKey(key))
+	if endpoint := os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
+		objectUrl = fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(endpoint, "/"), bucket, escapeObjectKey(key))
+	}
+
+	request, err := http.NewRequest("PUT", objectUrl, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", contentType)
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		request.Header.Set("X-Amz-Security-Token", token)
+	}
+	digest := sha256.Sum256(content)
+	signS3Request(request, hex.EncodeToString(digest[:]), region, accessKey, secretKey, time.Now())
+
+	return putObject(request)
+}
+
+// signS3Request adds a SigV4 Authorization header covering the host and every
+// header already set on the request.
+func signS3Request(request *http.Request, payloadHash string, region string, accessKey string, secretKey string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": request.URL.Host}
+	for name, values := range request.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	var names []string
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		request.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, region)
+	canonicalDigest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := fmt.Sprintf("AWS4-HMAC-SHA256\n%s\n%s\n%s", amzDate, scope, hex.EncodeToString(canonicalDigest[:]))
+
+	signingKey := []byte("AWS4" + secretKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		signingKey = hmacSha256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSha256(signingKey, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, scope, signedHeaders, signature))
+}
+
+// putGcsObject uploads with an OAuth access token, for example the one given
+// by `gcloud auth print-access-token`.
+func putGcsObject(bucket string, key string, content []byte, contentType string) error {
+	token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+	request, err := http.NewRequest("PUT", fmt.Sprintf("https://storage.googleapis.com/%s/%s", bucket, escapeObjectKey(key)), bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Content-Type", contentType)
+
+	return putObject(request)
+}
+
+func putObject(request *http.Request) error {
+	client := client()
+	resp, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// escapeObjectKey encodes every byte but the unreserved ones, as SigV4
+// expects in the canonical request.
+func escapeObjectKey(key string) string {
+	var escaped strings.Builder
+	for _, b := range []byte(key) {
+		if b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z' || b >= '0' && b <= '9' || strings.IndexByte("-_.~/", b) >= 0 {
+			escaped.WriteByte(b)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
+
+func hmacSha256(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	neturl "net/url"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// asyncApiRules check the structure AsyncAPI requires, as validationRules
+// do for OpenAPI. They run before every publication of an AsyncAPI
+// definition.
+var asyncApiRules = []lintRule{
+	{Name: "asyncapi-required-fields", Severity: "error", Check: checkAsyncApiRequiredFields},
+	{Name: "asyncapi-channels", Severity: "error", Check: checkAsyncApiChannels},
+	{Name: "asyncapi-operations", Severity: "error", Check: checkAsyncApiOperations},
+	{Name: "asyncapi-refs", Severity: "error", Check: checkRefs},
+}
+
+// asyncApi2ChannelFields are the fields of a channel of AsyncAPI 2.
+var asyncApi2ChannelFields = []string{"$ref", "description", "servers", "subscribe", "publish", "parameters", "bindings"}
+
+// asyncApiHandler handles AsyncAPI by major version, 2 or 3. SwaggerHub
+// takes it on the same endpoint as OpenAPI, without an OAS level.
+type asyncApiHandler struct {
+	version string
+}
+
+func (handler asyncApiHandler) name() string {
+	return "AsyncAPI " + handler.version
+}
+
+func (handler asyncApiHandler) specType() string {
+	return "asyncapi"
+}
+
+func (handler asyncApiHandler) detect(document *openApiDocument) bool {
+	return strings.HasPrefix(scalarValue(document.lookup("asyncapi")), handler.version+".")
+}
+
+func (handler asyncApiHandler) validation() []lintRule {
+	return asyncApiRules
+}
+
+func (handler asyncApiHandler) rules(options *commandLineOptions) []lintRule {
+	if options.CheckLinks {
+		return linkRules
+	}
+	return nil
+}
+
+func (handler asyncApiHandler) publishQuery(document *openApiDocument, options *commandLineOptions) neturl.Values {
+	if options.Oas != "" {
+		log.Printf("%s is %s, the OAS level %s of --oas or the config is left out", document.Path, handler.name(), options.Oas)
+	}
+	return neturl.Values{}
+}
+
+func (document *openApiDocument) isAsyncApi() bool {
+	return mappingValue(document.Root, "asyncapi") != nil
+}
+
+func (document *openApiDocument) isAsyncApi2() bool {
+	return strings.HasPrefix(scalarValue(document.lookup("asyncapi")), "2.")
+}
+
+func checkAsyncApiRequiredFields(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	var findings []lintFinding
+	missing := func(node *yaml.Node, pointer string, fields ...string) {
+		for _, field := range fields {
+			if mappingValue(node, field) == nil {
+				findings = append(findings, lintFinding{
+					Message: fmt.Sprintf("%s is missing", strings.TrimPrefix(strings.ReplaceAll(pointer, "/", ".")+"."+field, "#.")),
+					Pointer: pointer,
+					Line:    node.Line,
+				})
+			}
+		}
+	}
+
+	missing(document.Root, "#", "info")
+	if document.isAsyncApi2() {
+		// channels are optional since AsyncAPI 3
+		missing(document.Root, "#", "channels")
+	}
+	if info := document.lookup("info"); info != nil {
+		missing(info, "#/info", "title", "version")
+	}
+	return findings
+}
+
+// checkAsyncApiChannels checks that every parameter of the address of a
+// channel is defined, and that AsyncAPI 2 channels only hold their fields,
+// as a get or post there is an OpenAPI habit.
+func checkAsyncApiChannels(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	var findings []lintFinding
+	eachMapping(document.lookup("channels"), func(name *yaml.Node, channel *yaml.Node) {
+		report := func(line int, format string, args ...interface{}) {
+			findings = append(findings, lintFinding{Message: fmt.Sprintf(format, args...), Pointer: joinPointer("channels", name.Value), Line: line})
+		}
+		if mappingValue(channel, "$ref") != nil {
+			return
+		}
+
+		address := name.Value
+		if !document.isAsyncApi2() {
+			address = scalarValue(mappingValue(channel, "address"))
+		} else {
+			eachMapping(channel, func(key *yaml.Node, value *yaml.Node) {
+				if !containsString(asyncApi2ChannelFields, key.Value) && !strings.HasPrefix(key.Value, "x-") {
+					report(key.Line, "the channel %s has the unknown field %s", name.Value, key.Value)
+				}
+			})
+		}
+		parameters := mappingValue(channel, "parameters")
+		for _, placeholder := range pathTemplate.FindAllStringSubmatch(address, -1) {
+			if mappingValue(parameters, placeholder[1]) == nil {
+				report(name.Line, "the channel %s has no parameter for {%s}", name.Value, placeholder[1])
+			}
+		}
+	})
+	return findings
+}
+
+// checkAsyncApiOperations checks that the operation ids of AsyncAPI 2 are
+// unique, and that AsyncAPI 3 operations send or receive on a channel.
+func checkAsyncApiOperations(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	var findings []lintFinding
+	if document.isAsyncApi2() {
+		seen := map[string]bool{}
+		eachMapping(document.lookup("channels"), func(name *yaml.Node, channel *yaml.Node) {
+			for _, action := range []string{"publish", "subscribe"} {
+				id := mappingValue(mappingValue(channel, action), "operationId")
+				if id == nil {
+					continue
+				}
+				if seen[id.Value] {
+					findings = append(findings, lintFinding{
+						Message: fmt.Sprintf("the operationId %s is used more than once", id.Value),
+						Pointer: joinPointer("channels", name.Value, action, "operationId"),
+						Line:    id.Line,
+					})
+				}
+				seen[id.Value] = true
+			}
+		})
+		return findings
+	}
+
+	eachMapping(document.lookup("operations"), func(name *yaml.Node, operation *yaml.Node) {
+		report := func(format string, args ...interface{}) {
+			findings = append(findings, lintFinding{Message: fmt.Sprintf(format, args...), Pointer: joinPointer("operations", name.Value), Line: name.Line})
+		}
+		if mappingValue(operation, "$ref") != nil {
+			return
+		}
+		if action := scalarValue(mappingValue(operation, "action")); action != "send" && action != "receive" {
+			report("the operation %s needs an action, send or receive", name.Value)
+		}
+		if channel := mappingValue(operation, "channel"); channel == nil || mappingValue(channel, "$ref") == nil {
+			report("the operation %s needs a channel, as a $ref", name.Value)
+		}
+	})
+	return findings
+}
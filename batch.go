@@ -0,0 +1,325 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mijailr/swaggergo/pkg/swaggerhub"
+)
+
+// publishMapping maps the definitions matching Files, a glob where **
+// stands for any number of directories, to an API. {name} in Api is the
+// file name without its extension.
+type publishMapping struct {
+	Files string `yaml:"files"`
+	Api   string `yaml:"api"`
+}
+
+// batchPublication is a definition of a batch, with the API it goes to and
+// how its publication ended.
+type batchPublication struct {
+	Path   string          `json:"file"`
+	Api    string          `json:"api"`
+	Result string          `json:"result"`
+	Output json.RawMessage `json:"output,omitempty"`
+}
+
+// publishBatch publishes several definitions, given as files or glob
+// patterns. They're published in-process with the same options and one
+// client, so the connections, the retries and the circuit breaker are shared,
+// but a failure only ends its own publication and a summary is printed at
+// the end. The API of a definition comes from its x-swaggerhub.api, the
+// publish mappings of the config or --api (or --api-from-spec), in that
+// order. --concurrency publishes that many at the same time.
+func publishBatch(patterns []string, options *commandLineOptions) {
+	paths, err := expandDefinitionPatterns(patterns)
+	if err != nil {
+		exitAndError(err)
+	}
+	config, err := loadProjectConfig(options.Config)
+	if err != nil {
+		exitAndError(err)
+	}
+	concurrency, err := strconv.Atoi(options.Concurrency)
+	if err != nil || concurrency < 1 {
+		exitAndError(fmt.Sprintf("invalid concurrency %s", options.Concurrency))
+	}
+
+	// set up once, for every publication
+	useProjectConnections(options.Config, httpConfig{Resolve: splitList(options.Resolve), DnsServer: options.DnsServer, CaCert: options.CaCert, ClientCert: options.ClientCert, ClientKey: options.ClientKey, Proxy: options.Proxy, Timeout: options.Timeout, Verbose: options.Verbose})
+	useAccessTokenSource(options.AccessTokenFile, options.AccessTokenStdin, &options.SwaggerHubAccessToken)
+	environment := useEnvironment(options.Config, options.Environment, options.Profile, options.RegistryUrl, new(string), &options.SwaggerHubAccessToken)
+	registry := publishRegistry(options)
+
+	var publications []batchPublication
+	for _, definitionPath := range paths {
+		api, err := definitionApi(definitionPath, config.Publish, options.SwaggerHubApi, options.ApiFromSpec)
+		if err != nil {
+			exitAndError(err)
+		}
+		if environment.Owner != "" && !strings.Contains(api, "/") {
+			api = environment.Owner + "/" + api
+		}
+		if options.ApiFromSpec && !strings.Contains(api, "/") {
+			exitAndError("--api-from-spec needs an owner, give it with --api or the environment or profile")
+		}
+		publications = append(publications, batchPublication{Path: definitionPath, Api: api})
+	}
+
+	var mutex sync.Mutex
+	var wait sync.WaitGroup
+	queue := make(chan int)
+	failed := 0
+	// the exit codes of the failed publications, the batch exits with theirs
+	// when they all failed the same way
+	exitCodes := map[int]bool{}
+	progress := newProgress("publishing", len(publications))
+	logs := log.Writer()
+	log.SetOutput(progress.logTo(logs))
+	for i := 0; i < concurrency; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for i := range queue {
+				entry := &publications[i]
+				if interrupted() {
+					mutex.Lock()
+					entry.Result = "skipped"
+					failed++
+					mutex.Unlock()
+					progress.finish(entry.Path, "skipped")
+					continue
+				}
+				log.Printf("publishing %s (%d of %d) to %s", entry.Path, i+1, len(publications), entry.Api)
+				progress.start(entry.Path)
+				code, problem, output := publishInBatch(entry.Path, entry.Api, environment, registry, *options)
+				progress.finish(entry.Path, problem)
+
+				mutex.Lock()
+				if outputJson {
+					entry.Output = output
+				}
+				entry.Result = "published"
+				if options.DryRun {
+					entry.Result = "checked"
+				}
+				if code != exitOk {
+					entry.Result = "failed"
+					if interrupted() {
+						entry.Result = "interrupted"
+					}
+					failed++
+					exitCodes[code] = true
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	for i := range publications {
+		queue <- i
+	}
+	close(queue)
+	wait.Wait()
+	log.SetOutput(logs)
+	progress.close()
+
+	if outputJson {
+		status := "published"
+		if failed > 0 {
+			status = "failed"
+		}
+		printJsonResult(batchResult{Status: status, Failed: failed, Publications: publications}, batchExitCode(failed, exitCodes), fmt.Sprintf("%d of %d publications failed", failed, len(publications)))
+		return
+	}
+
+	rows := [][]string{{"FILE", "API", "RESULT"}}
+	for _, entry := range publications {
+		rows = append(rows, []string{entry.Path, entry.Api, entry.Result})
+	}
+	fmt.Println()
+	printTable(rows)
+	if failed > 0 {
+		exitWithCode(batchExitCode(failed, exitCodes), fmt.Sprintf("%d of %d publications failed", failed, len(publications)))
+	}
+}
+
+// publishInBatch publishes a definition of a batch with its own copy of the
+// options. It returns the exit code of the publication, the error it failed
+// with, which is reported here instead of ending the run, and its JSON
+// result or error.
+func publishInBatch(definitionPath string, api string, environment *environmentConfig, registry *swaggerhub.Client, options commandLineOptions) (int, string, json.RawMessage) {
+	options.SwaggerHubApi, options.ApiFromSpec, options.inBatch = api, false, true
+	fields := map[string]interface{}{}
+	var hooks []func(message interface{})
+	current := &publication{registry: registry, logFields: fields, errorFields: fields, hooks: &hooks}
+
+	openApi, err := readPublication(definitionPath, &options)
+	if err == nil {
+		var result publishResult
+		if result, err = publishDefinition(definitionPath, openApi, environment, &options, current); err == nil {
+			output, _ := json.Marshal(result)
+			return exitOk, "", output
+		}
+	}
+
+	for _, hook := range hooks {
+		hook(err)
+	}
+	if logJson {
+		writeLogEntry(os.Stderr, "error", err.Error(), fields)
+	}
+	status, code := "failed", exitCode(err)
+	if interrupted() {
+		status, code = "interrupted", interruptedExitCode()
+	}
+	document := map[string]interface{}{}
+	for name, value := range fields {
+		document[name] = value
+	}
+	document["status"] = status
+	document["error"] = err.Error()
+	output, _ := json.Marshal(document)
+	return code, err.Error(), output
+}
+
+func batchExitCode(failed int, exitCodes map[int]bool) int {
+	if failed == 0 {
+		return exitOk
+	}
+	if len(exitCodes) == 1 {
+		for code := range exitCodes {
+			return code
+		}
+	}
+	return exitFailure
+}
+
+// batchResult is the JSON output of a batch, with the one of every
+// publication.
+type batchResult struct {
+	Status       string             `json:"status"`
+	Failed       int                `json:"failed"`
+	Publications []batchPublication `json:"publications"`
+}
+
+// definitionApi finds the API a definition of a batch is published to. With
+// --api-from-spec the flag only gives the owner, if any.
+func definitionApi(definitionPath string, mappings []publishMapping, flagApi string, fromSpec bool) (string, error) {
+	document, err := readOpenApiDocument(definitionPath)
+	if err != nil {
+		return "", err
+	}
+	if api := scalarValue(document.lookup("x-swaggerhub", "api")); api != "" {
+		return api, nil
+	}
+	for _, mapping := range mappings {
+		if matchPattern(mapping.Files, definitionPath) {
+			name := strings.TrimSuffix(filepath.Base(definitionPath), filepath.Ext(definitionPath))
+			return strings.ReplaceAll(mapping.Api, "{name}", name), nil
+		}
+	}
+	if fromSpec {
+		return specApi(definitionPath, scalarValue(document.lookup("info", "title")), flagApi)
+	}
+	if flagApi != "" {
+		return flagApi, nil
+	}
+	return "", fmt.Errorf("there is no API for %s, set x-swaggerhub.api in it, add it to the publish mappings of the config or use --api", definitionPath)
+}
+
+// expandDefinitionPatterns returns the files matching the patterns, sorted
+// and without repeating any. Arguments without a pattern are kept as they
+// are.
+func expandDefinitionPatterns(patterns []string) ([]string, error) {
+	seen := map[string]bool{}
+	var paths []string
+	for _, pattern := range patterns {
+		if pattern == stdinPath || isDefinitionUrl(pattern) {
+			return nil, fmt.Errorf("%s can't be published with other definitions, a batch needs files", pattern)
+		}
+		matches := []string{pattern}
+		if strings.ContainsAny(pattern, "*?[") {
+			var err error
+			if matches, err = globFiles(pattern); err != nil {
+				return nil, err
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no definition matches %s", pattern)
+			}
+		}
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				paths = append(paths, match)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// globFiles walks the directory a pattern starts with, looking for the
+// files it matches.
+func globFiles(pattern string) ([]string, error) {
+	segments := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
+	root := []string{}
+	for _, segment := range segments {
+		if strings.ContainsAny(segment, "*?[") {
+			break
+		}
+		root = append(root, segment)
+	}
+	start := "."
+	if len(root) > 0 {
+		start = filepath.FromSlash(strings.Join(root, "/"))
+	}
+
+	var matches []string
+	err := filepath.Walk(start, func(walked string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() && matchPattern(pattern, walked) {
+			matches = append(matches, walked)
+		}
+		return nil
+	})
+	sort.Strings(matches)
+	return matches, err
+}
+
+// matchPattern matches a path with a glob where ** stands for any number of
+// directories.
+func matchPattern(pattern string, name string) bool {
+	pattern = filepath.ToSlash(filepath.Clean(pattern))
+	name = filepath.ToSlash(filepath.Clean(name))
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern []string, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
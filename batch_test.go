@@ -0,0 +1,107 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"specs/*.yml", "specs/pets.yml", true},
+		{"specs/*.yml", "specs/v1/pets.yml", false},
+		{"specs/**/*.yml", "specs/pets.yml", true},
+		{"specs/**/*.yml", "specs/v1/pets.yml", true},
+		{"specs/**/*.yml", "specs/v1/internal/pets.yml", true},
+		{"specs/**/*.yml", "other/pets.yml", false},
+		{"**/openapi.yml", "openapi.yml", true},
+		{"**/openapi.yml", "services/orders/openapi.yml", true},
+		{"services/**", "services/orders/openapi.yml", true},
+		{"services/*/openapi.yml", "services/orders/openapi.yml", true},
+		{"services/*/openapi.yml", "services/orders/v2/openapi.yml", false},
+		{"specs/pet?.yml", "specs/pets.yml", true},
+		{"specs/[a-m]*.yml", "specs/pets.yml", false},
+		{"./specs/*.yml", "specs/pets.yml", true},
+		{"specs/*.yml", "specs/pets.yaml", false},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern+" "+test.name, func(t *testing.T) {
+			if got := matchPattern(test.pattern, test.name); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGlobFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"specs/pets.yml", "specs/v1/orders.yml", "specs/v1/internal/users.yml", "specs/v1/notes.txt", "other/pets.yml"} {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte("openapi: 3.0.0\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	working, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(working)
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"specs/*.yml", []string{"specs/pets.yml"}},
+		{"specs/**/*.yml", []string{"specs/pets.yml", "specs/v1/internal/users.yml", "specs/v1/orders.yml"}},
+		{"**/pets.yml", []string{"other/pets.yml", "specs/pets.yml"}},
+		{"specs/v1/*", []string{"specs/v1/notes.txt", "specs/v1/orders.yml"}},
+		{"missing/**/*.yml", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			matches, err := globFiles(test.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, match := range matches {
+				got = append(got, filepath.ToSlash(match))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBatchExitCode(t *testing.T) {
+	tests := []struct {
+		name      string
+		failed    int
+		exitCodes map[int]bool
+		want      int
+	}{
+		{"all published", 0, map[int]bool{}, exitOk},
+		{"one way", 2, map[int]bool{exitInvalid: true}, exitInvalid},
+		{"several ways", 2, map[int]bool{exitInvalid: true, exitNetwork: true}, exitFailure},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := batchExitCode(test.failed, test.exitCodes); got != test.want {
+				t.Errorf("got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// circuitBreakerConfig is swaggerhub.circuitBreaker in the config.
+type circuitBreakerConfig struct {
+	Failures    int    `yaml:"failures"`
+	Cooldown    string `yaml:"cooldown"`
+	MaxCooldown string `yaml:"maxCooldown"`
+}
+
+// circuitBreaker stops sending requests to a SwaggerHub node after
+// consecutive failures. Once the cooldown is over a single request goes
+// through (half open): a success closes the circuit, a failure opens it again
+// for twice as long, up to maxCooldown.
+type circuitBreaker struct {
+	mutex       sync.Mutex
+	failures    int
+	threshold   int
+	cooldown    time.Duration
+	minCooldown time.Duration
+	maxCooldown time.Duration
+	openUntil   time.Time
+	probing     bool
+}
+
+var (
+	circuitBreakers      = map[string]*circuitBreaker{}
+	circuitBreakersMutex sync.Mutex
+	circuitSettings      = circuitBreakerConfig{Failures: 5, Cooldown: "30s", MaxCooldown: "5m"}
+)
+
+func configureCircuitBreakers(config circuitBreakerConfig) error {
+	if config.Failures == 0 {
+		config.Failures = circuitSettings.Failures
+	}
+	if config.Cooldown == "" {
+		config.Cooldown = circuitSettings.Cooldown
+	}
+	if config.MaxCooldown == "" {
+		config.MaxCooldown = circuitSettings.MaxCooldown
+	}
+	for _, duration := range []string{config.Cooldown, config.MaxCooldown} {
+		if _, err := time.ParseDuration(duration); err != nil {
+			return fmt.Errorf("invalid swaggerhub.circuitBreaker duration %s", duration)
+		}
+	}
+	circuitSettings = config
+	return nil
+}
+
+func breakerFor(baseUrl string) *circuitBreaker {
+	circuitBreakersMutex.Lock()
+	defer circuitBreakersMutex.Unlock()
+
+	breaker, ok := circuitBreakers[baseUrl]
+	if !ok {
+		cooldown, _ := time.ParseDuration(circuitSettings.Cooldown)
+		maxCooldown, _ := time.ParseDuration(circuitSettings.MaxCooldown)
+		breaker = &circuitBreaker{threshold: circuitSettings.Failures, cooldown: cooldown, minCooldown: cooldown, maxCooldown: maxCooldown}
+		circuitBreakers[baseUrl] = breaker
+	}
+	return breaker
+}
+
+// allow tells whether a request can be sent now, and when the circuit will
+// let the next one through otherwise.
+func (breaker *circuitBreaker) allow() (bool, time.Time) {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	if breaker.failures < breaker.threshold {
+		return true, time.Time{}
+	}
+	if time.Now().Before(breaker.openUntil) || breaker.probing {
+		return false, breaker.openUntil
+	}
+	breaker.probing = true
+	return true, time.Time{}
+}
+
+func (breaker *circuitBreaker) record(success bool) {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	if success {
+		breaker.failures = 0
+		breaker.cooldown = breaker.minCooldown
+		breaker.probing = false
+		return
+	}
+
+	breaker.failures++
+	if breaker.probing {
+		breaker.probing = false
+		breaker.cooldown *= 2
+		if breaker.cooldown > breaker.maxCooldown {
+			breaker.cooldown = breaker.maxCooldown
+		}
+	}
+	if breaker.failures >= breaker.threshold {
+		breaker.openUntil = time.Now().Add(breaker.cooldown)
+	}
+}
+
+// swaggerHubBreakers hands the circuit breakers of each base URL to the
+// swaggerhub client.
+type swaggerHubBreakers struct{}
+
+func (swaggerHubBreakers) Allow(baseUrl string) error {
+	if allowed, retryAt := breakerFor(baseUrl).allow(); !allowed {
+		return fmt.Errorf("the circuit to %s is open after repeated failures, retrying after %s", baseUrl, retryAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (swaggerHubBreakers) Record(baseUrl string, success bool) {
+	breakerFor(baseUrl).record(success)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mijailr/swaggergo/pkg/swaggerhub"
+)
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	breaker := &circuitBreaker{threshold: 3, cooldown: time.Hour, minCooldown: time.Hour, maxCooldown: 2 * time.Hour}
+	for i := 0; i < 3; i++ {
+		if allowed, _ := breaker.allow(); !allowed {
+			t.Fatalf("request %d refused before the threshold", i+1)
+		}
+		breaker.record(false)
+	}
+	if allowed, _ := breaker.allow(); allowed {
+		t.Fatal("the circuit didn't open after 3 failures")
+	}
+
+	// past the cooldown a single request goes through, and a failure opens
+	// the circuit for twice as long
+	breaker.openUntil = time.Now()
+	if allowed, _ := breaker.allow(); !allowed {
+		t.Fatal("no request let through after the cooldown")
+	}
+	if allowed, _ := breaker.allow(); allowed {
+		t.Fatal("a second request let through while half open")
+	}
+	breaker.record(false)
+	if breaker.cooldown != 2*time.Hour {
+		t.Fatalf("cooldown after a failed probe is %s, want 2h", breaker.cooldown)
+	}
+
+	breaker.openUntil = time.Now()
+	breaker.allow()
+	breaker.record(true)
+	if allowed, _ := breaker.allow(); !allowed || breaker.cooldown != time.Hour {
+		t.Fatal("a successful probe didn't close the circuit")
+	}
+}
+
+// The publications of a batch share the breakers of the run, so the one
+// after the first failing one stops hitting the node before running out of
+// retries, with the default threshold.
+func TestCircuitBreakerOpensAcrossPublications(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		registry := &swaggerhub.Client{Urls: []string{server.URL}, Retries: 3, RetryWait: time.Millisecond, Breaker: swaggerHubBreakers{}}
+		registry.Publish(context.Background(), swaggerhub.PublishRequest{Api: "owner/api", Definition: []byte("openapi: 3.0.0"), MediaType: "application/yaml"})
+	}
+
+	if got := int(atomic.LoadInt32(&requests)); got != circuitSettings.Failures {
+		t.Fatalf("SwaggerHub got %d requests, want %d before the circuit opened", got, circuitSettings.Failures)
+	}
+	if err := (swaggerHubBreakers{}).Allow(server.URL); err == nil {
+		t.Fatal("the circuit is still closed")
+	}
+}
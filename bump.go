@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+)
+
+type bumpOptions struct {
+	SwaggerHubApi string `flag:"api" env:"SWAGGERHUB_API" config:"api"`
+	File          string `flag:"file" config:"file"`
+	Level         string `flag:"level"`
+	Auto          bool   `flag:"auto"`
+	Publish       bool   `flag:"publish"`
+	Output        string `flag:"output" default:"text"`
+	connectionOptions
+}
+
+// bumpResult is what bump prints with --output json, unless it publishes
+// the definition and prints the result of the publication instead.
+type bumpResult struct {
+	Api     string `json:"api"`
+	File    string `json:"file"`
+	Latest  string `json:"latest"`
+	Version string `json:"version"`
+}
+
+// bumpCommand writes in info.version of the definition the version after
+// the latest one of the API on SwaggerHub, at the level given or, with
+// --auto, the one its changes call for, and prints it. With --publish the
+// definition is then published as publish would.
+func bumpCommand(args []string) {
+	options := bumpOptions{}
+	positional := parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+	if len(positional) > 0 {
+		options.File = positional[0]
+	}
+	if options.File == "" {
+		exitAndError("bump needs the path to the OpenAPI definition")
+	}
+	if options.File == stdinPath || isDefinitionUrl(options.File) {
+		exitAndError("bump writes the new version in the definition, it needs a file")
+	}
+	switch {
+	case options.Level != "" && options.Auto:
+		exitAndError("use either --level or --auto")
+	case options.Level == "" && !options.Auto:
+		exitAndError("bump needs --level patch, minor or major, or --auto")
+	case options.Level != "":
+		if _, err := (semanticVersion{}).bump(options.Level); err != nil {
+			exitAndError(err)
+		}
+	}
+	options.useConnection(&options.SwaggerHubApi)
+	if options.SwaggerHubApi == "" {
+		exitAndError("missing api")
+	}
+	if len(strings.Split(options.SwaggerHubApi, "/")) != 2 {
+		exitAndError("api is in the wrong format")
+	}
+
+	content, err := ioutil.ReadFile(options.File)
+	if err != nil {
+		exitAndError(fmt.Sprintf("can't read %s: %v", options.File, err))
+	}
+	latest, latestName, err := latestVersion(options.SwaggerHubApi, options.SwaggerHubAccessToken)
+	if err != nil {
+		exitAndError(err)
+	}
+	level := options.Level
+	if options.Auto {
+		level = autoBumpLevel(options.File, content, options.SwaggerHubApi, latestName, options.SwaggerHubAccessToken)
+	}
+	next, _ := latest.bump(level)
+	if content, err = replaceDefinitionVersion(options.File, content, next.String()); err != nil {
+		exitAndError(err)
+	}
+	if err := ioutil.WriteFile(options.File, content, 0644); err != nil {
+		exitAndError(fmt.Sprintf("can't write %s: %v", options.File, err))
+	}
+	log.Printf("%s is at %s on SwaggerHub, %s is now at %s", options.SwaggerHubApi, latest, options.File, next)
+	switch {
+	case !outputJson:
+		fmt.Println(next)
+	case !options.Publish:
+		printJson(bumpResult{Api: options.SwaggerHubApi, File: options.File, Latest: latest.String(), Version: next.String()})
+	}
+
+	if options.Publish {
+		publish(options.File, bumpPublishOptions(&options))
+	}
+}
+
+// latestVersion is the highest semantic version of the API on SwaggerHub,
+// with its name there. Versions that aren't semantic ones are skipped.
+func latestVersion(api string, accessToken string) (semanticVersion, string, error) {
+	versions, err := listVersions(swaggerHubUrls, api, accessToken)
+	if err != nil {
+		return semanticVersion{}, "", fmt.Errorf("can't list the versions of %s: %w", api, err)
+	}
+	var latest semanticVersion
+	name := ""
+	for _, version := range versions {
+		if parsed, ok := parseSemanticVersion(version.Version); ok && (name == "" || latest.less(parsed)) {
+			latest, name = parsed, version.Version
+		}
+	}
+	if name == "" {
+		return latest, "", fmt.Errorf("%s has no semantic version to bump", api)
+	}
+	return latest, name, nil
+}
+
+// autoBumpLevel compares the definition with the latest version as diff
+// does and logs why it picks its level: major when a change is breaking,
+// minor when something was added, and patch otherwise.
+func autoBumpLevel(openApiPath string, openApi []byte, api string, version string, accessToken string) string {
+	local, err := parseOpenApiDocument(openApiPath, openApi)
+	if err != nil {
+		exitAndError(err)
+	}
+	// a cached copy would hide what SwaggerHub has now
+	swaggerHubCache = nil
+	remote, err := fetchDocument(api, version, accessToken)
+	if err != nil {
+		exitAndError(fmt.Errorf("can't fetch %s %s: %w", api, version, err))
+	}
+
+	changes := diffSpecs(remote, local)
+	var breaking, added []specChange
+	for _, change := range changes {
+		if len(change.Breaking) > 0 {
+			breaking = append(breaking, change)
+		} else if change.Action == "added" {
+			added = append(added, change)
+		}
+	}
+	switch {
+	case len(breaking) > 0:
+		log.Printf("major, %d of the %d changes from %s %s are breaking:", len(breaking), len(changes), api, version)
+		for _, change := range breaking {
+			log.Printf("  %s %s %s: %s", change.Action, strings.TrimSuffix(change.Kind, "s"), change.Name, strings.Join(change.Breaking, ", "))
+		}
+		return "major"
+	case len(added) > 0:
+		log.Printf("minor, %d of the %d changes from %s %s add to it without breaking anything:", len(added), len(changes), api, version)
+		for _, change := range added {
+			log.Printf("  %s %s %s", change.Action, strings.TrimSuffix(change.Kind, "s"), change.Name)
+		}
+		return "minor"
+	case len(changes) > 0:
+		log.Printf("patch, the %d changes from %s %s neither add nor break anything", len(changes), api, version)
+	default:
+		log.Printf("patch, there are no changes from %s %s", api, version)
+	}
+	return "patch"
+}
+
+// bumpPublishOptions are the options of publish for bump --publish: the
+// connection flags of bump, and the variables and config as publish reads
+// them. The version is the one just written in the definition.
+func bumpPublishOptions(options *bumpOptions) *commandLineOptions {
+	args := []string{"publish", "--api", options.SwaggerHubApi}
+	for flag, value := range map[string]string{
+		"config":       options.Config,
+		"env":          options.Environment,
+		"profile":      options.Profile,
+		"registry-url": options.RegistryUrl,
+		"ca-cert":      options.CaCert,
+		"client-cert":  options.ClientCert,
+		"client-key":   options.ClientKey,
+		"proxy":        options.Proxy,
+		"timeout":      options.Timeout,
+	} {
+		if value != "" {
+			args = append(args, "--"+flag, value)
+		}
+	}
+	if options.Verbose {
+		args = append(args, "--verbose")
+	}
+
+	publishOptions := commandLineOptions{}
+	parseArgs(&publishOptions, args)
+	publishOptions.SwaggerHubAccessToken = options.SwaggerHubAccessToken
+	publishOptions.AccessTokenFile = ""
+	publishOptions.ApiVersion = ""
+	publishOptions.VersionFrom = ""
+	return &publishOptions
+}
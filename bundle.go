@@ -0,0 +1,344 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type bundleOptions struct {
+	File         string `flag:"file" config:"file"`
+	FileHeader   string `flag:"file-header" env:"SWAGGERGO_FILE_HEADER"`
+	Out          string `flag:"out"`
+	RefHeader    string `flag:"ref-header" env:"SWAGGERGO_REF_HEADER"`
+	NoRemoteRefs bool   `flag:"no-remote-refs" env:"SWAGGERGO_NO_REMOTE_REFS" config:"noRemoteRefs"`
+	Output       string `flag:"output" default:"text"`
+	transportOptions
+}
+
+// bundleSections are walked first when bundling, so that what they reference
+// lands in them rather than in the first operation using it.
+var bundleSections = []string{"components", "definitions", "parameters", "responses"}
+
+// bundleCommand prints the definition with the files it references bundled
+// in, or writes it to --out.
+func bundleCommand(args []string) {
+	options := bundleOptions{}
+	positional := parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+	if len(positional) > 0 {
+		options.File = positional[0]
+	}
+	if options.File == "" {
+		exitAndError("bundle needs the path to the OpenAPI definition")
+	}
+	useDocumentOutput(options.Out)
+	options.useTransport()
+
+	openApi, err := readDefinition(options.File, options.FileHeader)
+	if err != nil {
+		exitAndError(err)
+	}
+	bundled, err := bundleDefinition(options.File, openApi, options.RefHeader, !options.NoRemoteRefs)
+	if err != nil {
+		exitAndError(err)
+	}
+	if options.Out == "" {
+		fmt.Printf("%s", bundled)
+		return
+	}
+	if err := ioutil.WriteFile(options.Out, bundled, 0644); err != nil {
+		exitAndError(fmt.Sprintf("can't write %s: %v", options.Out, err))
+	}
+	printWrittenResult(options.File, options.Out)
+}
+
+// bundleDefinition inlines what the $ref of the definition point to in
+// other files, as SwaggerHub can't resolve relative references. The first
+// reference to something gets its content and the next ones point to it,
+// which keeps recursive schemas finite. References to URLs are downloaded
+// too, sending refHeader, unless remote is off; the ones into the registry
+// are left to SwaggerHub. A definition without references to bundle is
+// returned untouched, otherwise it's written again in its format.
+func bundleDefinition(definitionPath string, content []byte, refHeader string, remote bool) ([]byte, error) {
+	document, err := parseOpenApiDocument(definitionPath, content)
+	if err != nil {
+		return nil, err
+	}
+	bundler := definitionBundler{root: definitionPath, header: refHeader, remote: remote, files: map[string]*yaml.Node{}, placed: map[string]string{}}
+	if !isDefinitionUrl(definitionPath) {
+		if bundler.root, err = filepath.Abs(definitionPath); err != nil {
+			return nil, err
+		}
+	}
+
+	root := document.Root
+	for _, section := range bundleSections {
+		if node := mappingValue(root, section); node != nil {
+			if err := bundler.walk(node, bundler.root, joinPointer(section)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if !containsString(bundleSections, root.Content[i].Value) {
+			if err := bundler.walk(root.Content[i+1], bundler.root, joinPointer(root.Content[i].Value)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !bundler.changed {
+		return content, nil
+	}
+
+	return encodeDefinition(root, definitionType(definitionPath, content))
+}
+
+// definitionBundler keeps the files read while bundling, by absolute path
+// or URL, and where what was inlined from them got placed, by location and
+// pointer. The root is the location of the definition.
+type definitionBundler struct {
+	root    string
+	header  string
+	remote  bool
+	files   map[string]*yaml.Node
+	placed  map[string]string
+	changed bool
+}
+
+// walk bundles the references of a node of file found at pointer in the
+// bundled definition.
+func (bundler *definitionBundler) walk(node *yaml.Node, file string, pointer string) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		if ref := mappingValue(node, "$ref"); ref != nil && ref.Kind == yaml.ScalarNode {
+			if replaced, err := bundler.replace(node, ref.Value, file, pointer); replaced || err != nil {
+				return err
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := bundler.walk(node.Content[i+1], file, pointer+"/"+escapePointer(node.Content[i].Value)); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			if err := bundler.walk(item, file, pointer+"/"+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// replace puts in place of the reference node what it points to, or a
+// reference to where that was already placed. Local references of the
+// definition are left alone, and so are remote ones when they can't be
+// downloaded.
+func (bundler *definitionBundler) replace(node *yaml.Node, ref string, file string, pointer string) (bool, error) {
+	target, fragment, location, err := referenceTarget(ref, file)
+	if err != nil {
+		return false, err
+	}
+	if location != nil && (!bundler.remote || isRegistryReference(location)) {
+		return false, nil
+	}
+	if target == bundler.root {
+		if strings.HasPrefix(ref, "#") {
+			return false, nil
+		}
+		// a file pointing back into the definition
+		node.Content = []*yaml.Node{scalarNode("$ref"), scalarNode("#" + fragment)}
+		bundler.changed = true
+		return true, nil
+	}
+
+	key := target + "#" + fragment
+	if placed, ok := bundler.placed[key]; ok {
+		node.Content = []*yaml.Node{scalarNode("$ref"), scalarNode(placed)}
+		bundler.changed = true
+		return true, nil
+	}
+	targetRoot, err := bundler.load(target, file)
+	if err != nil {
+		return false, err
+	}
+	resolved := pointerNode(targetRoot, fragment)
+	if resolved == nil {
+		return false, fmt.Errorf("can't resolve %s in %s, %s has nothing there", ref, file, target)
+	}
+
+	inlined := copyNode(resolved)
+	// the description or summary next to a reference wins over the target's
+	for i := 0; i+1 < len(node.Content) && inlined.Kind == yaml.MappingNode; i += 2 {
+		if key := node.Content[i].Value; key != "$ref" {
+			if value := mappingValue(inlined, key); value != nil {
+				*value = *node.Content[i+1]
+			} else {
+				inlined.Content = append(inlined.Content, node.Content[i], node.Content[i+1])
+			}
+		}
+	}
+	*node = *inlined
+	bundler.placed[key] = pointer
+	bundler.changed = true
+	return true, bundler.walk(node, target, pointer)
+}
+
+// referenceTarget is the file or URL a reference of file points to, with
+// the JSON pointer after its #. The location is parsed for URLs.
+func referenceTarget(ref string, file string) (string, string, *neturl.URL, error) {
+	refPath, fragment := ref, ""
+	if i := strings.Index(ref, "#"); i >= 0 {
+		refPath, fragment = ref[:i], ref[i+1:]
+	}
+
+	switch {
+	case refPath == "":
+		return file, fragment, nil, nil
+	case isDefinitionUrl(refPath) || isDefinitionUrl(file):
+		base, err := neturl.Parse(file)
+		if err != nil {
+			return "", "", nil, fmt.Errorf("invalid URL %s: %v", file, err)
+		}
+		location, err := base.Parse(refPath)
+		if err != nil {
+			return "", "", nil, fmt.Errorf("invalid reference %s in %s", ref, file)
+		}
+		return location.String(), fragment, location, nil
+	}
+	unescaped, err := neturl.PathUnescape(refPath)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("invalid reference %s in %s", ref, file)
+	}
+	target := filepath.FromSlash(unescaped)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(file), target)
+	}
+	return target, fragment, nil, nil
+}
+
+// load reads a file referenced from another one, or downloads it, once.
+func (bundler *definitionBundler) load(path string, from string) (*yaml.Node, error) {
+	if root, ok := bundler.files[path]; ok {
+		return root, nil
+	}
+	var content []byte
+	var err error
+	if isDefinitionUrl(path) {
+		content, err = downloadReference(path, bundler.header)
+	} else if content, err = ioutil.ReadFile(path); err != nil {
+		err = fmt.Errorf("can't read %s, referenced from %s", path, from)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, fmt.Errorf("can't parse %s: %v", path, err)
+	}
+	if len(root.Content) == 0 {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+	bundler.files[path] = root.Content[0]
+	return root.Content[0], nil
+}
+
+// pointerNode follows a JSON pointer, as /components/schemas/Pet or
+// /items/0, from the root of a file.
+func pointerNode(root *yaml.Node, pointer string) *yaml.Node {
+	if pointer == "" || pointer == "/" {
+		return root
+	}
+	node := root
+	for _, key := range splitPointer("#" + pointer) {
+		if node.Kind == yaml.SequenceNode {
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node.Content) {
+				return nil
+			}
+			node = node.Content[index]
+		} else if node = mappingValue(node, key); node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+func copyNode(node *yaml.Node) *yaml.Node {
+	copied := *node
+	copied.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		copied.Content[i] = copyNode(child)
+	}
+	return &copied
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// downloadReference downloads a file referenced by URL, sending header
+// ("Name: value") when given. Downloads are cached on disk as the responses
+// of SwaggerHub are, and the cached copy is used when the server can't be
+// reached.
+func downloadReference(url string, header string) ([]byte, error) {
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %s: %v", url, err)
+	}
+	request.Header.Set("Accept", "application/yaml, application/json;q=0.9, */*;q=0.8")
+	if name, value, ok := splitHeader(header); ok {
+		request.Header.Set(name, value)
+	}
+	var cached *cachedResponse
+	if swaggerHubCache != nil {
+		cached = swaggerHubCache.prepare(request, header)
+	}
+
+	client := client()
+	response, err := client.Do(request)
+	if err != nil {
+		if cached != nil {
+			log.Printf("%s can't be reached, using the cached copy: %v", url, err)
+			return cached.Body, nil
+		}
+		return nil, fmt.Errorf("can't download %s: %w", url, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotModified && cached != nil {
+		return cached.Body, nil
+	}
+	if response.StatusCode >= 300 {
+		return nil, fmt.Errorf("can't download %s: %s", url, response.Status)
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("can't download %s: %w", url, err)
+	}
+	if swaggerHubCache != nil {
+		swaggerHubCache.store(request.URL.String(), header, response, body)
+	}
+	return body, nil
+}
+
+// isRegistryReference tells whether a reference points into the registry,
+// as a domain, which SwaggerHub resolves itself.
+func isRegistryReference(location *neturl.URL) bool {
+	if !registryReferencePattern.MatchString(location.Path) {
+		return false
+	}
+	for _, registryUrl := range append([]string{swaggerHubUrl}, swaggerHubUrls...) {
+		if registry, err := neturl.Parse(registryUrl); err == nil && registry.Host == location.Host {
+			return true
+		}
+	}
+	return false
+}
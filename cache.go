@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// responseCache keeps GET responses on disk with their validators, so the
+// next request for the same URL is conditional and a 304 answer is served
+// from disk.
+type responseCache struct {
+	Dir string
+}
+
+type cachedResponse struct {
+	Url          string `json:"url"`
+	ETag         string `json:"etag"`
+	LastModified string `json:"lastModified"`
+	Body         []byte `json:"body"`
+}
+
+// swaggerHubCache is nil when caching is disabled, with --no-cache or
+// SWAGGERGO_CACHE_DIR=off.
+var swaggerHubCache = defaultResponseCache()
+
+func defaultResponseCache() *responseCache {
+	dir := os.Getenv("SWAGGERGO_CACHE_DIR")
+	if dir == "off" {
+		return nil
+	}
+	if dir == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			return nil
+		}
+		dir = filepath.Join(userCache, commandLineName)
+	}
+	return &responseCache{Dir: dir}
+}
+
+// path keys the entry by URL and credentials, so a response is never served
+// to someone using a different token.
+func (cache *responseCache) path(url string, accessToken string) string {
+	key := sha256.Sum256([]byte(url + "\n" + accessToken))
+	return filepath.Join(cache.Dir, hex.EncodeToString(key[:])+".json")
+}
+
+// prepare adds the validators of the cached response to the request.
+func (cache *responseCache) prepare(request *http.Request, accessToken string) *cachedResponse {
+	content, err := ioutil.ReadFile(cache.path(request.URL.String(), accessToken))
+	if err != nil {
+		return nil
+	}
+	var cached cachedResponse
+	if json.Unmarshal(content, &cached) != nil {
+		return nil
+	}
+	if cached.ETag != "" {
+		request.Header.Set("If-None-Match", cached.ETag)
+	}
+	if cached.LastModified != "" {
+		request.Header.Set("If-Modified-Since", cached.LastModified)
+	}
+	return &cached
+}
+
+func (cache *responseCache) store(url string, accessToken string, response *http.Response, body []byte) {
+	cached := cachedResponse{
+		Url:          url,
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+		Body:         body,
+	}
+	if cached.ETag == "" && cached.LastModified == "" {
+		return
+	}
+	content, _ := json.Marshal(cached)
+	if os.MkdirAll(cache.Dir, 0700) == nil {
+		ioutil.WriteFile(cache.path(url, accessToken), content, 0600)
+	}
+}
+
+// Prepare and Store serve the cache to the swaggerhub client, the token
+// being the Authorization header of the request.
+func (cache *responseCache) Prepare(request *http.Request) ([]byte, bool) {
+	cached := cache.prepare(request, request.Header.Get("Authorization"))
+	if cached == nil {
+		return nil, false
+	}
+	return cached.Body, true
+}
+
+func (cache *responseCache) Store(response *http.Response, body []byte) {
+	cache.store(response.Request.URL.String(), response.Request.Header.Get("Authorization"), response, body)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+type changelogOptions struct {
+	SwaggerHubApi string `flag:"api" env:"SWAGGERHUB_API" required:"true"`
+	File          string `flag:"file" config:"file"`
+	From          string `flag:"from" required:"true"`
+	To            string `flag:"to" default:"local"`
+	Out           string `flag:"out"`
+	Output        string `flag:"output" default:"text"`
+	connectionOptions
+}
+
+// changelogResult is what changelog prints with --output json, the changes
+// the Markdown is made of.
+type changelogResult struct {
+	Api     string       `json:"api"`
+	From    string       `json:"from"`
+	To      string       `json:"to"`
+	Out     string       `json:"out,omitempty"`
+	Changes []diffChange `json:"changes"`
+}
+
+// changelogSections are the sections of a changelog, in order, by the
+// action of their changes. Breaking changes have their own, first.
+var changelogSections = []struct {
+	Action string
+	Title  string
+}{
+	{"added", "Added"},
+	{"removed", "Removed"},
+	{"changed", "Changed"},
+}
+
+// changelogCommand prints the changes between a version on SwaggerHub and
+// the local definition, or another version, as markdown for release notes
+// and the description of the version.
+func changelogCommand(args []string) {
+	options := changelogOptions{}
+	positional := parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+	if len(positional) > 0 {
+		options.File = positional[0]
+	}
+	if options.To == "local" && options.File == "" {
+		exitAndError("changelog needs the path to the OpenAPI definition, or --to with a version")
+	}
+	options.useConnection(&options.SwaggerHubApi)
+	if len(strings.Split(options.SwaggerHubApi, "/")) != 2 {
+		exitAndError("api is in the wrong format")
+	}
+
+	// a cached copy would hide what SwaggerHub has now
+	swaggerHubCache = nil
+	from, err := fetchDocument(options.SwaggerHubApi, options.From, options.SwaggerHubAccessToken)
+	if err != nil {
+		exitAndError(fmt.Errorf("can't fetch %s %s: %w", options.SwaggerHubApi, options.From, err))
+	}
+	var to *openApiDocument
+	version := options.To
+	if options.To == "local" {
+		openApi, err := readDefinition(options.File, "")
+		if err != nil {
+			exitAndError(err)
+		}
+		if to, err = parseOpenApiDocument(options.File, openApi); err != nil {
+			exitAndError(err)
+		}
+		version = definitionVersion(openApi)
+	} else if to, err = fetchDocument(options.SwaggerHubApi, options.To, options.SwaggerHubAccessToken); err != nil {
+		exitAndError(fmt.Errorf("can't fetch %s %s: %w", options.SwaggerHubApi, options.To, err))
+	}
+
+	changes := diffSpecs(from, to)
+	changelog := renderChangelog(options.SwaggerHubApi, options.From, version, changes)
+	if options.Out != "" {
+		if err := ioutil.WriteFile(options.Out, []byte(changelog), 0644); err != nil {
+			exitAndError(fmt.Sprintf("can't write %s: %v", options.Out, err))
+		}
+	}
+	switch {
+	case outputJson:
+		result := changelogResult{Api: options.SwaggerHubApi, From: options.From, To: version, Out: options.Out, Changes: []diffChange{}}
+		for _, change := range changes {
+			result.Changes = append(result.Changes, diffChange{Kind: change.Kind, Action: change.Action, Name: change.Name, Breaking: change.Breaking})
+		}
+		printJson(result)
+	case options.Out == "":
+		fmt.Print(changelog)
+	}
+}
+
+// renderChangelog writes the changes as markdown: the breaking ones with
+// why they break clients, then what was added, removed and changed, each
+// by kind as diff prints them.
+func renderChangelog(api string, from string, to string, changes []specChange) string {
+	var changelog strings.Builder
+	fmt.Fprintf(&changelog, "## %s\n\nChanges to %s since %s.\n", to, api, from)
+	if len(changes) == 0 {
+		changelog.WriteString("\nNo changes.\n")
+		return changelog.String()
+	}
+
+	section := func(title string, include func(change specChange) bool, line func(change specChange) string) {
+		var lines []string
+		for _, kind := range specChangeKinds {
+			for _, change := range changes {
+				if change.Kind == kind && include(change) {
+					lines = append(lines, "- "+line(change))
+				}
+			}
+		}
+		if len(lines) > 0 {
+			fmt.Fprintf(&changelog, "\n### %s\n\n%s\n", title, strings.Join(lines, "\n"))
+		}
+	}
+	section("Breaking changes", func(change specChange) bool {
+		return len(change.Breaking) > 0
+	}, func(change specChange) string {
+		// the reason of a removal only says it was removed
+		line := capitalize(change.Action + " " + changelogName(change))
+		if change.Action == "removed" {
+			return line
+		}
+		return fmt.Sprintf("%s: %s", line, strings.Join(change.Breaking, ", "))
+	})
+	for _, action := range changelogSections {
+		action := action
+		section(action.Title, func(change specChange) bool {
+			return len(change.Breaking) == 0 && change.Action == action.Action
+		}, func(change specChange) string {
+			return capitalize(changelogName(change))
+		})
+	}
+	return changelog.String()
+}
+
+// changelogName names what changed, as "operation `GET /pets`".
+func changelogName(change specChange) string {
+	return fmt.Sprintf("%s `%s`", strings.TrimSuffix(change.Kind, "s"), change.Name)
+}
+
+func capitalize(text string) string {
+	if text == "" {
+		return text
+	}
+	return strings.ToUpper(text[:1]) + text[1:]
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// operationDiff lists the operations added, modified or removed between two
+// versions of a document.
+type operationDiff struct {
+	Added   []openApiOperation
+	Changed []openApiOperation
+	Removed []openApiOperation
+}
+
+// changedOperations compares the document with its version at gitRef and
+// returns the pointers of the operations that were added or modified. Changes
+// to path level parameters count as changes of every operation of the path.
+func changedOperations(document *openApiDocument, gitRef string) (map[string]bool, error) {
+	base, err := documentAtRef(document.Path, gitRef)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := map[string]bool{}
+	diff := diffOperations(base, document)
+	for _, operation := range append(diff.Added, diff.Changed...) {
+		changed[operation.pointer()] = true
+	}
+	return changed, nil
+}
+
+// diffOperations compares the operations of two documents. A nil base means
+// every operation was added.
+func diffOperations(base *openApiDocument, document *openApiDocument) operationDiff {
+	diff := operationDiff{}
+	baseOperations := map[string]string{}
+	if base != nil {
+		for _, operation := range base.operations() {
+			baseOperations[operation.pointer()] = operationFingerprint(base, operation)
+		}
+	}
+
+	current := map[string]bool{}
+	for _, operation := range document.operations() {
+		current[operation.pointer()] = true
+		if fingerprint, ok := baseOperations[operation.pointer()]; !ok {
+			diff.Added = append(diff.Added, operation)
+		} else if fingerprint != operationFingerprint(document, operation) {
+			diff.Changed = append(diff.Changed, operation)
+		}
+	}
+	if base != nil {
+		for _, operation := range base.operations() {
+			if !current[operation.pointer()] {
+				diff.Removed = append(diff.Removed, operation)
+			}
+		}
+	}
+	return diff
+}
+
+// documentAtRef reads the document as committed in gitRef. It returns nil
+// when the file didn't exist yet.
+func documentAtRef(path string, gitRef string) (*openApiDocument, error) {
+	var stdout, stderr bytes.Buffer
+	command := exec.Command("git", "show", fmt.Sprintf("%s:./%s", gitRef, filepath.Base(path)))
+	command.Dir = filepath.Dir(path)
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+
+	if err := command.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if strings.Contains(message, "does not exist") || strings.Contains(message, "exists on disk, but not in") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("can't read %s at %s: %s", path, gitRef, message)
+	}
+
+	base, err := parseOpenApiDocument(fmt.Sprintf("%s@%s", path, gitRef), stdout.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return base, nil
+}
+
+// operationFingerprint ignores formatting, so a definition reformatted by
+// SwaggerHub compares equal to the uploaded one.
+func operationFingerprint(document *openApiDocument, operation openApiOperation) string {
+	parameters := document.lookup("paths", operation.Path, "parameters")
+	fingerprint, _ := json.Marshal([]interface{}{nodeValue(operation.Node), nodeValue(parameters)})
+	return string(fingerprint)
+}
+
+// onlyChangedOperations keeps the findings located inside changed operations.
+func onlyChangedOperations(findings []lintFinding, changed map[string]bool) []lintFinding {
+	var kept []lintFinding
+	for _, finding := range findings {
+		for pointer := range changed {
+			if finding.Pointer == pointer || strings.HasPrefix(finding.Pointer, pointer+"/") {
+				kept = append(kept, finding)
+				break
+			}
+		}
+	}
+	return kept
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+type codegenOptions struct {
+	Generator string `flag:"generator" default:"openapi-generator"`
+	Lang      string `flag:"lang" required:"true"`
+	Config    string `flag:"config"`
+	Out       string `flag:"out"`
+	Ruleset   string `flag:"ruleset" env:"SWAGGERGO_RULESET"`
+	Output    string `flag:"output" default:"text"`
+}
+
+// codegenArguments builds the command line of each supported generator.
+var codegenArguments = map[string]func(openApiPath string, options *codegenOptions) []string{
+	"openapi-generator": func(openApiPath string, options *codegenOptions) []string {
+		args := []string{"generate", "-i", openApiPath, "-g", options.Lang, "-o", options.Out}
+		if options.Config != "" {
+			args = append(args, "-c", options.Config)
+		}
+		return args
+	},
+	"swagger-codegen": func(openApiPath string, options *codegenOptions) []string {
+		args := []string{"generate", "-i", openApiPath, "-l", options.Lang, "-o", options.Out}
+		if options.Config != "" {
+			args = append(args, "-c", options.Config)
+		}
+		return args
+	},
+}
+
+// codegenCommand checks the definition the same way publishing does and then
+// runs a locally installed generator on it, so clients are only generated
+// from definitions that would be accepted for publishing.
+func codegenCommand(args []string) {
+	options := codegenOptions{}
+	positional := parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+	if len(positional) != 2 || positional[0] != "local" {
+		exitAndError("usage: swaggergo codegen local path/to/openapi.yml --lang go")
+	}
+
+	arguments, ok := codegenArguments[options.Generator]
+	if !ok {
+		exitAndError(fmt.Sprintf("unknown generator %s, use openapi-generator or swagger-codegen", options.Generator))
+	}
+	if _, err := exec.LookPath(options.Generator); err != nil {
+		exitAndError(fmt.Sprintf("%s is not installed or not in the PATH", options.Generator))
+	}
+	if options.Out == "" {
+		options.Out = filepath.Join("generated", options.Lang)
+	}
+
+	openApiPath := positional[1]
+	openApi, err := ioutil.ReadFile(openApiPath)
+	if err != nil {
+		exitAndError(fmt.Sprintf("can't read the file %s", openApiPath))
+	}
+	publishOptions := commandLineOptions{CheckSchemas: true, Ruleset: options.Ruleset}
+	rules, err := publishRules(&publishOptions)
+	if err != nil {
+		exitAndError(err)
+	}
+	if _, _, _, err := checkDocument(openApiPath, openApi, rules, &publishOptions); err != nil {
+		exitAndError(err)
+	}
+
+	command := exec.Command(options.Generator, arguments(openApiPath, &options)...)
+	command.Stdout = os.Stderr
+	command.Stderr = os.Stderr
+	log.Printf("running %s %s", options.Generator, strings.Join(command.Args[1:], " "))
+	if err := command.Run(); err != nil {
+		exitAndError(fmt.Errorf("%s failed: %w", options.Generator, err))
+	}
+
+	log.Printf("%s client for %s generated into %s", options.Lang, openApiPath, options.Out)
+	printWrittenResult(openApiPath, options.Out)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/oleiade/reflections"
+)
+
+// command is a subcommand. Options is the struct its flags are parsed into,
+// also used for its help and the shell completion; Run gets the arguments
+// starting with the name of the command.
+type command struct {
+	Summary string
+	Usage   string
+	Options interface{}
+	Run     func(args []string)
+}
+
+// commands are the subcommands by name. A first argument that isn't one of
+// them is the definition of the implicit publish.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"publish": {
+			Summary: "Check a definition and publish it to SwaggerHub.",
+			Usage:   "publish path/to/openapi.yml|-|https://...|'specs/**/*.yml' ... --api owner/name [--access-token ...] [--type yml|json] [--spec-type openapi|asyncapi] [--file-header ...]",
+			Options: &commandLineOptions{},
+			Run:     publishCommand,
+		},
+		"bundle": {
+			Summary: "Bundle the files a definition references into one document.",
+			Usage:   "bundle path/to/openapi.yml [--out bundled.yml]",
+			Options: &bundleOptions{},
+			Run:     bundleCommand,
+		},
+		"check-refs": {
+			Summary: "Report the references of a definition that are broken or go round in circles.",
+			Usage:   "check-refs path/to/openapi.yml [--no-remote-refs] [--output (github | json)]",
+			Options: &checkRefsOptions{},
+			Run:     checkRefsCommand,
+		},
+		"convert": {
+			Summary: "Convert a definition between YAML and JSON, or upgrade it to OpenAPI 3.0 or 3.1.",
+			Usage:   "convert path/to/openapi.yml (--to json|yaml | --target-oas 3.0|3.1 [--converter auto|local|service]) [--out openapi.json]",
+			Options: &convertOptions{},
+			Run:     convertCommand,
+		},
+		"lint": {
+			Summary: "Lint definitions with the spectral:oas rules or a ruleset, and the rules of the config.",
+			Usage:   "lint path/to/openapi.yml [more.yml ...] [--ruleset .spectral.yaml] [--report junit --report-file results.xml] [--output github]",
+			Options: &lintOptions{},
+			Run:     lintCommand,
+		},
+		"validate": {
+			Summary: "Check that definitions are valid OpenAPI, without publishing them.",
+			Usage:   "validate path/to/openapi.yml [more.yml ...] [--report junit --report-file results.xml] [--output github]",
+			Options: &validateOptions{},
+			Run:     validateCommand,
+		},
+		"fetch": {
+			Summary: "Fetch a definition, optionally verifying it against a signed attestation.",
+			Usage:   "fetch --api owner/name --version 1.0.0 [--type (yml | json)] [--out openapi.yml] [--revision-file openapi.rev]",
+			Options: &fetchOptions{},
+			Run:     fetchCommand,
+		},
+		"verify": {
+			Summary: "Verify that a published version matches the local definition.",
+			Usage:   "verify --api owner/name [--api-version 1.0.0] path/to/openapi.yml",
+			Options: &verifyOptions{},
+			Run:     verifyCommand,
+		},
+		"diff": {
+			Summary: "Compare a local definition with a version on SwaggerHub.",
+			Usage:   "diff path/to/openapi.yml --api owner/name [--api-version 1.0.0] [--fail-on-breaking] [--output github]",
+			Options: &diffOptions{},
+			Run:     diffCommand,
+		},
+		"changelog": {
+			Summary: "Write the changes between two versions of an API as a markdown changelog.",
+			Usage:   "changelog path/to/openapi.yml --api owner/name --from 1.1.0 [--to (local | 1.2.0)] [--out CHANGELOG.md]",
+			Options: &changelogOptions{},
+			Run:     changelogCommand,
+		},
+		"promote": {
+			Summary: "Promote a version between the accounts of two profiles.",
+			Usage:   "promote --from-profile staging --to-profile prod --api owner/name --api-version 1.0.0",
+			Options: &promoteOptions{},
+			Run:     promoteCommand,
+		},
+		"list": {
+			Summary: "List the APIs of an owner with their version, visibility and last change.",
+			Usage:   "list --owner owner [--output (text | json)]",
+			Options: &listOptions{},
+			Run:     listCommand,
+		},
+		"versions": {
+			Summary: "List the versions of an API with their OAS level, lifecycle and default flag.",
+			Usage:   "versions --api owner/name [--output (text | json)]",
+			Options: &versionsOptions{},
+			Run:     versionsCommand,
+		},
+		"bump": {
+			Summary: "Write the version after the latest one on SwaggerHub in a definition, and optionally publish it.",
+			Usage:   "bump path/to/openapi.yml --api owner/name (--level (patch | minor | major) | --auto) [--publish]",
+			Options: &bumpOptions{},
+			Run:     bumpCommand,
+		},
+		"delete": {
+			Summary: "Delete a version of an API, or the whole API.",
+			Usage:   "delete --api owner/name (--version 1.0.0 [--yes] | --all-versions --confirm owner/name)",
+			Options: &deleteOptions{},
+			Run:     deleteCommand,
+		},
+		"domain": {
+			Summary: "Publish, fetch and list domains, the shared components APIs reference.",
+			Usage:   "domain (publish shared-models.yml --domain owner/name [--version 1.0.0] | fetch --domain owner/name --version 1.0.0 [--out domain.yml] | list (--owner owner | --domain owner/name))",
+			Options: &domainOptions{},
+			Run:     domainCommand,
+		},
+		"inventory": {
+			Summary: "Export the catalog of the APIs of an owner as CSV or JSON.",
+			Usage:   "inventory --owner owner [--out inventory.csv] [--format (csv | json)]",
+			Options: &inventoryOptions{},
+			Run:     inventoryCommand,
+		},
+		"export": {
+			Summary: "Export every API and domain of an owner into a directory to commit.",
+			Usage:   "export gitops --owner owner [--out registry]",
+			Options: &exportOptions{},
+			Run:     exportCommand,
+		},
+		"apply": {
+			Summary: "Reconcile SwaggerHub with an exported directory.",
+			Usage:   "apply --dir registry [--prune] [--plan | --auto-approve]",
+			Options: &applyOptions{},
+			Run:     applyCommand,
+		},
+		"graph": {
+			Summary: "Graph the references between the APIs and domains of an owner.",
+			Usage:   "graph --owner owner [--format (dot | mermaid)] [--out graph.dot]",
+			Options: &graphOptions{},
+			Run:     graphCommand,
+		},
+		"digest": {
+			Summary: "Report the versions created lately across an owner.",
+			Usage:   "digest --owner owner [--since 168h] [--no-email]",
+			Options: &digestOptions{},
+			Run:     digestCommand,
+		},
+		"retention": {
+			Summary: "Delete the versions beyond a retention window.",
+			Usage:   "retention apply (--api owner/name --keep 10 | --dir registry) [--keep-published]",
+			Options: &retentionOptions{},
+			Run:     retentionCommand,
+		},
+		"probe": {
+			Summary: "Probe a live environment, checking that GET operations answer as documented.",
+			Usage:   "probe --base-url https://api.example.com path/to/openapi.yml",
+			Options: &probeOptions{},
+			Run:     probeCommand,
+		},
+		"docs": {
+			Summary: "Build static HTML docs with Redoc or Swagger UI.",
+			Usage:   "docs build path/to/openapi.yml [--out site] [--renderer swagger-ui]",
+			Options: &docsOptions{},
+			Run:     docsCommand,
+		},
+		"codegen": {
+			Summary: "Generate a client with a locally installed generator.",
+			Usage:   "codegen local path/to/openapi.yml --lang go",
+			Options: &codegenOptions{},
+			Run:     codegenCommand,
+		},
+		"push": {
+			Summary: "Push a definition to an OCI registry as an artifact.",
+			Usage:   "push oci://registry.example.com/apis/orders:1.4.0 path/to/openapi.yml",
+			Options: &pushOptions{},
+			Run:     pushCommand,
+		},
+		"login": {
+			Summary: "Store a SwaggerHub API key in the keychain of the OS, for the commands to use.",
+			Usage:   "login [--profile name] [--access-token-stdin | --access-token-file path]",
+			Options: &loginOptions{},
+			Run:     loginCommand,
+		},
+		"whoami": {
+			Summary: "Check the access token and show the organizations it belongs to.",
+			Usage:   "whoami [--owner name | --api owner/name] [--output (text | json)]",
+			Options: &whoamiOptions{},
+			Run:     whoamiCommand,
+		},
+		"config": {
+			Summary: "Show the options a command would use and where each one comes from.",
+			Usage:   "config show [command] [flags ...]",
+			Options: &struct{}{},
+			Run:     configCommand,
+		},
+		"completion": {
+			Summary: "Print the shell completion for PowerShell or bash.",
+			Usage:   "completion (powershell | bash)",
+			Options: &struct{}{},
+			Run:     completionCommand,
+		},
+		"help": {
+			Summary: "Show the help of swaggergo or of a command.",
+			Usage:   "help [command]",
+			Options: &struct{}{},
+			Run:     helpCommand,
+		},
+	}
+}
+
+// publishCommand publishes the definition given first, after the name of the
+// command or of the program for the implicit publish.
+func publishCommand(args []string) {
+	options := commandLineOptions{}
+	positional := parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+	if options.File != "" && len(positional) == 0 {
+		positional = append(positional, options.File)
+	}
+	if len(positional) == 0 {
+		exitAndError("publish needs the path to the OpenAPI definition, or - to read it from the standard input")
+	}
+	limitRunTime(options.MaxTime, options.Deadline)
+
+	if len(positional) > 1 || strings.ContainsAny(positional[0], "*?[") {
+		publishBatch(positional, &options)
+		return
+	}
+	publish(positional[0], &options)
+}
+
+func helpCommand(args []string) {
+	if len(args) < 2 {
+		fmt.Printf("%s\n", commandLineUsage)
+		return
+	}
+	if _, ok := commands[args[1]]; !ok {
+		exitAndError(fmt.Sprintf("unknown command %s", args[1]))
+	}
+	printCommandUsage(args[1])
+}
+
+// printCommandUsage prints the usage of a command and its flags, with the
+// environment variables and defaults they fall back to.
+func printCommandUsage(name string) {
+	command := commands[name]
+	fmt.Printf("%s\n\nUsage:\n  $ %s %s\n", command.Summary, commandLineName, command.Usage)
+
+	options := command.Options
+	fields := optionFields(options)
+	if len(fields) == 0 {
+		return
+	}
+	fmt.Printf("\nFlags:\n")
+	for _, field := range fields {
+		flag, _ := reflections.GetFieldTag(options, field, "flag")
+		var notes []string
+		if required, _ := reflections.GetFieldTag(options, field, "required"); required == "true" {
+			notes = append(notes, "required")
+		}
+		if env, _ := reflections.GetFieldTag(options, field, "env"); env != "" {
+			notes = append(notes, "$"+env)
+		}
+		if key, _ := reflections.GetFieldTag(options, field, "config"); key != "" {
+			notes = append(notes, "config "+key)
+		}
+		if value, _ := reflections.GetFieldTag(options, field, "default"); value != "" {
+			notes = append(notes, "default "+value)
+		}
+		fmt.Println(strings.TrimRight(fmt.Sprintf("  --%-24s %s", flag, strings.Join(notes, ", ")), " "))
+	}
+}
+
+// commandNames are the names of the commands, sorted.
+func commandNames() []string {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/oleiade/reflections"
+)
+
+var completionScripts = map[string]*template.Template{
+	"powershell": template.Must(template.New("powershell").Parse(`Register-ArgumentCompleter -Native -CommandName swaggergo -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $flags = @{
+{{- range $command, $flags := .Flags}}
+        '{{$command}}' = @({{range $i, $flag := $flags}}{{if $i}}, {{end}}'{{$flag}}'{{end}})
+{{- end}}
+    }
+    $commands = @({{range $i, $command := .Commands}}{{if $i}}, {{end}}'{{$command}}'{{end}})
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    $command = if ($words.Count -gt 0 -and $flags.ContainsKey($words[0])) { $words[0] } else { '' }
+    if ($wordToComplete.StartsWith('-')) {
+        $candidates = $flags[$command]
+    } elseif ($words.Count -le 1) {
+        $candidates = $commands
+    } else {
+        return
+    }
+    $candidates | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterName', $_)
+    }
+}
+`)),
+	"bash": template.Must(template.New("bash").Parse(`_swaggergo() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" flags
+    case "${COMP_WORDS[1]}" in
+{{- range $command, $flags := .Flags}}{{if $command}}
+        {{$command}}) flags="{{range $i, $flag := $flags}}{{if $i}} {{end}}{{$flag}}{{end}}" ;;
+{{- end}}{{end}}
+        *) flags="{{range $i, $flag := index .Flags ""}}{{if $i}} {{end}}{{$flag}}{{end}}" ;;
+    esac
+    if [[ "$cur" == -* ]]; then
+        COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+    elif [ "$COMP_CWORD" -eq 1 ]; then
+        COMPREPLY=($(compgen -W "{{range $i, $command := .Commands}}{{if $i}} {{end}}{{$command}}{{end}}" -- "$cur") $(compgen -f -- "$cur"))
+    else
+        COMPREPLY=($(compgen -f -- "$cur"))
+    fi
+}
+complete -o filenames -F _swaggergo swaggergo
+`)),
+}
+
+// completionCommand prints the completion script of a shell, generated from
+// the flags of every command.
+func completionCommand(args []string) {
+	if len(args) != 2 {
+		exitAndError("usage: swaggergo completion (powershell | bash)")
+	}
+	script, ok := completionScripts[args[1]]
+	if !ok {
+		exitAndError(fmt.Sprintf("no completion for %s, use powershell or bash", args[1]))
+	}
+
+	flags := map[string][]string{}
+	// the command without a name is the implicit publish
+	names := append(commandNames(), "")
+	for _, name := range names {
+		options := commands["publish"].Options
+		if name != "" {
+			options = commands[name].Options
+		}
+		fields := optionFields(options)
+		for _, field := range fields {
+			flag, _ := reflections.GetFieldTag(options, field, "flag")
+			flags[name] = append(flags[name], "--"+flag)
+		}
+	}
+
+	var out strings.Builder
+	script.Execute(&out, struct {
+		Flags    map[string][]string
+		Commands []string
+	}{flags, commandNames()})
+	os.Stdout.WriteString(out.String())
+}
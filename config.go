@@ -0,0 +1,272 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/oleiade/reflections"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultConfigPath = "swaggergo.yml"
+
+type projectConfig struct {
+	Rules      []expressionRuleConfig `yaml:"rules"`
+	Http       httpConfig             `yaml:"http"`
+	SwaggerHub struct {
+		Urls           []string             `yaml:"urls"`
+		CircuitBreaker circuitBreakerConfig `yaml:"circuitBreaker"`
+	} `yaml:"swaggerhub"`
+	Environments  map[string]environmentConfig `yaml:"environments"`
+	Publish       []publishMapping             `yaml:"publish"`
+	Notifications struct {
+		Email emailConfig `yaml:"email"`
+	} `yaml:"notifications"`
+}
+
+// expressionRuleConfig is a custom rule: every node selected by Given must
+// satisfy the Assert expression.
+type expressionRuleConfig struct {
+	Name     string `yaml:"name"`
+	Severity string `yaml:"severity"`
+	Given    string `yaml:"given"`
+	Assert   string `yaml:"assert"`
+	Message  string `yaml:"message"`
+}
+
+// loadProjectConfig reads the configuration file. Without an explicit path
+// swaggergo.yml is used when it exists in the working directory.
+func loadProjectConfig(path string) (*projectConfig, error) {
+	config := &projectConfig{}
+	if path = projectConfigPath(path); path == "" {
+		return config, nil
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the config %s", path)
+	}
+	if err := yaml.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("can't parse the config %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// optionsConfigValues reads the project config for the options with a
+// config tag, as api or lint.ruleset, giving every setting by its dotted
+// path. The config is the one of --config, SWAGGERGO_CONFIG or
+// swaggergo.yml.
+func optionsConfigValues(opts interface{}, flags *flag.FlagSet) map[string]string {
+	fields := optionFields(opts)
+	tagged := false
+	for _, field := range fields {
+		if name, _ := reflections.GetFieldTag(opts, field, "config"); name != "" {
+			tagged = true
+		}
+	}
+	if !tagged {
+		return nil
+	}
+
+	path := os.Getenv("SWAGGERGO_CONFIG")
+	if configFlag := flags.Lookup("config"); configFlag != nil && configFlag.Value.String() != "" {
+		path = configFlag.Value.String()
+	}
+	if path = projectConfigPath(path); path == "" {
+		return nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		exitAndError(fmt.Sprintf("can't read the config %s", path))
+	}
+	var settings map[string]interface{}
+	if err := yaml.Unmarshal(content, &settings); err != nil {
+		exitAndError(fmt.Errorf("can't parse the config %s: %w", path, err))
+	}
+
+	values := map[string]string{}
+	var flatten func(prefix string, settings map[string]interface{})
+	flatten = func(prefix string, settings map[string]interface{}) {
+		for key, value := range settings {
+			switch value := value.(type) {
+			case map[string]interface{}:
+				flatten(prefix+key+".", value)
+			case []interface{}, nil:
+			default:
+				values[prefix+key] = fmt.Sprint(value)
+			}
+		}
+	}
+	flatten("", settings)
+	return values
+}
+
+// projectConfigPath is the config given, or swaggergo.yml when none was
+// given and it exists in the working directory.
+func projectConfigPath(path string) string {
+	if path != "" {
+		return path
+	}
+	if _, err := os.Stat(defaultConfigPath); err != nil {
+		return ""
+	}
+	return defaultConfigPath
+}
+
+func (config *projectConfig) lintRules() ([]lintRule, error) {
+	var rules []lintRule
+	for i, definition := range config.Rules {
+		if definition.Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i+1)
+		}
+		rule, err := expressionRule(definition)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %v", definition.Name, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// secretFlags are masked by config show.
+var secretFlags = []string{"token", "password", "secret"}
+
+// configCommand prints the configuration a command would run with, as
+// `config show [command] [flags ...]`: every option with its value and
+// where it came from (flag, env, config or default), and the SwaggerHub
+// URLs after the environment and profile are applied. Tokens are masked.
+func configCommand(args []string) {
+	if len(args) < 2 || args[1] != "show" {
+		exitAndError("usage: swaggergo config show [command] [flags ...]")
+	}
+	name := "publish"
+	rest := args[2:]
+	if len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+		name, rest = rest[0], rest[1:]
+	}
+	command, ok := commands[name]
+	if !ok || name == "config" {
+		exitAndError(fmt.Sprintf("unknown command %s", name))
+	}
+
+	options := reflect.New(reflect.TypeOf(command.Options).Elem()).Interface()
+	_, values := resolveArgs(options, append([]string{name}, rest...))
+	configPath, _ := optionField(options, "Config")
+	config, err := loadProjectConfig(configPath)
+	if err != nil {
+		exitAndError(err)
+	}
+	urlsSource := "default"
+	caCert, _ := optionField(options, "CaCert")
+	clientCert, _ := optionField(options, "ClientCert")
+	clientKey, _ := optionField(options, "ClientKey")
+	proxy, _ := optionField(options, "Proxy")
+	timeout, _ := optionField(options, "Timeout")
+	useProjectConnections(configPath, httpConfig{CaCert: caCert, ClientCert: clientCert, ClientKey: clientKey, Proxy: proxy, Timeout: timeout})
+	if len(config.SwaggerHub.Urls) > 0 {
+		urlsSource = "config swaggerhub.urls"
+	}
+
+	// a token file or the standard input replace the token, the environment
+	// and the profile can complete the api and the token
+	tokenFile, _ := optionField(options, "AccessTokenFile")
+	tokenStdin, _ := reflections.GetField(options, "AccessTokenStdin")
+	stdin, _ := tokenStdin.(bool)
+	token, tokenSource, err := readAccessTokenSource(tokenFile, stdin)
+	if err != nil {
+		exitAndError(err)
+	}
+	if token != "" {
+		reflections.SetField(options, "SwaggerHubAccessToken", token)
+		for i := range values {
+			if values[i].Flag == "access-token" {
+				values[i].Value, values[i].Source = token, tokenSource
+			}
+		}
+	}
+	environmentName, _ := optionField(options, "Environment")
+	profileName, hasProfile := optionField(options, "Profile")
+	if hasProfile {
+		api, _ := optionField(options, "SwaggerHubApi")
+		token, _ := optionField(options, "SwaggerHubAccessToken")
+		urls := strings.Join(swaggerHubUrls, ", ")
+		registryUrl, _ := optionField(options, "RegistryUrl")
+		environment := useEnvironment(configPath, environmentName, profileName, registryUrl, &api, &token)
+		source := "profile " + environment.Profile
+		if environmentName != "" {
+			source = "environment " + environmentName
+		}
+		if strings.Join(swaggerHubUrls, ", ") != urls {
+			urlsSource = source
+		}
+		for _, value := range values {
+			if value.Flag == "registry-url" && value.Value != "" {
+				urlsSource = value.Source
+			}
+		}
+		completed := map[string]string{"api": api, "access-token": token}
+		for i := range values {
+			value := &values[i]
+			if resolved, ok := completed[value.Flag]; ok && resolved != value.Value {
+				completedBy := source
+				if value.Flag == "access-token" && environment.tokenSource != "" {
+					completedBy = environment.tokenSource
+				}
+				if value.Value == "" {
+					value.Source = completedBy
+				} else {
+					value.Source += ", " + completedBy
+				}
+				value.Value = resolved
+			}
+		}
+	}
+
+	rows := [][]string{{"OPTION", "VALUE", "SOURCE"}}
+	for _, value := range values {
+		shown := value.Value
+		for _, secret := range secretFlags {
+			if strings.Contains(value.Flag, secret) && !strings.HasSuffix(value.Flag, "-file") && shown != "" {
+				shown = maskSecret(shown)
+			}
+		}
+		if value.Flag == "proxy" {
+			shown = redactProxy(shown)
+		}
+		source := value.Source
+		if value.Required && value.Value == "" {
+			source = "missing"
+		}
+		rows = append(rows, []string{"--" + value.Flag, shown, source})
+	}
+	fmt.Printf("command: %s\n", name)
+	if path := projectConfigPath(configPath); path != "" {
+		fmt.Printf("config: %s\n", path)
+	}
+	fmt.Printf("swaggerhub: %s (%s)\n\n", strings.Join(swaggerHubUrls, ", "), urlsSource)
+	printTable(rows)
+}
+
+// optionField is the string field of options with the name, when it has
+// one.
+func optionField(options interface{}, name string) (string, bool) {
+	if ok, _ := reflections.HasField(options, name); !ok {
+		return "", false
+	}
+	value, _ := reflections.GetField(options, name)
+	text, ok := value.(string)
+	return text, ok
+}
+
+// maskSecret keeps the last characters of a long secret, enough to tell
+// which one is used.
+func maskSecret(secret string) string {
+	if len(secret) < 12 {
+		return "****"
+	}
+	return "****" + secret[len(secret)-4:]
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var yaml11Booleans = []string{"y", "yes", "n", "no", "on", "off"}
+
+type convertOptions struct {
+	File         string `flag:"file" config:"file"`
+	FileHeader   string `flag:"file-header" env:"SWAGGERGO_FILE_HEADER"`
+	To           string `flag:"to"`
+	TargetOas    string `flag:"target-oas"`
+	Converter    string `flag:"converter" env:"SWAGGERGO_CONVERTER" default:"auto"`
+	ConverterUrl string `flag:"converter-url" env:"SWAGGERGO_CONVERTER_URL" default:"https://converter.swagger.io/api/convert"`
+	Out          string `flag:"out"`
+	Output       string `flag:"output" default:"text"`
+	transportOptions
+}
+
+// convertCommand prints the definition in JSON or YAML, upgraded to the
+// target OAS if given, or writes it to --out.
+func convertCommand(args []string) {
+	options := convertOptions{}
+	positional := parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+	if len(positional) > 0 {
+		options.File = positional[0]
+	}
+	if options.File == "" {
+		exitAndError("convert needs the path to the OpenAPI definition")
+	}
+	if options.To == "" && options.TargetOas == "" {
+		exitAndError("convert needs --to json or yaml, or --target-oas")
+	}
+	if options.Converter != "auto" && options.Converter != "local" && options.Converter != "service" {
+		exitAndError(fmt.Sprintf("unknown converter %s, use auto, local or service", options.Converter))
+	}
+	useDocumentOutput(options.Out)
+	options.useTransport()
+
+	openApi, err := readDefinition(options.File, options.FileHeader)
+	if err != nil {
+		exitAndError(err)
+	}
+	from := definitionType(options.File, openApi)
+	to := from
+	if options.To != "" {
+		if to, err = definitionFormat(options.To); err != nil {
+			exitAndError(err)
+		}
+	}
+	if options.TargetOas != "" {
+		if openApi, from, err = upgradeDefinition(options.File, openApi, from, options.TargetOas, options.Converter, options.ConverterUrl); err != nil {
+			exitAndError(err)
+		}
+	}
+	converted, err := convertDefinition(options.File, openApi, from, to)
+	if err != nil {
+		exitWithCode(exitInvalid, err)
+	}
+	if options.Out == "" {
+		fmt.Printf("%s", converted)
+		return
+	}
+	if err := ioutil.WriteFile(options.Out, converted, 0644); err != nil {
+		exitAndError(fmt.Sprintf("can't write %s: %v", options.Out, err))
+	}
+	printWrittenResult(options.File, options.Out)
+}
+
+// definitionFormat is the type of a format name: json, or yml for yaml and
+// yml.
+func definitionFormat(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case "json":
+		return "json", nil
+	case "yaml", "yml":
+		return "yml", nil
+	}
+	return "", fmt.Errorf("unknown format %s, use json or yaml", name)
+}
+
+// convertDefinition writes a definition of type from in type to. Keys keep
+// their order and numbers their digits; the comments of YAML are lost in
+// JSON, and JSON becomes YAML in block style.
+func convertDefinition(definitionPath string, content []byte, from string, to string) ([]byte, error) {
+	if from == to {
+		return content, nil
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, fmt.Errorf("can't parse %s: %v", definitionPath, err)
+	}
+	if len(root.Content) == 0 {
+		return nil, fmt.Errorf("%s is empty", definitionPath)
+	}
+	if from == "json" {
+		plainStyle(root.Content[0])
+	}
+	converted, err := encodeDefinition(root.Content[0], to)
+	if err != nil {
+		return nil, fmt.Errorf("can't convert %s: %v", definitionPath, err)
+	}
+	return converted, nil
+}
+
+// encodeDefinition writes a parsed definition as JSON, indented by two
+// spaces, or as YAML.
+func encodeDefinition(node *yaml.Node, format string) ([]byte, error) {
+	var encoded bytes.Buffer
+	if format == "json" {
+		if err := writeJsonNode(&encoded, node, ""); err != nil {
+			return nil, err
+		}
+		encoded.WriteString("\n")
+		return encoded.Bytes(), nil
+	}
+	encoder := yaml.NewEncoder(&encoded)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	encoder.Close()
+	return encoded.Bytes(), nil
+}
+
+// plainStyle drops the flow style and quotes JSON has, the encoder quoting
+// again the strings that need it. Strings YAML 1.1 reads as booleans, as
+// yes, keep their quotes for the parsers still following it.
+func plainStyle(node *yaml.Node) {
+	node.Style = 0
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" && containsString(yaml11Booleans, strings.ToLower(node.Value)) {
+		node.Style = yaml.DoubleQuotedStyle
+	}
+	for _, child := range node.Content {
+		plainStyle(child)
+	}
+}
+
+func writeJsonNode(output *bytes.Buffer, node *yaml.Node, indent string) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		return writeJsonNode(output, node.Content[0], indent)
+	case yaml.AliasNode:
+		return writeJsonNode(output, node.Alias, indent)
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			output.WriteString("[]")
+			return nil
+		}
+		output.WriteString("[\n")
+		for i, item := range node.Content {
+			output.WriteString(indent + "  ")
+			if err := writeJsonNode(output, item, indent+"  "); err != nil {
+				return err
+			}
+			if i < len(node.Content)-1 {
+				output.WriteString(",")
+			}
+			output.WriteString("\n")
+		}
+		output.WriteString(indent + "]")
+	case yaml.MappingNode:
+		pairs := mergedPairs(node)
+		if len(pairs) == 0 {
+			output.WriteString("{}")
+			return nil
+		}
+		output.WriteString("{\n")
+		for i := 0; i+1 < len(pairs); i += 2 {
+			output.WriteString(indent + "  " + jsonString(pairs[i].Value) + ": ")
+			if err := writeJsonNode(output, pairs[i+1], indent+"  "); err != nil {
+				return err
+			}
+			if i+2 < len(pairs) {
+				output.WriteString(",")
+			}
+			output.WriteString("\n")
+		}
+		output.WriteString(indent + "}")
+	default:
+		scalar, err := jsonScalar(node)
+		if err != nil {
+			return err
+		}
+		output.WriteString(scalar)
+	}
+	return nil
+}
+
+// mergedPairs are the keys and values of a mapping with the ones of the
+// mappings merged in by <<, which its own keys override.
+func mergedPairs(node *yaml.Node) []*yaml.Node {
+	var pairs []*yaml.Node
+	index := map[string]int{}
+	add := func(key *yaml.Node, value *yaml.Node, override bool) {
+		if i, ok := index[key.Value]; ok {
+			if override {
+				pairs[i+1] = value
+			}
+			return
+		}
+		index[key.Value] = len(pairs)
+		pairs = append(pairs, key, value)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Tag != "!!merge" {
+			add(node.Content[i], node.Content[i+1], true)
+			continue
+		}
+		merged := []*yaml.Node{node.Content[i+1]}
+		if merged[0].Kind == yaml.SequenceNode {
+			merged = merged[0].Content
+		}
+		for _, mapping := range merged {
+			if mapping.Kind == yaml.AliasNode {
+				mapping = mapping.Alias
+			}
+			merge := mergedPairs(mapping)
+			for j := 0; j+1 < len(merge); j += 2 {
+				add(merge[j], merge[j+1], false)
+			}
+		}
+	}
+	return pairs
+}
+
+// jsonScalar writes a YAML scalar as its JSON value. Numbers that are valid
+// JSON keep their text, as 1.10, and the others are written in decimal.
+func jsonScalar(node *yaml.Node) (string, error) {
+	switch node.Tag {
+	case "!!null":
+		return "null", nil
+	case "!!bool":
+		var value bool
+		if err := node.Decode(&value); err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(value), nil
+	case "!!int", "!!float":
+		if json.Valid([]byte(node.Value)) {
+			return node.Value, nil
+		}
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return "", err
+		}
+		if number, ok := value.(float64); ok {
+			if math.IsInf(number, 0) || math.IsNaN(number) {
+				return "", fmt.Errorf("line %d: %s has no JSON value", node.Line, node.Value)
+			}
+			return strconv.FormatFloat(number, 'g', -1, 64), nil
+		}
+		return fmt.Sprint(value), nil
+	}
+	return jsonString(node.Value), nil
+}
+
+// jsonString quotes a string as JSON, leaving <, > and & as they are.
+func jsonString(value string) string {
+	var quoted bytes.Buffer
+	encoder := json.NewEncoder(&quoted)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+	return strings.TrimSuffix(quoted.String(), "\n")
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// maxAccessTokenSize bounds what --access-token-stdin reads, a token is far
+// smaller.
+const maxAccessTokenSize = 64 * 1024
+
+// useAccessTokenSource reads the token from the file of --access-token-file,
+// as a mounted secret, or from the standard input with --access-token-stdin,
+// as piped by a secret manager, so it's neither in the arguments nor in the
+// environment of the process. Both replace --access-token.
+func useAccessTokenSource(path string, stdin bool, accessToken *string) {
+	token, _, err := readAccessTokenSource(path, stdin)
+	if err != nil {
+		exitAndError(err)
+	}
+	if token != "" {
+		*accessToken = token
+	}
+}
+
+// readAccessTokenSource returns the token of the file or of the standard
+// input with where it came from, or nothing when neither is given.
+func readAccessTokenSource(path string, stdin bool) (string, string, error) {
+	switch {
+	case path != "" && stdin:
+		return "", "", errors.New("use either --access-token-file or --access-token-stdin")
+	case path != "":
+		token, err := readAccessTokenFile(path)
+		return token, "file " + path, err
+	case stdin:
+		token, err := readAccessTokenStdin()
+		return token, "standard input", err
+	}
+	return "", "", nil
+}
+
+// readAccessTokenFile returns the content of the file without the spaces
+// and line breaks around it.
+func readAccessTokenFile(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("can't read the access token from %s", path)
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return "", fmt.Errorf("the access token file %s is empty", path)
+	}
+	return token, nil
+}
+
+func readAccessTokenStdin() (string, error) {
+	content, err := ioutil.ReadAll(io.LimitReader(os.Stdin, maxAccessTokenSize))
+	if err != nil {
+		return "", fmt.Errorf("can't read the access token from the standard input: %w", err)
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return "", errors.New("there is no access token on the standard input")
+	}
+	return token, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+type deleteOptions struct {
+	SwaggerHubApi string `flag:"api" env:"SWAGGERHUB_API" required:"true"`
+	Version       string `flag:"version"`
+	AllVersions   bool   `flag:"all-versions"`
+	Confirm       string `flag:"confirm"`
+	Yes           bool   `flag:"yes"`
+	Output        string `flag:"output" default:"text"`
+	connectionOptions
+}
+
+// deleteResult is what delete prints with --output json. The version is
+// left out when the whole API was deleted.
+type deleteResult struct {
+	Status  string `json:"status"`
+	Api     string `json:"api"`
+	Version string `json:"version,omitempty"`
+}
+
+// deleteCommand deletes a version of an API, once confirmed on the terminal
+// or with --yes. The whole API is deleted with --all-versions, which needs
+// its name repeated in --confirm instead.
+func deleteCommand(args []string) {
+	options := deleteOptions{}
+	parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+	options.useConnection(&options.SwaggerHubApi)
+	if options.SwaggerHubAccessToken == "" {
+		exitAndError("missing access-token")
+	}
+	if len(strings.Split(options.SwaggerHubApi, "/")) != 2 {
+		exitAndError("api is in the wrong format")
+	}
+	registry := swaggerHub(swaggerHubUrls, options.SwaggerHubAccessToken)
+
+	if options.AllVersions {
+		if options.Version != "" {
+			exitAndError("use either --version or --all-versions")
+		}
+		if options.Confirm != options.SwaggerHubApi {
+			exitAndError(fmt.Sprintf("deleting every version needs --confirm %s", options.SwaggerHubApi))
+		}
+		if err := registry.DeleteApi(runContext, options.SwaggerHubApi); err != nil {
+			exitAndError(fmt.Errorf("can't delete %s: %w", options.SwaggerHubApi, err))
+		}
+		log.Printf("%s deleted with all its versions", options.SwaggerHubApi)
+		if outputJson {
+			printJson(deleteResult{Status: "deleted", Api: options.SwaggerHubApi})
+		}
+		return
+	}
+	if options.Version == "" {
+		exitAndError("missing version, or --all-versions to delete the whole API")
+	}
+
+	if !options.Yes {
+		if !isTerminal(os.Stdin) {
+			exitAndError("--yes is needed when the deletion can't be confirmed on a terminal")
+		}
+		if !confirmed(fmt.Sprintf("delete %s %s?", options.SwaggerHubApi, options.Version)) {
+			exitAndError("nothing was deleted")
+		}
+	}
+
+	if err := registry.DeleteVersion(runContext, options.SwaggerHubApi, options.Version); err != nil {
+		exitAndError(fmt.Errorf("can't delete %s %s: %w", options.SwaggerHubApi, options.Version, err))
+	}
+	log.Printf("%s %s deleted", options.SwaggerHubApi, options.Version)
+	if outputJson {
+		printJson(deleteResult{Status: "deleted", Api: options.SwaggerHubApi, Version: options.Version})
+	}
+}
@@ -0,0 +1,559 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type diffOptions struct {
+	SwaggerHubApi  string `flag:"api" env:"SWAGGERHUB_API" required:"true"`
+	ApiVersion     string `flag:"api-version"`
+	FailOnBreaking bool   `flag:"fail-on-breaking"`
+	Output         string `flag:"output" default:"text"`
+	connectionOptions
+}
+
+// specChange is a path, operation, parameter or schema added, removed or
+// changed between two versions of a definition. Breaking lists why the
+// change can break existing clients.
+type specChange struct {
+	Kind     string
+	Action   string
+	Name     string
+	Breaking []string
+}
+
+var specChangeKinds = []string{"paths", "operations", "parameters", "schemas"}
+
+var specChangeSymbols = map[string]string{"added": "+", "removed": "-", "changed": "~"}
+
+// diffCommand compares a local definition with a version on SwaggerHub, the
+// default one unless --api-version is given. Both documents are compared
+// parsed, so formatting and key order don't count. With --fail-on-breaking
+// it exits with exitBreaking when a change can break existing clients.
+func diffCommand(args []string) {
+	options := diffOptions{}
+	positional := parseArgs(&options, args)
+	options.useConnection(&options.SwaggerHubApi)
+	if len(positional) != 1 {
+		exitAndError("diff needs the path to the OpenAPI definition")
+	}
+	if len(strings.Split(options.SwaggerHubApi, "/")) != 2 {
+		exitAndError("api is in the wrong format")
+	}
+	useOutput(options.Output, "text", "github", "json")
+
+	openApiPath := positional[0]
+	openApi, err := readDefinition(openApiPath, "")
+	if err != nil {
+		exitAndError(err)
+	}
+	local, err := parseOpenApiDocument(openApiPath, openApi)
+	if err != nil {
+		exitAndError(err)
+	}
+
+	// a cached copy would hide what SwaggerHub has now
+	swaggerHubCache = nil
+	if options.ApiVersion == "" {
+		options.ApiVersion = defaultVersion(options.SwaggerHubApi, options.SwaggerHubAccessToken)
+	}
+	remote, err := fetchDocument(options.SwaggerHubApi, options.ApiVersion, options.SwaggerHubAccessToken)
+	if err != nil {
+		exitAndError(fmt.Errorf("can't fetch %s %s: %w", options.SwaggerHubApi, options.ApiVersion, err))
+	}
+
+	changes := diffSpecs(remote, local)
+	if outputJson {
+		printDiffResult(openApiPath, changes, &options)
+		return
+	}
+	if len(changes) == 0 {
+		log.Printf("%s has no changes from %s %s", openApiPath, options.SwaggerHubApi, options.ApiVersion)
+		return
+	}
+	fmt.Printf("%s compared with %s %s\n", openApiPath, options.SwaggerHubApi, options.ApiVersion)
+	for _, kind := range specChangeKinds {
+		printed := false
+		for _, change := range changes {
+			if change.Kind != kind {
+				continue
+			}
+			if !printed {
+				fmt.Printf("\n%s:\n", kind)
+				printed = true
+			}
+			fmt.Printf("  %s %s\n", specChangeSymbols[change.Action], change.Name)
+			for _, reason := range change.Breaking {
+				fmt.Printf("      breaking: %s\n", reason)
+			}
+		}
+	}
+
+	breaking := 0
+	for _, change := range changes {
+		if len(change.Breaking) > 0 {
+			breaking++
+		}
+	}
+	fmt.Printf("\n%d changes, %d breaking\n", len(changes), breaking)
+	if options.Output == "github" {
+		annotateChanges(local, changes, options.FailOnBreaking)
+	}
+	if options.FailOnBreaking && breaking > 0 {
+		exitWithCode(exitBreaking, fmt.Sprintf("%s has breaking changes from %s %s", openApiPath, options.SwaggerHubApi, options.ApiVersion))
+	}
+}
+
+// diffResult is the JSON output of diff.
+type diffResult struct {
+	Status   string       `json:"status"`
+	Path     string       `json:"path"`
+	Api      string       `json:"api"`
+	Version  string       `json:"version"`
+	Breaking int          `json:"breaking"`
+	Changes  []diffChange `json:"changes"`
+}
+
+type diffChange struct {
+	Kind     string   `json:"kind"`
+	Action   string   `json:"action"`
+	Name     string   `json:"name"`
+	Breaking []string `json:"breaking,omitempty"`
+}
+
+// printDiffResult prints the changes with --output json, exiting on breaking
+// changes with --fail-on-breaking as the text output does.
+func printDiffResult(openApiPath string, changes []specChange, options *diffOptions) {
+	result := diffResult{Status: "unchanged", Path: openApiPath, Api: options.SwaggerHubApi, Version: options.ApiVersion, Changes: []diffChange{}}
+	for _, change := range changes {
+		result.Status = "changed"
+		if len(change.Breaking) > 0 {
+			result.Breaking++
+		}
+		result.Changes = append(result.Changes, diffChange{Kind: change.Kind, Action: change.Action, Name: change.Name, Breaking: change.Breaking})
+	}
+	code := exitOk
+	if options.FailOnBreaking && result.Breaking > 0 {
+		code = exitBreaking
+	}
+	printJsonResult(result, code, fmt.Sprintf("%s has breaking changes from %s %s", openApiPath, options.SwaggerHubApi, options.ApiVersion))
+}
+
+// annotateChanges prints a GitHub Actions annotation per change, on its line
+// of the local definition when it is still there. Breaking changes are errors
+// when they fail the run and warnings otherwise.
+func annotateChanges(document *openApiDocument, changes []specChange, failOnBreaking bool) {
+	for _, change := range changes {
+		level, title := "notice", "change"
+		message := fmt.Sprintf("%s %s %s", change.Action, strings.TrimSuffix(change.Kind, "s"), change.Name)
+		if len(change.Breaking) > 0 {
+			level, title = "warning", "breaking change"
+			if failOnBreaking {
+				level = "error"
+			}
+			message += ": " + strings.Join(change.Breaking, ", ")
+		}
+		if err := githubAnnotation(os.Stdout, level, document.Path, changeLine(document, change), title, message); err != nil {
+			exitAndError(fmt.Errorf("can't write the output: %w", err))
+		}
+	}
+}
+
+// changeLine is the line of what changed in the document, or 0 when it was
+// removed.
+func changeLine(document *openApiDocument, change specChange) int {
+	if change.Action == "removed" {
+		return 0
+	}
+	keys := []string{"paths", change.Name}
+	switch change.Kind {
+	case "operations", "parameters":
+		fields := strings.Fields(change.Name)
+		if len(fields) < 2 {
+			return 0
+		}
+		keys = []string{"paths", fields[1], strings.ToLower(fields[0])}
+	case "schemas":
+		keys = []string{"components", "schemas", change.Name}
+		if document.isSwagger2() {
+			keys = []string{"definitions", change.Name}
+		}
+	}
+
+	line := 0
+	eachMapping(document.lookup(keys[:len(keys)-1]...), func(key *yaml.Node, _ *yaml.Node) {
+		if key.Value == keys[len(keys)-1] {
+			line = key.Line
+		}
+	})
+	return line
+}
+
+// defaultVersion returns the default version of an API, or stops when it
+// has none.
+func defaultVersion(api string, accessToken string) string {
+	versions, err := listVersions(swaggerHubUrls, api, accessToken)
+	if err != nil {
+		exitAndError(fmt.Errorf("can't list the versions of %s: %w", api, err))
+	}
+	for _, version := range versions {
+		if version.Default {
+			return version.Version
+		}
+	}
+	exitAndError(fmt.Sprintf("%s has no default version, use --api-version", api))
+	return ""
+}
+
+// diffSpecs compares the paths, operations, parameters and schemas of two
+// documents. Parameters are compared by location and name, with path level
+// parameters applied to every operation of the path and references
+// resolved.
+func diffSpecs(base *openApiDocument, document *openApiDocument) []specChange {
+	var changes []specChange
+	usages := schemaUsages(base, document)
+	compare := func(kind string, before map[string]interface{}, after map[string]interface{}) {
+		for _, name := range sortedKeys(before, after) {
+			beforeValue, inBefore := before[name]
+			afterValue, inAfter := after[name]
+			usage := requestUsage
+			if kind == "schemas" {
+				usage = usages.of(name)
+			}
+			switch {
+			case !inAfter:
+				changes = append(changes, specChange{Kind: kind, Action: "removed", Name: name, Breaking: breakingChanges(kind, "removed", beforeValue, nil, usage)})
+			case !inBefore:
+				changes = append(changes, specChange{Kind: kind, Action: "added", Name: name, Breaking: breakingChanges(kind, "added", nil, afterValue, usage)})
+			case fingerprint(beforeValue) != fingerprint(afterValue):
+				changes = append(changes, specChange{Kind: kind, Action: "changed", Name: name, Breaking: breakingChanges(kind, "changed", beforeValue, afterValue, usage)})
+			}
+		}
+	}
+
+	compare("paths", documentPaths(base), documentPaths(document))
+
+	diff := diffOperations(base, document)
+	for _, operation := range diff.Added {
+		changes = append(changes, specChange{Kind: "operations", Action: "added", Name: operation.String()})
+	}
+	for _, operation := range diff.Removed {
+		changes = append(changes, specChange{Kind: "operations", Action: "removed", Name: operation.String(), Breaking: []string{"removed operation"}})
+	}
+
+	baseOperations := map[string]openApiOperation{}
+	for _, operation := range base.operations() {
+		baseOperations[operation.String()] = operation
+	}
+	for _, operation := range diff.Changed {
+		changes = append(changes, specChange{Kind: "operations", Action: "changed", Name: operation.String(), Breaking: operationBreakingChanges(base, baseOperations[operation.String()], document, operation)})
+	}
+	for _, operation := range document.operations() {
+		if baseOperation, ok := baseOperations[operation.String()]; ok {
+			compare("parameters", operationParameters(base, baseOperation), operationParameters(document, operation))
+		}
+	}
+
+	compare("schemas", documentSchemas(base), documentSchemas(document))
+	return changes
+}
+
+// breakingChanges classifies a change: removed paths, operations and
+// schemas, new required parameters and changed types break clients. What
+// breaks in a schema depends on who sends it: new required fields and
+// narrowed enums break the clients sending requests, while fields no longer
+// required and widened enums break the ones reading responses.
+func breakingChanges(kind string, action string, before interface{}, after interface{}, usage schemaUsage) []string {
+	switch {
+	case action == "removed" && kind == "paths":
+		return []string{"removed path"}
+	case action == "removed" && kind == "schemas":
+		return []string{"removed schema"}
+	case action == "added" && kind == "parameters":
+		if required, _ := valueMap(after)["required"].(bool); required {
+			return []string{"new required parameter"}
+		}
+	case action == "changed" && kind == "parameters":
+		var reasons []string
+		wasRequired, _ := valueMap(before)["required"].(bool)
+		if required, _ := valueMap(after)["required"].(bool); required && !wasRequired {
+			reasons = append(reasons, "the parameter became required")
+		}
+		return append(reasons, schemaBreakingChanges(parameterSchema(before), parameterSchema(after), "the parameter", requestUsage)...)
+	case action == "changed" && kind == "schemas":
+		return schemaBreakingChanges(valueMap(before), valueMap(after), "the schema", usage)
+	}
+	return nil
+}
+
+// operationBreakingChanges compares the request body and response schemas
+// of two versions of an operation, media type by media type. Referenced
+// named schemas are compared with the schemas.
+func operationBreakingChanges(base *openApiDocument, baseOperation openApiOperation, document *openApiDocument, operation openApiOperation) []string {
+	var reasons []string
+	compare := func(before map[string]interface{}, after map[string]interface{}, usage schemaUsage) {
+		for _, name := range sortedKeys(before) {
+			if afterSchema, ok := after[name]; ok {
+				reasons = append(reasons, schemaBreakingChanges(valueMap(before[name]), valueMap(afterSchema), "the "+name, usage)...)
+			}
+		}
+	}
+	baseRequests, baseResponses := operationSchemas(base, baseOperation)
+	requests, responses := operationSchemas(document, operation)
+	compare(baseRequests, requests, requestUsage)
+	compare(baseResponses, responses, schemaUsage{response: true})
+	return reasons
+}
+
+// operationSchemas returns the schemas of the request body of an operation
+// by "request body media-type" and of its responses by "status response
+// media-type". Swagger 2.0 responses have a single schema, and its body
+// parameters are compared with the parameters.
+func operationSchemas(document *openApiDocument, operation openApiOperation) (map[string]interface{}, map[string]interface{}) {
+	requests, responses := map[string]interface{}{}, map[string]interface{}{}
+	eachMapping(mappingValue(document.resolve(mappingValue(operation.Node, "requestBody")), "content"), func(media *yaml.Node, content *yaml.Node) {
+		if schema := mappingValue(content, "schema"); schema != nil {
+			requests["request body "+media.Value] = nodeValue(schema)
+		}
+	})
+	eachMapping(mappingValue(operation.Node, "responses"), func(status *yaml.Node, response *yaml.Node) {
+		response = document.resolve(response)
+		if schema := mappingValue(response, "schema"); schema != nil {
+			responses[status.Value+" response"] = nodeValue(schema)
+		}
+		eachMapping(mappingValue(response, "content"), func(media *yaml.Node, content *yaml.Node) {
+			if schema := mappingValue(content, "schema"); schema != nil {
+				responses[status.Value+" response "+media.Value] = nodeValue(schema)
+			}
+		})
+	})
+	return requests, responses
+}
+
+// schemaBreakingChanges compares the type, enum and required fields of two
+// versions of a schema, going into the properties and items they share.
+func schemaBreakingChanges(before map[string]interface{}, after map[string]interface{}, at string, usage schemaUsage) []string {
+	var reasons []string
+	if beforeType, afterType := fingerprint(before["type"]), fingerprint(after["type"]); before["type"] != nil && after["type"] != nil && beforeType != afterType {
+		reasons = append(reasons, fmt.Sprintf("changed type of %s from %s to %s", at, beforeType, afterType))
+	}
+
+	beforeEnum, hadEnum := before["enum"].([]interface{})
+	afterEnum, hasEnum := after["enum"].([]interface{})
+	if usage.request && hasEnum {
+		if !hadEnum {
+			reasons = append(reasons, fmt.Sprintf("narrowed %s to an enum", at))
+		}
+		for _, value := range missingValues(beforeEnum, afterEnum) {
+			reasons = append(reasons, fmt.Sprintf("narrowed the enum of %s, removing %s", at, value))
+		}
+	}
+	if usage.response && hadEnum {
+		if !hasEnum {
+			reasons = append(reasons, fmt.Sprintf("removed the enum of %s", at))
+		}
+		for _, value := range missingValues(afterEnum, beforeEnum) {
+			reasons = append(reasons, fmt.Sprintf("widened the enum of %s, adding %s", at, value))
+		}
+	}
+
+	beforeRequired, _ := before["required"].([]interface{})
+	afterRequired, _ := after["required"].([]interface{})
+	if usage.request {
+		for _, name := range missingValues(afterRequired, beforeRequired) {
+			reasons = append(reasons, fmt.Sprintf("new required field %s in %s", strings.Trim(name, `"`), at))
+		}
+	}
+	if usage.response {
+		for _, name := range missingValues(beforeRequired, afterRequired) {
+			reasons = append(reasons, fmt.Sprintf("the field %s of %s is no longer required", strings.Trim(name, `"`), at))
+		}
+	}
+
+	beforeProperties, afterProperties := valueMap(before["properties"]), valueMap(after["properties"])
+	for _, name := range sortedKeys(beforeProperties) {
+		if _, ok := afterProperties[name]; ok {
+			reasons = append(reasons, schemaBreakingChanges(valueMap(beforeProperties[name]), valueMap(afterProperties[name]), at+"."+name, usage)...)
+		}
+	}
+	if before["items"] != nil && after["items"] != nil {
+		reasons = append(reasons, schemaBreakingChanges(valueMap(before["items"]), valueMap(after["items"]), at+"[]", usage)...)
+	}
+	return reasons
+}
+
+// missingValues are the values of from that aren't in to, as JSON.
+func missingValues(from []interface{}, to []interface{}) []string {
+	present := map[string]bool{}
+	for _, value := range to {
+		present[fingerprint(value)] = true
+	}
+	var missing []string
+	for _, value := range from {
+		if !present[fingerprint(value)] {
+			missing = append(missing, fingerprint(value))
+		}
+	}
+	return missing
+}
+
+// schemaUsage tells whether a schema is sent by clients, in parameters and
+// request bodies, or read by them, in responses.
+type schemaUsage struct {
+	request  bool
+	response bool
+}
+
+var requestUsage = schemaUsage{request: true}
+
+// namedSchemaUsages are the usages of the named schemas of documents.
+type namedSchemaUsages map[string]schemaUsage
+
+// of is the usage of a named schema. Schemas no operation uses, as the ones
+// shared with other definitions, could be used both ways.
+func (usages namedSchemaUsages) of(name string) schemaUsage {
+	if usage, ok := usages[name]; ok {
+		return usage
+	}
+	return schemaUsage{request: true, response: true}
+}
+
+// schemaUsages finds where the operations of the documents use the named
+// schemas, following references through components and other schemas. The
+// requests of webhooks are sent to clients, so they count as responses.
+func schemaUsages(documents ...*openApiDocument) namedSchemaUsages {
+	usages := namedSchemaUsages{}
+	for _, document := range documents {
+		walk := func(node *yaml.Node, response bool) {
+			visited := map[*yaml.Node]bool{}
+			var visit func(node *yaml.Node)
+			visit = func(node *yaml.Node) {
+				if node == nil || visited[node] {
+					return
+				}
+				visited[node] = true
+				if ref := scalarValue(mappingValue(node, "$ref")); strings.HasPrefix(ref, "#/") {
+					keys := splitPointer(ref)
+					if name := namedSchema(keys); name != "" {
+						usage := usages[name]
+						if response {
+							usage.response = true
+						} else {
+							usage.request = true
+						}
+						usages[name] = usage
+					}
+					visit(document.lookup(keys...))
+				}
+				for _, child := range node.Content {
+					visit(child)
+				}
+			}
+			visit(node)
+		}
+
+		for _, operation := range append(document.operations(), document.webhooks()...) {
+			item := operation.keys()[:2]
+			for _, node := range []*yaml.Node{document.lookup(append(item, "parameters")...), mappingValue(operation.Node, "parameters"), mappingValue(operation.Node, "requestBody")} {
+				walk(node, operation.Webhook)
+			}
+			walk(mappingValue(operation.Node, "responses"), !operation.Webhook)
+		}
+	}
+	return usages
+}
+
+// namedSchema is the name of the schema a pointer points to, if it points to
+// one of components.schemas or definitions.
+func namedSchema(keys []string) string {
+	switch {
+	case len(keys) == 3 && keys[0] == "components" && keys[1] == "schemas":
+		return keys[2]
+	case len(keys) == 2 && keys[0] == "definitions":
+		return keys[1]
+	}
+	return ""
+}
+
+// parameterSchema is the schema of an OpenAPI 3 parameter, or the parameter
+// itself in Swagger 2.0 where it carries the type and enum.
+func parameterSchema(parameter interface{}) map[string]interface{} {
+	if schema, ok := valueMap(parameter)["schema"]; ok {
+		return valueMap(schema)
+	}
+	return valueMap(parameter)
+}
+
+func valueMap(value interface{}) map[string]interface{} {
+	if values, ok := value.(map[string]interface{}); ok {
+		return values
+	}
+	return map[string]interface{}{}
+}
+
+func documentPaths(document *openApiDocument) map[string]interface{} {
+	paths := map[string]interface{}{}
+	eachMapping(document.lookup("paths"), func(path *yaml.Node, item *yaml.Node) {
+		paths[path.Value] = nodeValue(item)
+	})
+	return paths
+}
+
+// operationParameters returns the parameters of an operation by
+// "METHOD /path in name".
+func operationParameters(document *openApiDocument, operation openApiOperation) map[string]interface{} {
+	parameters := map[string]interface{}{}
+	for _, list := range []*yaml.Node{document.lookup("paths", operation.Path, "parameters"), mappingValue(operation.Node, "parameters")} {
+		if list == nil {
+			continue
+		}
+		for _, parameter := range list.Content {
+			parameter = document.resolve(parameter)
+			name := scalarValue(mappingValue(parameter, "name"))
+			if name == "" {
+				continue
+			}
+			parameters[fmt.Sprintf("%s %s %s", operation, scalarValue(mappingValue(parameter, "in")), name)] = nodeValue(parameter)
+		}
+	}
+	return parameters
+}
+
+// documentSchemas returns the named schemas, from components.schemas or the
+// definitions of Swagger 2.0.
+func documentSchemas(document *openApiDocument) map[string]interface{} {
+	schemas := map[string]interface{}{}
+	for _, node := range []*yaml.Node{document.lookup("components", "schemas"), document.lookup("definitions")} {
+		eachMapping(node, func(name *yaml.Node, schema *yaml.Node) {
+			schemas[name.Value] = nodeValue(schema)
+		})
+	}
+	return schemas
+}
+
+func fingerprint(value interface{}) string {
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+func sortedKeys(maps ...map[string]interface{}) []string {
+	keys := map[string]bool{}
+	for _, values := range maps {
+		for key := range values {
+			keys[key] = true
+		}
+	}
+	var sorted []string
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBreakingChanges(t *testing.T) {
+	responseUsage := schemaUsage{response: true}
+	bothUsage := schemaUsage{request: true, response: true}
+	object := func(values ...interface{}) map[string]interface{} {
+		result := map[string]interface{}{}
+		for i := 0; i < len(values); i += 2 {
+			result[values[i].(string)] = values[i+1]
+		}
+		return result
+	}
+	list := func(values ...interface{}) []interface{} {
+		return values
+	}
+
+	tests := []struct {
+		name   string
+		kind   string
+		action string
+		before interface{}
+		after  interface{}
+		usage  schemaUsage
+		want   []string
+	}{
+		{"removed path", "paths", "removed", object(), nil, requestUsage, []string{"removed path"}},
+		{"added path", "paths", "added", nil, object(), requestUsage, nil},
+		{"removed schema", "schemas", "removed", object(), nil, responseUsage, []string{"removed schema"}},
+		{"added optional parameter", "parameters", "added", nil, object("name", "limit"), requestUsage, nil},
+		{"added required parameter", "parameters", "added", nil, object("name", "id", "required", true), requestUsage, []string{"new required parameter"}},
+		{"removed parameter", "parameters", "removed", object("name", "limit"), nil, requestUsage, nil},
+		{"parameter became required", "parameters", "changed", object("required", false), object("required", true), requestUsage, []string{"the parameter became required"}},
+		{"parameter no longer required", "parameters", "changed", object("required", true), object("required", false), requestUsage, nil},
+		{"parameter type changed", "parameters", "changed", object("schema", object("type", "integer")), object("schema", object("type", "string")), requestUsage, []string{`changed type of the parameter from "integer" to "string"`}},
+		{"parameter enum narrowed", "parameters", "changed", object("schema", object("enum", list("a", "b"))), object("schema", object("enum", list("a"))), requestUsage, []string{`narrowed the enum of the parameter, removing "b"`}},
+		{"request field required", "schemas", "changed", object("required", list()), object("required", list("name")), requestUsage, []string{"new required field name in the schema"}},
+		{"response field required", "schemas", "changed", object("required", list()), object("required", list("name")), responseUsage, nil},
+		{"response field no longer required", "schemas", "changed", object("required", list("name")), object("required", list()), responseUsage, []string{"the field name of the schema is no longer required"}},
+		{"request field no longer required", "schemas", "changed", object("required", list("name")), object("required", list()), requestUsage, nil},
+		{"request enum added", "schemas", "changed", object("type", "string"), object("type", "string", "enum", list("a")), requestUsage, []string{"narrowed the schema to an enum"}},
+		{"response enum removed", "schemas", "changed", object("type", "string", "enum", list("a")), object("type", "string"), responseUsage, []string{"removed the enum of the schema"}},
+		{"response enum widened", "schemas", "changed", object("enum", list("a")), object("enum", list("a", "b")), responseUsage, []string{`widened the enum of the schema, adding "b"`}},
+		{"request enum widened", "schemas", "changed", object("enum", list("a")), object("enum", list("a", "b")), requestUsage, nil},
+		{"enum in both directions", "schemas", "changed", object("enum", list("a", "b")), object("enum", list("a", "c")), bothUsage, []string{`narrowed the enum of the schema, removing "b"`, `widened the enum of the schema, adding "c"`}},
+		{"nested property type", "schemas", "changed", object("properties", object("id", object("type", "integer"))), object("properties", object("id", object("type", "string"))), responseUsage, []string{`changed type of the schema.id from "integer" to "string"`}},
+		{"items type", "schemas", "changed", object("items", object("type", "integer")), object("items", object("type", "number")), requestUsage, []string{`changed type of the schema[] from "integer" to "number"`}},
+		{"description only", "schemas", "changed", object("type", "string", "description", "a"), object("type", "string", "description", "b"), bothUsage, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := breakingChanges(test.kind, test.action, test.before, test.after, test.usage)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestOperationBreakingChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   []string
+	}{
+		{"request body field required", `requestBody: {content: {application/json: {schema: {type: object, required: []}}}}`, `requestBody: {content: {application/json: {schema: {type: object, required: [name]}}}}`, []string{"new required field name in the request body application/json"}},
+		{"request body enum widened", `requestBody: {content: {application/json: {schema: {enum: [a]}}}}`, `requestBody: {content: {application/json: {schema: {enum: [a, b]}}}}`, nil},
+		{"referenced request body", `requestBody: {$ref: '#/components/requestBodies/Pet'}`, `requestBody: {$ref: '#/components/requestBodies/NewPet'}`, []string{`changed type of the request body application/json.id from "integer" to "string"`}},
+		{"response field no longer required", `responses: {"200": {description: ok, content: {application/json: {schema: {required: [name]}}}}}`, `responses: {"200": {description: ok, content: {application/json: {schema: {required: []}}}}}`, []string{"the field name of the 200 response application/json is no longer required"}},
+		{"response enum widened", `responses: {"200": {description: ok, content: {application/json: {schema: {enum: [a]}}}}}`, `responses: {"200": {description: ok, content: {application/json: {schema: {enum: [a, b]}}}}}`, []string{`widened the enum of the 200 response application/json, adding "b"`}},
+		{"response field required", `responses: {"200": {description: ok, content: {application/json: {schema: {required: []}}}}}`, `responses: {"200": {description: ok, content: {application/json: {schema: {required: [name]}}}}}`, nil},
+		{"named schema left to the schemas", `responses: {"200": {description: ok, content: {application/json: {schema: {$ref: '#/components/schemas/Pet'}}}}}`, `responses: {"200": {description: ok, content: {application/json: {schema: {$ref: '#/components/schemas/NewPet'}}}}}`, nil},
+	}
+	components := "components:\n  requestBodies:\n    Pet: {content: {application/json: {schema: {properties: {id: {type: integer}}}}}}\n    NewPet: {content: {application/json: {schema: {properties: {id: {type: string}}}}}}\n  schemas:\n    Pet: {type: object}\n    NewPet: {type: object}\n"
+	document := func(operation string) *openApiDocument {
+		document, err := parseOpenApiDocument("openapi.yml", []byte("openapi: 3.0.0\ninfo: {title: Pets, version: \"1.0\"}\npaths:\n  /pets:\n    post: {"+operation+"}\n"+components))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return document
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			found := false
+			for _, change := range diffSpecs(document(test.before), document(test.after)) {
+				if change.Kind == "operations" && change.Name == "POST /pets" {
+					got, found = change.Breaking, true
+				}
+			}
+			if !found {
+				t.Fatal("the operation didn't change")
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestOperationBreakingChangesSwagger2(t *testing.T) {
+	document := func(schema string) *openApiDocument {
+		document, err := parseOpenApiDocument("swagger.yml", []byte("swagger: \"2.0\"\ninfo: {title: Pets, version: \"1.0\"}\npaths:\n  /pets:\n    get:\n      responses: {\"200\": {description: ok, schema: "+schema+"}}\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return document
+	}
+	changes := diffSpecs(document("{type: integer}"), document("{type: string}"))
+	if want := []string{`changed type of the 200 response from "integer" to "string"`}; len(changes) != 2 || !reflect.DeepEqual(changes[1].Breaking, want) {
+		t.Errorf("got %+v, want %q", changes, want)
+	}
+}
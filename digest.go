@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+type digestOptions struct {
+	Owner   string `flag:"owner"`
+	Since   string `flag:"since" default:"168h"`
+	NoEmail bool   `flag:"no-email"`
+	Ruleset string `flag:"ruleset" env:"SWAGGERGO_RULESET"`
+	Output  string `flag:"output" default:"text"`
+	connectionOptions
+}
+
+// digestEntry is a version created during the period of the digest.
+type digestEntry struct {
+	Api        string         `json:"api"`
+	Version    string         `json:"version"`
+	Created    time.Time      `json:"created"`
+	Breaking   []string       `json:"breaking,omitempty"`
+	Violations map[string]int `json:"violations,omitempty"`
+}
+
+// digestResult is what digest prints with --output json, instead of the
+// report.
+type digestResult struct {
+	Owner    string        `json:"owner"`
+	Since    time.Time     `json:"since"`
+	Until    time.Time     `json:"until"`
+	Versions []digestEntry `json:"versions"`
+}
+
+// digestCommand reports the versions created across an owner since a given
+// time, with the operations they removed from the previous version and the
+// errors the rules of the project find in them. The report is printed and
+// emailed to the recipients of notifications.email, each of them getting
+// the APIs matching their patterns. It's meant to run on a schedule, for
+// example from a weekly pipeline.
+func digestCommand(args []string) {
+	options := digestOptions{}
+	parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+	environment := options.useConnection(new(string))
+	if options.Owner == "" {
+		options.Owner = environment.Owner
+	}
+	if options.Owner == "" {
+		exitAndError("missing owner")
+	}
+	period, err := time.ParseDuration(options.Since)
+	if err != nil || period <= 0 {
+		exitAndError(fmt.Sprintf("invalid since %s, use a duration as 168h", options.Since))
+	}
+	until := time.Now()
+	since := until.Add(-period)
+
+	publishOptions := commandLineOptions{CheckSchemas: true, Ruleset: options.Ruleset}
+	publishOptions.Config = options.Config
+	rules, err := publishRules(&publishOptions)
+	if err != nil {
+		exitAndError(err)
+	}
+
+	apis, err := listOwner(swaggerHubUrls, options.Owner, options.SwaggerHubAccessToken)
+	if err != nil {
+		exitAndError(fmt.Errorf("can't list the APIs of %s: %w", options.Owner, err))
+	}
+	var entries []digestEntry
+	for _, name := range apis {
+		api := options.Owner + "/" + name
+		apiEntries, err := digestOfApi(api, since, rules, &publishOptions, options.SwaggerHubAccessToken)
+		if err != nil {
+			exitAndError(fmt.Errorf("can't compile the changes of %s: %w", api, err))
+		}
+		entries = append(entries, apiEntries...)
+	}
+
+	title := fmt.Sprintf("API digest for %s, %s to %s", options.Owner, since.Format("2006-01-02"), until.Format("2006-01-02"))
+	if outputJson {
+		printJson(digestResult{Owner: options.Owner, Since: since, Until: until, Versions: append([]digestEntry{}, entries...)})
+	} else {
+		fmt.Print(formatDigest(title, entries))
+	}
+	if options.NoEmail {
+		return
+	}
+
+	config, err := loadProjectConfig(options.Config)
+	if err != nil {
+		exitAndError(err)
+	}
+	email := &config.Notifications.Email
+	if email.Smtp == "" {
+		return
+	}
+	// recipients of quiet APIs still get their digest, saying so
+	byRecipient := map[string][]digestEntry{}
+	for _, name := range apis {
+		for _, address := range email.recipients(options.Owner + "/" + name) {
+			byRecipient[address] = byRecipient[address]
+		}
+	}
+	for _, entry := range entries {
+		for _, address := range email.recipients(entry.Api) {
+			byRecipient[address] = append(byRecipient[address], entry)
+		}
+	}
+	for address, recipientEntries := range byRecipient {
+		if err := sendEmail(email, []string{address}, "[swaggergo] "+title, strings.ReplaceAll(formatDigest(title, recipientEntries), "\n", "\r\n")); err != nil {
+			exitAndError(fmt.Errorf("can't send the digest to %s: %w", address, err))
+		}
+		log.Printf("digest sent to %s", address)
+	}
+}
+
+// digestOfApi compares every version created since the given time with the
+// version listed before it.
+func digestOfApi(api string, since time.Time, rules []lintRule, options *commandLineOptions, accessToken string) ([]digestEntry, error) {
+	versions, err := listVersions(swaggerHubUrls, api, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []digestEntry
+	for i, version := range versions {
+		created, err := time.Parse(time.RFC3339, version.Created)
+		if err != nil || created.Before(since) {
+			continue
+		}
+		document, err := fetchDocument(api, version.Version, accessToken)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := digestEntry{Api: api, Version: version.Version, Created: created, Violations: map[string]int{}}
+		if i > 0 {
+			previous, err := fetchDocument(api, versions[i-1].Version, accessToken)
+			if err != nil {
+				return nil, err
+			}
+			for _, operation := range diffOperations(previous, document).Removed {
+				entry.Breaking = append(entry.Breaking, fmt.Sprintf("removed %s (since %s)", operation, versions[i-1].Version))
+			}
+		}
+		findings, err := lintSpec(document, rules, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, finding := range findings {
+			if finding.Severity == "error" {
+				entry.Violations[finding.Rule]++
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func fetchDocument(api string, version string, accessToken string) (*openApiDocument, error) {
+	openApi, err := getFromSwaggerHub(fmt.Sprintf("%s/%s/swagger.yaml", api, version), accessToken)
+	if err != nil {
+		return nil, err
+	}
+	return parseOpenApiDocument(fmt.Sprintf("%s %s", api, version), openApi)
+}
+
+func formatDigest(title string, entries []digestEntry) string {
+	var digest strings.Builder
+	fmt.Fprintf(&digest, "%s\n\n", title)
+	if len(entries) == 0 {
+		digest.WriteString("No new versions.\n")
+		return digest.String()
+	}
+
+	breaking, violating := 0, 0
+	for _, entry := range entries {
+		fmt.Fprintf(&digest, "%s %s (created %s)\n", entry.Api, entry.Version, entry.Created.Format("2006-01-02"))
+		for _, change := range entry.Breaking {
+			fmt.Fprintf(&digest, "  breaking: %s\n", change)
+		}
+		var rules []string
+		for rule := range entry.Violations {
+			rules = append(rules, rule)
+		}
+		sort.Strings(rules)
+		for _, rule := range rules {
+			fmt.Fprintf(&digest, "  policy: %d errors of %s\n", entry.Violations[rule], rule)
+		}
+		if len(entry.Breaking) > 0 {
+			breaking++
+		}
+		if len(rules) > 0 {
+			violating++
+		}
+	}
+	fmt.Fprintf(&digest, "\n%d new versions, %d with breaking changes, %d with policy violations\n", len(entries), breaking, violating)
+	return digest.String()
+}
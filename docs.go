@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+type docsOptions struct {
+	Out      string `flag:"out" default:"site"`
+	Renderer string `flag:"renderer" default:"redoc"`
+	Output   string `flag:"output" default:"text"`
+}
+
+var docsRenderers = map[string]*template.Template{
+	"redoc": template.Must(template.New("redoc").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>{{.Title}}</title>
+</head>
+<body>
+  <div id="redoc"></div>
+  <script src="https://cdn.redoc.ly/redoc/v2.1.5/bundles/redoc.standalone.js"></script>
+  <script>
+    Redoc.init({{.Spec}}, {}, document.getElementById("redoc"));
+  </script>
+</body>
+</html>
+`)),
+	"swagger-ui": template.Must(template.New("swagger-ui").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5.17.14/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5.17.14/swagger-ui-bundle.js"></script>
+  <script>
+    SwaggerUIBundle({spec: {{.Spec}}, dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`)),
+}
+
+// docsCommand renders the definition into a single index.html with the spec
+// embedded, next to a copy of the definition for downloading.
+func docsCommand(args []string) {
+	options := docsOptions{}
+	positional := parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+	if len(positional) != 2 || positional[0] != "build" {
+		exitAndError("usage: swaggergo docs build path/to/openapi.yml --out site")
+	}
+
+	renderer, ok := docsRenderers[options.Renderer]
+	if !ok {
+		exitAndError(fmt.Sprintf("unknown renderer %s, use redoc or swagger-ui", options.Renderer))
+	}
+
+	openApiPath := positional[1]
+	openApi, err := ioutil.ReadFile(openApiPath)
+	if err != nil {
+		exitAndError(fmt.Sprintf("can't read the file %s", openApiPath))
+	}
+	document, err := parseOpenApiDocument(openApiPath, openApi)
+	if err != nil {
+		exitAndError(err)
+	}
+
+	spec, err := json.Marshal(nodeValue(document.Root))
+	if err != nil {
+		exitAndError(err)
+	}
+	title := scalarValue(document.lookup("info", "title"))
+	if title == "" {
+		title = filepath.Base(openApiPath)
+	}
+
+	if err := os.MkdirAll(options.Out, 0755); err != nil {
+		exitAndError(fmt.Sprintf("can't create the directory %s", options.Out))
+	}
+	index, err := os.Create(filepath.Join(options.Out, "index.html"))
+	if err != nil {
+		exitAndError(fmt.Sprintf("can't write into %s", options.Out))
+	}
+	defer index.Close()
+
+	if err := renderer.Execute(index, struct {
+		Title string
+		Spec  template.JS
+	}{title, template.JS(spec)}); err != nil {
+		exitAndError(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(options.Out, filepath.Base(openApiPath)), openApi, 0644); err != nil {
+		exitAndError(fmt.Sprintf("can't write into %s", options.Out))
+	}
+
+	log.Printf("%s docs for %s written to %s", options.Renderer, openApiPath, options.Out)
+	printWrittenResult(openApiPath, options.Out)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// openApiDocument is a parsed definition. The YAML node tree is kept instead
+// of decoding into structs so every finding can point back to a line.
+type openApiDocument struct {
+	Path string
+	Root *yaml.Node
+}
+
+type openApiOperation struct {
+	Path   string
+	Method string
+	Node   *yaml.Node
+	// Webhook is set for the operations of OpenAPI 3.1 webhooks, whose Path
+	// is the name of the webhook.
+	Webhook bool
+}
+
+func parseOpenApiDocument(path string, content []byte) (*openApiDocument, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, fmt.Errorf("can't parse %s: %v", path, err)
+	}
+
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("can't parse %s: the document is not an object", path)
+	}
+
+	return &openApiDocument{Path: path, Root: root.Content[0]}, nil
+}
+
+// definitionVersion reads info.version from a definition that may not have
+// been parsed yet.
+func definitionVersion(openApi []byte) string {
+	var definition struct {
+		Info struct {
+			Version string `yaml:"version"`
+		} `yaml:"info"`
+	}
+	yaml.Unmarshal(openApi, &definition)
+	return definition.Info.Version
+}
+
+// definitionTitle reads info.title as definitionVersion reads info.version.
+func definitionTitle(openApi []byte) string {
+	var definition struct {
+		Info struct {
+			Title string `yaml:"title"`
+		} `yaml:"info"`
+	}
+	yaml.Unmarshal(openApi, &definition)
+	return definition.Info.Title
+}
+
+func (document *openApiDocument) isSwagger2() bool {
+	return mappingValue(document.Root, "swagger") != nil
+}
+
+func (document *openApiDocument) lookup(keys ...string) *yaml.Node {
+	node := document.Root
+	for _, key := range keys {
+		node = mappingValue(node, key)
+		if node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// resolve follows local references ("#/components/...") until it reaches a
+// node without $ref. Unresolvable references return nil.
+func (document *openApiDocument) resolve(node *yaml.Node) *yaml.Node {
+	for i := 0; node != nil && i < 32; i++ {
+		ref := scalarValue(mappingValue(node, "$ref"))
+		if ref == "" {
+			return node
+		}
+		if !strings.HasPrefix(ref, "#/") {
+			return nil
+		}
+		node = document.lookup(splitPointer(ref)...)
+	}
+	return nil
+}
+
+func (document *openApiDocument) operations() []openApiOperation {
+	return document.sectionOperations("paths")
+}
+
+// webhooks are the operations of the webhooks of an OpenAPI 3.1 document,
+// which SwaggerHub calls rather than serves.
+func (document *openApiDocument) webhooks() []openApiOperation {
+	return document.sectionOperations("webhooks")
+}
+
+func (document *openApiDocument) sectionOperations(section string) []openApiOperation {
+	var operations []openApiOperation
+	eachMapping(document.lookup(section), func(path *yaml.Node, item *yaml.Node) {
+		for _, method := range httpMethods {
+			if operation := mappingValue(item, method); operation != nil {
+				operations = append(operations, openApiOperation{Path: path.Value, Method: method, Node: operation, Webhook: section == "webhooks"})
+			}
+		}
+	})
+	return operations
+}
+
+func (operation openApiOperation) String() string {
+	if operation.Webhook {
+		return fmt.Sprintf("%s webhook %s", strings.ToUpper(operation.Method), operation.Path)
+	}
+	return fmt.Sprintf("%s %s", strings.ToUpper(operation.Method), operation.Path)
+}
+
+func (operation openApiOperation) pointer(keys ...string) string {
+	return joinPointer(append(operation.keys(), keys...)...)
+}
+
+// keys are the keys of the operation from the root of the document.
+func (operation openApiOperation) keys() []string {
+	section := "paths"
+	if operation.Webhook {
+		section = "webhooks"
+	}
+	return []string{section, operation.Path, operation.Method}
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func eachMapping(node *yaml.Node, fn func(key *yaml.Node, value *yaml.Node)) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		fn(node.Content[i], node.Content[i+1])
+	}
+}
+
+func scalarValue(node *yaml.Node) string {
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
+
+func splitPointer(pointer string) []string {
+	parts := strings.Split(strings.TrimPrefix(pointer, "#/"), "/")
+	for i, part := range parts {
+		parts[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+	}
+	return parts
+}
+
+func joinPointer(keys ...string) string {
+	escaped := make([]string, len(keys))
+	for i, key := range keys {
+		escaped[i] = escapePointer(key)
+	}
+	return "#/" + strings.Join(escaped, "/")
+}
+
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	neturl "net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mijailr/swaggergo/pkg/swaggerhub"
+)
+
+type domainOptions struct {
+	Domain           string `flag:"domain" env:"SWAGGERHUB_DOMAIN"`
+	Owner            string `flag:"owner"`
+	Version          string `flag:"version"`
+	FileHeader       string `flag:"file-header" env:"SWAGGERGO_FILE_HEADER"`
+	Visibility       string `flag:"visibility" env:"SWAGGERGO_VISIBILITY"`
+	Force            bool   `flag:"force"`
+	SetDefault       bool   `flag:"set-default"`
+	PublishLifecycle bool   `flag:"publish-lifecycle"`
+	Type             string `flag:"type" default:"yml"`
+	Out              string `flag:"out"`
+	Output           string `flag:"output" default:"text"`
+	Format           string `flag:"format"`
+	connectionOptions
+}
+
+// domainDefinitionNames are the documents SwaggerHub serves a domain
+// version as, by --type.
+var domainDefinitionNames = map[string]string{
+	"yml":  "domain.yaml",
+	"json": "domain.json",
+}
+
+// domainCommand publishes, fetches or lists domains, the libraries of
+// shared components APIs reference, by its first argument.
+func domainCommand(args []string) {
+	options := domainOptions{}
+	positional := parseArgs(&options, args)
+	useFormat(options.Format, &options.Output)
+	useOutput(options.Output, "text", "json")
+	if len(positional) == 0 {
+		exitAndError("usage: swaggergo domain (publish | fetch | list) ...")
+	}
+	environment := options.useConnection(&options.Domain)
+	if options.Owner == "" {
+		options.Owner = environment.Owner
+	}
+
+	switch positional[0] {
+	case "publish":
+		if len(positional) != 2 {
+			exitAndError("domain publish needs the path to the domain")
+		}
+		publishDomain(positional[1], &options)
+	case "fetch":
+		fetchDomain(&options)
+	case "list":
+		listDomains(&options)
+	default:
+		exitAndError(fmt.Sprintf("unknown domain command %s, use publish, fetch or list", positional[0]))
+	}
+}
+
+// publishDomain checks the references of a domain and uploads it, as a
+// definition is, then changes the settings of the version as asked. The
+// version is the one of --version, or the info.version of the domain.
+func publishDomain(domainPath string, options *domainOptions) {
+	checkDomainName(options.Domain)
+	if options.SwaggerHubAccessToken == "" {
+		exitAndError("missing access-token")
+	}
+	if options.Visibility != "" && options.Visibility != "private" && options.Visibility != "public" {
+		exitAndError(fmt.Sprintf("invalid visibility %s, use private or public", options.Visibility))
+	}
+
+	domain, err := readDefinition(domainPath, options.FileHeader)
+	if err != nil {
+		exitAndError(err)
+	}
+	document, err := parseOpenApiDocument(domainPath, domain)
+	if err != nil {
+		exitWithCode(exitInvalid, err)
+	}
+	if !isDomainDocument(document) {
+		exitWithCode(exitInvalid, fmt.Sprintf("%s is not a domain, it has no components, definitions, parameters or responses", domainPath))
+	}
+	findings := lintDocument(document, []lintRule{{Name: "oas-refs", Severity: "error", Check: checkRefs}}, &commandLineOptions{})
+	if errors := reportFindings(document, findings); errors > 0 {
+		exitWithCode(exitInvalid, fmt.Sprintf("found %d problems in %s", errors, domainPath))
+	}
+
+	version := options.Version
+	if version == "" {
+		version = scalarValue(document.lookup("info", "version"))
+	}
+	if version == "" {
+		exitAndError(fmt.Sprintf("missing version, %s has no info.version", domainPath))
+	}
+	query := neturl.Values{}
+	query.Set("version", version)
+	if options.Visibility != "" {
+		query.Set("isPrivate", fmt.Sprint(options.Visibility == "private"))
+	}
+	if options.Force {
+		query.Set("force", "true")
+	}
+	mediaType := "application/yaml"
+	if definitionType(domainPath, domain) == "json" {
+		mediaType = "application/json"
+	}
+
+	registry := swaggerHub(swaggerHubDomainUrls(), options.SwaggerHubAccessToken)
+	response, err := registry.Publish(runContext, swaggerhub.PublishRequest{Api: options.Domain, Definition: domain, MediaType: mediaType, Query: query})
+	var statusError *swaggerhub.StatusError
+	if err != nil && !errors.As(err, &statusError) {
+		exitWithCode(exitNetwork, "problem connecting to swaggerhub")
+	}
+	log.Print(string(response.Body))
+	log.Printf("Domain sended with response: %s", response.Status)
+	if response.StatusCode >= 400 {
+		exitWithCode(statusExitCode(response.StatusCode, response.Body), fmt.Sprintf("swaggerhub rejected %s %s: %s", options.Domain, version, response.Status))
+	}
+
+	if options.PublishLifecycle {
+		if err := registry.SetPublished(runContext, options.Domain, version, true); err != nil {
+			exitAndError(fmt.Errorf("can't publish the lifecycle of %s: %w", version, err))
+		}
+		log.Printf("%s of %s is now published", version, options.Domain)
+	}
+	if options.SetDefault {
+		if err := registry.SetDefault(runContext, options.Domain, version); err != nil {
+			exitAndError(fmt.Errorf("can't make %s the default version: %w", version, err))
+		}
+		log.Printf("%s is now the default version of %s", version, options.Domain)
+	}
+	printPublishResult(publishResult{
+		Status:     "published",
+		Api:        options.Domain,
+		Version:    version,
+		Url:        fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(swaggerHubDomainUrls()[0], "/"), options.Domain, version),
+		StatusCode: response.StatusCode,
+		Warnings:   []jsonFinding{},
+	})
+}
+
+// isDomainDocument tells whether a document holds components to share, in
+// the sections of OpenAPI 3 or Swagger 2.
+func isDomainDocument(document *openApiDocument) bool {
+	for _, section := range bundleSections {
+		if mappingValue(document.Root, section) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchDomain writes a version of a domain to --out, or to the standard
+// output.
+func fetchDomain(options *domainOptions) {
+	checkDomainName(options.Domain)
+	if options.Version == "" {
+		exitAndError("missing version")
+	}
+	definitionName, ok := domainDefinitionNames[options.Type]
+	if !ok {
+		exitAndError(fmt.Sprintf("unknown type %s, use yml or json", options.Type))
+	}
+	useDocumentOutput(options.Out)
+
+	domain, err := getFromSwaggerHubAt(swaggerHubDomainUrls(), fmt.Sprintf("%s/%s/%s", options.Domain, options.Version, definitionName), options.SwaggerHubAccessToken)
+	if err != nil {
+		exitAndError(fmt.Errorf("can't fetch %s %s: %w", options.Domain, options.Version, err))
+	}
+	if options.Out == "" {
+		os.Stdout.Write(domain)
+		return
+	}
+	if err := ioutil.WriteFile(options.Out, domain, 0644); err != nil {
+		exitAndError(fmt.Sprintf("can't write the file %s", options.Out))
+	}
+	log.Printf("%s %s written to %s", options.Domain, options.Version, options.Out)
+	if outputJson {
+		printJson(fetchResult{Status: "fetched", Api: options.Domain, Version: options.Version, Out: options.Out})
+	}
+}
+
+// listDomains prints the domains of --owner as list does the APIs, or the
+// versions of --domain as versions does.
+func listDomains(options *domainOptions) {
+	if options.Domain != "" {
+		listDomainVersions(options)
+		return
+	}
+	if options.Owner == "" {
+		exitAndError("missing owner, or --domain to list its versions")
+	}
+
+	domains, err := swaggerHub(swaggerHubDomainUrls(), options.SwaggerHubAccessToken).ListOwner(runContext, options.Owner)
+	if err != nil {
+		exitAndError(fmt.Errorf("can't list the domains of %s: %w", options.Owner, err))
+	}
+	entries := []listEntry{}
+	for _, domain := range domains {
+		entries = append(entries, listEntry{
+			Name:         options.Owner + "/" + domain.Name,
+			Version:      domain.Version,
+			Visibility:   visibilityName(domain.Private),
+			LastModified: domain.Modified,
+		})
+	}
+
+	if outputJson {
+		printJson(entries)
+		return
+	}
+	rows := [][]string{{"NAME", "VERSION", "VISIBILITY", "LAST MODIFIED"}}
+	for _, entry := range entries {
+		rows = append(rows, []string{entry.Name, entry.Version, entry.Visibility, entry.LastModified})
+	}
+	printTable(rows)
+}
+
+func listDomainVersions(options *domainOptions) {
+	checkDomainName(options.Domain)
+	versions, err := listVersions(swaggerHubDomainUrls(), options.Domain, options.SwaggerHubAccessToken)
+	if err != nil {
+		exitAndError(fmt.Errorf("can't list the versions of %s: %w", options.Domain, err))
+	}
+	entries := []versionsEntry{}
+	for _, version := range versions {
+		lifecycle := "unpublished"
+		if version.Published {
+			lifecycle = "published"
+		}
+		entries = append(entries, versionsEntry{Version: version.Version, Oas: version.Oas, Lifecycle: lifecycle, Default: version.Default})
+	}
+
+	if outputJson {
+		printJson(entries)
+		return
+	}
+	rows := [][]string{{"VERSION", "OAS", "LIFECYCLE", "DEFAULT"}}
+	for _, entry := range entries {
+		rows = append(rows, []string{entry.Version, entry.Oas, entry.Lifecycle, strconv.FormatBool(entry.Default)})
+	}
+	printTable(rows)
+}
+
+func checkDomainName(domain string) {
+	if domain == "" {
+		exitAndError("missing domain")
+	}
+	if len(strings.Split(domain, "/")) != 2 {
+		exitAndError("domain is in the wrong format")
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const defaultEnvFile = ".env"
+
+// envFileVariables are the variables set from an env file, with the file,
+// for config show.
+var envFileVariables = map[string]string{}
+
+// useEnvFile sets the variables of a dotenv file that aren't set already, so
+// SWAGGERHUB_ACCESS_TOKEN and the like can live in a local file. The file is
+// the one of --env-file (or SWAGGERGO_ENV_FILE), or .env when it exists in
+// the working directory. It returns args without --env-file, which every
+// command accepts.
+func useEnvFile(args []string) []string {
+	path := os.Getenv("SWAGGERGO_ENV_FILE")
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case (args[i] == "--env-file" || args[i] == "-env-file") && i+1 < len(args):
+			path = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--env-file=") || strings.HasPrefix(args[i], "-env-file="):
+			path = args[i][strings.Index(args[i], "=")+1:]
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+
+	if path == "" {
+		if _, err := os.Stat(defaultEnvFile); err != nil {
+			return rest
+		}
+		path = defaultEnvFile
+	}
+	variables, err := readEnvFile(path)
+	if err != nil {
+		exitAndError(err)
+	}
+	for name, value := range variables {
+		if _, set := os.LookupEnv(name); !set {
+			os.Setenv(name, value)
+			envFileVariables[name] = path
+		}
+	}
+	return rest
+}
+
+// readEnvFile reads NAME=value lines, optionally starting with export.
+// Values can be single quoted (taken as they are) or double quoted (with
+// \n, \" and the other Go escapes); # starts a comment outside quotes.
+func readEnvFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the env file %s", path)
+	}
+	defer file.Close()
+
+	variables := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		parts := strings.SplitN(line, "=", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("%s:%d: expected NAME=value", path, number)
+		}
+
+		value := strings.TrimSpace(parts[1])
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("%s:%d: unclosed quote", path, number)
+			}
+			value = value[1 : end+1]
+		case strings.HasPrefix(value, `"`):
+			quoted, err := strconv.QuotedPrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: unclosed quote", path, number)
+			}
+			value, _ = strconv.Unquote(quoted)
+		default:
+			if comment := strings.Index(value, " #"); comment >= 0 {
+				value = strings.TrimSpace(value[:comment])
+			}
+		}
+		variables[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can't read the env file %s: %v", path, err)
+	}
+	return variables, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// environmentConfig is a named stage in the environments section of the
+// config. The token comes from the variable named by TokenEnv, or from a
+// profile of the user config.
+type environmentConfig struct {
+	Url        string `yaml:"url"`
+	Owner      string `yaml:"owner"`
+	TokenEnv   string `yaml:"tokenEnv"`
+	Profile    string `yaml:"profile"`
+	Visibility string `yaml:"visibility"`
+	Oas        string `yaml:"oas"`
+
+	// tokenSource is keychain when the token is the one `login` stored
+	tokenSource string
+}
+
+// useEnvironment applies the environment given with --env and the profile
+// given with --profile (or the environment's): the SwaggerHub URL, the owner
+// for APIs given without one, and the token when none was given, falling
+// back to the one `login` stored in the keychain. The environment wins over
+// the profile where both say something, and --registry-url wins over both.
+func useEnvironment(configPath string, name string, profileName string, registryUrl string, api *string, accessToken *string) *environmentConfig {
+	environment := environmentConfig{}
+	if name != "" {
+		config, err := loadProjectConfig(configPath)
+		if err != nil {
+			exitAndError(err)
+		}
+		var ok bool
+		if environment, ok = config.Environments[name]; !ok {
+			exitAndError(fmt.Sprintf("there is no environment %s in the config", name))
+		}
+		if environment.Visibility != "" && environment.Visibility != "private" && environment.Visibility != "public" {
+			exitAndError(fmt.Sprintf("invalid visibility %s in the environment %s, use private or public", environment.Visibility, name))
+		}
+	}
+	if profileName != "" {
+		environment.Profile = profileName
+	}
+
+	profileToken := ""
+	if environment.Profile != "" {
+		profile, err := loadProfile(environment.Profile)
+		if err != nil {
+			exitAndError(err)
+		}
+		// the SaaS URL is the default of profiles, it doesn't replace the
+		// ones of the config
+		if environment.Url == "" && profile.Url != swaggerHubUrl {
+			environment.Url = profile.Url
+		}
+		if environment.Owner == "" {
+			environment.Owner = profile.Owner
+		}
+		if environment.Oas == "" {
+			environment.Oas = profile.Oas
+		}
+		profileToken = profile.Token
+	}
+
+	if registryUrl != "" {
+		environment.Url = registryUrl
+	}
+	if environment.Url != "" {
+		url, err := registryApiUrl(environment.Url)
+		if err != nil {
+			exitAndError(err)
+		}
+		swaggerHubUrls = []string{url}
+	}
+	if environment.Owner != "" && *api != "" && !strings.Contains(*api, "/") {
+		*api = environment.Owner + "/" + *api
+	}
+	if *accessToken == "" && environment.TokenEnv != "" {
+		*accessToken = os.Getenv(environment.TokenEnv)
+	}
+	if *accessToken == "" {
+		*accessToken = profileToken
+	}
+	if *accessToken == "" {
+		*accessToken = keychainToken(environment.Profile)
+		if *accessToken != "" {
+			environment.tokenSource = "keychain"
+		}
+	}
+	return &environment
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/mijailr/swaggergo/pkg/swaggerhub"
+)
+
+const publishedEventType = "com.github.mijailr.swaggergo.api.published"
+
+type cloudEvent struct {
+	SpecVersion     string      `json:"specversion"`
+	Id              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject"`
+	Time            string      `json:"time"`
+	DataContentType string      `json:"datacontenttype"`
+	Data            interface{} `json:"data"`
+}
+
+type publishedEventData struct {
+	Api     string      `json:"api"`
+	Version string      `json:"version"`
+	Actor   string      `json:"actor"`
+	Diff    diffSummary `json:"diff"`
+}
+
+type diffSummary struct {
+	Added   []string `json:"added"`
+	Changed []string `json:"changed"`
+	Removed []string `json:"removed"`
+}
+
+// publishedVersion returns the definition currently stored in SwaggerHub for
+// the version about to be published, nil when there is none yet.
+func publishedVersion(registry *swaggerhub.Client, openApi []byte, options *commandLineOptions) *openApiDocument {
+	version := publicationVersion(openApi, options)
+	previous, err := registry.Get(runContext, fmt.Sprintf("%s/%s/swagger.yaml", options.SwaggerHubApi, version))
+	if err != nil {
+		return nil
+	}
+	document, err := parseOpenApiDocument(fmt.Sprintf("%s %s", options.SwaggerHubApi, version), previous)
+	if err != nil {
+		return nil
+	}
+	return document
+}
+
+// sendPublishedEvent posts a structured mode CloudEvent describing the
+// publication, with the operations that changed from the previous content of
+// the same version.
+func sendPublishedEvent(openApiPath string, openApi []byte, previous *openApiDocument, options *commandLineOptions) error {
+	document, err := parseOpenApiDocument(openApiPath, openApi)
+	if err != nil {
+		return err
+	}
+	diff := diffOperations(previous, document)
+	summary := diffSummary{Added: []string{}, Changed: []string{}, Removed: []string{}}
+	for _, operation := range diff.Added {
+		summary.Added = append(summary.Added, operation.String())
+	}
+	for _, operation := range diff.Changed {
+		summary.Changed = append(summary.Changed, operation.String())
+	}
+	for _, operation := range diff.Removed {
+		summary.Removed = append(summary.Removed, operation.String())
+	}
+
+	id := make([]byte, 16)
+	rand.Read(id)
+	version := publicationVersion(openApi, options)
+	event := cloudEvent{
+		SpecVersion:     "1.0",
+		Id:              hex.EncodeToString(id),
+		Source:          "/swaggergo/" + options.SwaggerHubApi,
+		Type:            publishedEventType,
+		Subject:         fmt.Sprintf("%s/%s", options.SwaggerHubApi, version),
+		Time:            time.Now().UTC().Format(time.RFC3339),
+		DataContentType: "application/json",
+		Data: publishedEventData{
+			Api:     options.SwaggerHubApi,
+			Version: version,
+			Actor:   publishActor(),
+			Diff:    summary,
+		},
+	}
+	body, _ := json.Marshal(event)
+
+	request, err := http.NewRequest("POST", options.EventsUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/cloudevents+json")
+	if name, value, ok := splitHeader(options.EventsHeader); ok {
+		request.Header.Set(name, value)
+	}
+
+	client := client()
+	resp, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("can't send the publish event: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("the event sink answered %s", resp.Status)
+	}
+
+	log.Printf("publish event %s sent to %s", event.Id, options.EventsUrl)
+	return nil
+}
+
+// publishActor is who triggered the publication, as told by the CI system.
+func publishActor() string {
+	for _, name := range []string{"SWAGGERGO_ACTOR", "GITHUB_ACTOR", "GITLAB_USER_LOGIN", "BUILD_REQUESTEDFOR", "USER"} {
+		if actor := os.Getenv(name); actor != "" {
+			return actor
+		}
+	}
+	return ""
+}
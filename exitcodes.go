@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"strings"
+
+	"github.com/mijailr/swaggergo/pkg/swaggerhub"
+)
+
+// The exit codes of swaggergo, for pipelines to tell failures apart. An
+// interruption exits with 130 or 143, and 124 once the max time is over.
+const (
+	exitOk        = 0
+	exitFailure   = 1 // a usage error, or any failure without a code of its own
+	exitInvalid   = 2 // the definition is invalid or doesn't pass the lint
+	exitAuth      = 3 // SwaggerHub refused the access token
+	exitNotFound  = 4 // the API or version isn't on SwaggerHub
+	exitNetwork   = 5 // SwaggerHub (or another service) can't be reached
+	exitBreaking  = 6 // diff found breaking changes with --fail-on-breaking
+	exitPublished = 7 // the version is published and can't be overwritten
+)
+
+// codeError is a failure with an exit code of its own, returned by the steps
+// of a publication so a batch can report it and go on with the others.
+type codeError struct {
+	code int
+	err  error
+}
+
+func (err *codeError) Error() string {
+	return err.err.Error()
+}
+
+func (err *codeError) Unwrap() error {
+	return err.err
+}
+
+// withExitCode gives err the code exitAndError exits with.
+func withExitCode(code int, err error) error {
+	return &codeError{code, err}
+}
+
+// exitCode is the code exitAndError exits with for a message, from the
+// errors it wraps: the code given with withExitCode, the status SwaggerHub
+// answered or a failed connection.
+func exitCode(message interface{}) int {
+	err, ok := message.(error)
+	if !ok {
+		return exitFailure
+	}
+	var coded *codeError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	var statusError *swaggerhub.StatusError
+	if errors.As(err, &statusError) {
+		return statusExitCode(statusError.StatusCode, statusError.Body)
+	}
+	var urlError *neturl.Error
+	var netError net.Error
+	if errors.As(err, &urlError) || errors.As(err, &netError) {
+		return exitNetwork
+	}
+	return exitFailure
+}
+
+// statusExitCode is the exit code of an error status of SwaggerHub. A 403
+// is also how SwaggerHub refuses to overwrite a published version, which its
+// body tells apart from a refused token.
+func statusExitCode(statusCode int, body []byte) int {
+	switch statusCode {
+	case http.StatusForbidden:
+		if strings.Contains(strings.ToLower(string(body)), "published") {
+			return exitPublished
+		}
+		return exitAuth
+	case http.StatusUnauthorized:
+		return exitAuth
+	case http.StatusNotFound:
+		return exitNotFound
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return exitInvalid
+	}
+	return exitFailure
+}
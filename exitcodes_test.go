@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	neturl "net/url"
+	"testing"
+
+	"github.com/mijailr/swaggergo/pkg/swaggerhub"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		message interface{}
+		want    int
+	}{
+		{"message", "missing api", exitFailure},
+		{"error", errors.New("can't read the file"), exitFailure},
+		{"unauthorized", &swaggerhub.StatusError{StatusCode: 401}, exitAuth},
+		{"forbidden", &swaggerhub.StatusError{StatusCode: 403}, exitAuth},
+		{"published version", &swaggerhub.StatusError{StatusCode: 403, Body: []byte(`{"code":403,"message":"Cannot overwrite published API version"}`)}, exitPublished},
+		{"forbidden with another body", &swaggerhub.StatusError{StatusCode: 403, Body: []byte(`{"code":403,"message":"Forbidden"}`)}, exitAuth},
+		{"not found", &swaggerhub.StatusError{StatusCode: 404}, exitNotFound},
+		{"bad request", &swaggerhub.StatusError{StatusCode: 400}, exitInvalid},
+		{"unprocessable", &swaggerhub.StatusError{StatusCode: 422}, exitInvalid},
+		{"server error", &swaggerhub.StatusError{StatusCode: 500}, exitFailure},
+		{"wrapped status", fmt.Errorf("can't fetch orders: %w", &swaggerhub.StatusError{StatusCode: 404}), exitNotFound},
+		{"with a code", withExitCode(exitInvalid, errors.New("found 2 problems in openapi.yml")), exitInvalid},
+		{"wrapped code", fmt.Errorf("can't publish: %w", withExitCode(exitNetwork, errors.New("problem connecting to swaggerhub"))), exitNetwork},
+		{"url error", &neturl.Error{Op: "Get", URL: "https://api.swaggerhub.com", Err: errors.New("EOF")}, exitNetwork},
+		{"dial error", fmt.Errorf("can't list: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")}), exitNetwork},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := exitCode(test.message); got != test.want {
+				t.Errorf("got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
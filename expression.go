@@ -0,0 +1,442 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A small expression language for custom rules. Expressions are evaluated
+// against a node selected by JSONPath and support:
+//
+//   - field paths relative to the node (summary, info.contact.email), @ for
+//     the node itself and @key for its property name
+//   - string, number, boolean, null and list literals
+//   - ==, !=, <, <=, >, >=, in, &&, || and !
+//   - len, exists, matches, startsWith, endsWith, contains, lower and upper
+type expression func(node *yaml.Node, key string) (interface{}, error)
+
+type expressionParser struct {
+	source string
+	tokens []string
+	next   int
+}
+
+var expressionFunctions = map[string]int{
+	"len": 1, "exists": 1, "lower": 1, "upper": 1,
+	"matches": 2, "startsWith": 2, "endsWith": 2, "contains": 2,
+}
+
+func compileExpression(source string) (expression, error) {
+	tokens, err := tokenizeExpression(source)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &expressionParser{source: source, tokens: tokens}
+	compiled, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if parser.next < len(parser.tokens) {
+		return nil, parser.errorf("unexpected %q", parser.tokens[parser.next])
+	}
+	return compiled, nil
+}
+
+func tokenizeExpression(source string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(source[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("invalid expression %q: unterminated string", source)
+			}
+			tokens = append(tokens, source[i:i+end+2])
+			i += end + 2
+		case strings.ContainsRune("()[],", rune(c)):
+			tokens = append(tokens, string(c))
+			i++
+		case strings.ContainsRune("=!<>&|", rune(c)):
+			operator := string(c)
+			if i+1 < len(source) && strings.ContainsRune("=&|", rune(source[i+1])) {
+				operator = source[i : i+2]
+			}
+			tokens = append(tokens, operator)
+			i += len(operator)
+		default:
+			start := i
+			for i < len(source) && isIdentifierByte(source[i]) {
+				i++
+			}
+			if start == i {
+				return nil, fmt.Errorf("invalid expression %q: unexpected %q", source, c)
+			}
+			tokens = append(tokens, source[start:i])
+		}
+	}
+	return tokens, nil
+}
+
+func isIdentifierByte(c byte) bool {
+	return unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)) || strings.ContainsRune("_-$@.", rune(c))
+}
+
+func (parser *expressionParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid expression %q: %s", parser.source, fmt.Sprintf(format, args...))
+}
+
+func (parser *expressionParser) peek() string {
+	if parser.next < len(parser.tokens) {
+		return parser.tokens[parser.next]
+	}
+	return ""
+}
+
+func (parser *expressionParser) expect(token string) error {
+	if parser.peek() != token {
+		return parser.errorf("expected %q", token)
+	}
+	parser.next++
+	return nil
+}
+
+func (parser *expressionParser) parseOr() (expression, error) {
+	left, err := parser.parseAnd()
+	for err == nil && parser.peek() == "||" {
+		parser.next++
+		var right expression
+		if right, err = parser.parseAnd(); err == nil {
+			left = logical(left, right, true)
+		}
+	}
+	return left, err
+}
+
+func (parser *expressionParser) parseAnd() (expression, error) {
+	left, err := parser.parseNot()
+	for err == nil && parser.peek() == "&&" {
+		parser.next++
+		var right expression
+		if right, err = parser.parseNot(); err == nil {
+			left = logical(left, right, false)
+		}
+	}
+	return left, err
+}
+
+func logical(left expression, right expression, or bool) expression {
+	return func(node *yaml.Node, key string) (interface{}, error) {
+		value, err := left(node, key)
+		if err != nil || truthy(value) == or {
+			return truthy(value), err
+		}
+		value, err = right(node, key)
+		return truthy(value), err
+	}
+}
+
+func (parser *expressionParser) parseNot() (expression, error) {
+	if parser.peek() != "!" {
+		return parser.parseComparison()
+	}
+	parser.next++
+	operand, err := parser.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return func(node *yaml.Node, key string) (interface{}, error) {
+		value, err := operand(node, key)
+		return !truthy(value), err
+	}, nil
+}
+
+func (parser *expressionParser) parseComparison() (expression, error) {
+	left, err := parser.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	operator := parser.peek()
+	switch operator {
+	case "==", "!=", "<", "<=", ">", ">=", "in":
+	default:
+		return left, nil
+	}
+	parser.next++
+	right, err := parser.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(node *yaml.Node, key string) (interface{}, error) {
+		a, err := left(node, key)
+		if err != nil {
+			return nil, err
+		}
+		b, err := right(node, key)
+		if err != nil {
+			return nil, err
+		}
+		return compareValues(operator, a, b), nil
+	}, nil
+}
+
+func (parser *expressionParser) parsePrimary() (expression, error) {
+	token := parser.peek()
+	if token == "" {
+		return nil, parser.errorf("unexpected end")
+	}
+	parser.next++
+
+	switch {
+	case token == "(":
+		inner, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return inner, parser.expect(")")
+	case token == "[":
+		var items []expression
+		for parser.peek() != "]" {
+			item, err := parser.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			if parser.peek() == "," {
+				parser.next++
+			}
+		}
+		parser.next++
+		return func(node *yaml.Node, key string) (interface{}, error) {
+			list := make([]interface{}, len(items))
+			for i, item := range items {
+				value, err := item(node, key)
+				if err != nil {
+					return nil, err
+				}
+				list[i] = value
+			}
+			return list, nil
+		}, nil
+	case token[0] == '"' || token[0] == '\'':
+		return constant(token[1 : len(token)-1]), nil
+	case token == "true" || token == "false":
+		return constant(token == "true"), nil
+	case token == "null":
+		return constant(nil), nil
+	case token == "@key":
+		return func(node *yaml.Node, key string) (interface{}, error) {
+			return key, nil
+		}, nil
+	}
+
+	if number, err := strconv.ParseFloat(token, 64); err == nil {
+		return constant(number), nil
+	}
+	if arity, ok := expressionFunctions[token]; ok && parser.peek() == "(" {
+		return parser.parseCall(token, arity)
+	}
+	if !unicode.IsLetter(rune(token[0])) && token[0] != '$' && token[0] != '@' && token[0] != '_' {
+		return nil, parser.errorf("unexpected %q", token)
+	}
+
+	path := strings.Split(strings.TrimPrefix(strings.TrimPrefix(token, "@"), "."), ".")
+	return func(node *yaml.Node, key string) (interface{}, error) {
+		for _, name := range path {
+			if name == "" {
+				continue
+			}
+			if node = mappingValue(node, name); node == nil {
+				return nil, nil
+			}
+		}
+		return nodeValue(node), nil
+	}, nil
+}
+
+func (parser *expressionParser) parseCall(name string, arity int) (expression, error) {
+	parser.next++
+	var args []expression
+	for parser.peek() != ")" {
+		arg, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if parser.peek() == "," {
+			parser.next++
+		}
+	}
+	parser.next++
+	if len(args) != arity {
+		return nil, parser.errorf("%s takes %d arguments", name, arity)
+	}
+
+	var pattern *regexp.Regexp
+	if name == "matches" {
+		literal, err := args[1](nil, "")
+		source, ok := literal.(string)
+		if err != nil || !ok {
+			return nil, parser.errorf("matches needs a string literal pattern")
+		}
+		if pattern, err = regexp.Compile(source); err != nil {
+			return nil, parser.errorf("%v", err)
+		}
+	}
+
+	return func(node *yaml.Node, key string) (interface{}, error) {
+		values := make([]interface{}, len(args))
+		for i, arg := range args {
+			value, err := arg(node, key)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return callFunction(name, pattern, values), nil
+	}, nil
+}
+
+func callFunction(name string, pattern *regexp.Regexp, args []interface{}) interface{} {
+	text, _ := args[0].(string)
+	switch name {
+	case "exists":
+		return args[0] != nil
+	case "len":
+		switch value := args[0].(type) {
+		case string:
+			return float64(len([]rune(value)))
+		case []interface{}:
+			return float64(len(value))
+		case map[string]interface{}:
+			return float64(len(value))
+		}
+		return float64(0)
+	case "lower":
+		return strings.ToLower(text)
+	case "upper":
+		return strings.ToUpper(text)
+	case "matches":
+		return args[0] != nil && pattern.MatchString(fmt.Sprint(args[0]))
+	case "startsWith":
+		return strings.HasPrefix(text, fmt.Sprint(args[1]))
+	case "endsWith":
+		return strings.HasSuffix(text, fmt.Sprint(args[1]))
+	case "contains":
+		switch value := args[0].(type) {
+		case string:
+			return strings.Contains(value, fmt.Sprint(args[1]))
+		case []interface{}:
+			return compareValues("in", args[1], value)
+		case map[string]interface{}:
+			_, ok := value[fmt.Sprint(args[1])]
+			return ok
+		}
+	}
+	return false
+}
+
+func constant(value interface{}) expression {
+	return func(*yaml.Node, string) (interface{}, error) {
+		return value, nil
+	}
+}
+
+func compareValues(operator string, a interface{}, b interface{}) bool {
+	switch operator {
+	case "==":
+		return reflect.DeepEqual(a, b)
+	case "!=":
+		return !reflect.DeepEqual(a, b)
+	case "in":
+		list, _ := b.([]interface{})
+		for _, item := range list {
+			if reflect.DeepEqual(a, item) {
+				return true
+			}
+		}
+		return false
+	}
+
+	x, xOk := a.(float64)
+	y, yOk := b.(float64)
+	if !xOk || !yOk {
+		sa, saOk := a.(string)
+		sb, sbOk := b.(string)
+		if !saOk || !sbOk {
+			return false
+		}
+		x, y = float64(strings.Compare(sa, sb)), 0
+	}
+	switch operator {
+	case "<":
+		return x < y
+	case "<=":
+		return x <= y
+	case ">":
+		return x > y
+	default:
+		return x >= y
+	}
+}
+
+func truthy(value interface{}) bool {
+	switch value := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return value
+	case string:
+		return value != ""
+	case float64:
+		return value != 0
+	}
+	return true
+}
+
+// nodeValue converts a node into plain values: strings, float64 numbers,
+// booleans, nil, lists and maps.
+func nodeValue(node *yaml.Node) interface{} {
+	if node == nil {
+		return nil
+	}
+	switch node.Kind {
+	case yaml.AliasNode:
+		return nodeValue(node.Alias)
+	case yaml.SequenceNode:
+		list := make([]interface{}, len(node.Content))
+		for i, item := range node.Content {
+			list[i] = nodeValue(item)
+		}
+		return list
+	case yaml.MappingNode:
+		object := map[string]interface{}{}
+		eachMapping(node, func(key *yaml.Node, value *yaml.Node) {
+			object[key.Value] = nodeValue(value)
+		})
+		return object
+	}
+
+	switch node.Tag {
+	case "!!null":
+		return nil
+	case "!!bool":
+		return node.Value == "true"
+	case "!!int", "!!float":
+		if number, err := strconv.ParseFloat(node.Value, 64); err == nil {
+			return number
+		}
+	}
+	return node.Value
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const expressionOperation = `
+summary: List pets
+tags: [pets, admin]
+deprecated: false
+responses:
+  "200": {description: ok}
+x-rate-limit: 100
+`
+
+func TestCompileExpression(t *testing.T) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(expressionOperation), &root); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{`summary == "List pets"`, true},
+		{`summary != 'List pets'`, false},
+		{`@.summary == "List pets"`, true},
+		{`@key == "get"`, true},
+		{`len(tags) >= 2`, true},
+		{`len(summary) > 100`, false},
+		{`"admin" in tags`, true},
+		{`"owner" in tags`, false},
+		{`!deprecated`, true},
+		{`deprecated == false && exists(responses)`, true},
+		{`exists(description)`, false},
+		{`description == null`, true},
+		{`deprecated || len(tags) == 0`, false},
+		{`!(deprecated || len(tags) == 0)`, true},
+		{`startsWith(lower(summary), "list")`, true},
+		{`endsWith(upper(summary), "PETS")`, true},
+		{`matches(summary, "^List [a-z]+$")`, true},
+		{`contains(summary, "pets")`, true},
+		{`contains(tags, "pets")`, true},
+		{`contains(responses, "200")`, true},
+		{`contains(responses, "404")`, false},
+		{`responses.200.description == "ok"`, true},
+		{`x-rate-limit > 50`, true},
+		{`summary in ["List pets", "Get pet"]`, true},
+		{`summary < "Z"`, true},
+	}
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			compiled, err := compileExpression(test.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			value, err := compiled(root.Content[0], "get")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if truthy(value) != test.want {
+				t.Errorf("got %v, want %v", value, test.want)
+			}
+		})
+	}
+}
+
+func TestCompileExpressionErrors(t *testing.T) {
+	for _, source := range []string{
+		`summary ==`,
+		`summary == "List pets`,
+		`len(tags, summary)`,
+		`matches(summary, tags)`,
+		`matches(summary, "[")`,
+		`(summary == "List pets"`,
+		`summary "List pets"`,
+		`#summary`,
+	} {
+		t.Run(source, func(t *testing.T) {
+			if _, err := compileExpression(source); err == nil {
+				t.Errorf("%q compiled without an error", source)
+			}
+		})
+	}
+}
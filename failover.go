@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	neturl "net/url"
+	"strings"
+
+	"github.com/mijailr/swaggergo/pkg/swaggerhub"
+)
+
+// swaggerHubUrls are tried in order by the swaggerhub client. The config can
+// list several nodes of an on-premise installation in swaggerhub.urls.
+var swaggerHubUrls = []string{swaggerHubUrl}
+
+// onPremiseApiPath is where SwaggerHub On-Premise serves the registry API
+// when its URL is given without a path.
+const onPremiseApiPath = "/v1/apis"
+
+const swaggerHubSaasHost = "api.swaggerhub.com"
+
+// registryApiUrl is the APIs endpoint of a SwaggerHub URL, as given with
+// --registry-url, the config or a profile. The endpoint can be given as it
+// is (https://swaggerhub.mycorp.com/v1/apis), by its base path
+// (https://swaggerhub.mycorp.com/v1) or by the host alone, which is the
+// On-Premise layout except for the SaaS.
+func registryApiUrl(registryUrl string) (string, error) {
+	url, err := neturl.Parse(strings.TrimSuffix(registryUrl, "/"))
+	if err != nil || (url.Scheme != "http" && url.Scheme != "https") || url.Host == "" {
+		return "", fmt.Errorf("invalid SwaggerHub url %s, use http(s)://host[/path]", registryUrl)
+	}
+	switch {
+	case strings.HasSuffix(url.Path, "/apis"):
+	case url.Path == "" && url.Host == swaggerHubSaasHost:
+		url.Path = "/apis"
+	case url.Path == "":
+		url.Path = onPremiseApiPath
+	default:
+		url.Path += "/apis"
+	}
+	return url.String(), nil
+}
+
+// swaggerHub is the registry client for the given base URLs, going through
+// the transport, circuit breakers and cache of swaggergo.
+func swaggerHub(urls []string, accessToken string) *swaggerhub.Client {
+	httpClient := client()
+	registry := &swaggerhub.Client{
+		Urls:        urls,
+		AccessToken: accessToken,
+		HttpClient:  &httpClient,
+		Breaker:     swaggerHubBreakers{},
+		Logf:        log.Printf,
+	}
+	if swaggerHubCache != nil {
+		registry.Cache = swaggerHubCache
+	}
+	return registry
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+type fetchOptions struct {
+	SwaggerHubApi string `flag:"api" env:"SWAGGERHUB_API" required:"true"`
+	Version       string `flag:"version" required:"true"`
+	Type          string `flag:"type" default:"yml"`
+	Out           string `flag:"out"`
+	RevisionFile  string `flag:"revision-file"`
+	Verify        bool   `flag:"verify"`
+	Attestation   string `flag:"attestation"`
+	PublicKey     string `flag:"public-key" env:"SWAGGERGO_PUBLIC_KEY"`
+	NoCache       bool   `flag:"no-cache"`
+	Output        string `flag:"output" default:"text"`
+	connectionOptions
+}
+
+// fetchDefinitionNames are the documents SwaggerHub serves a version as, by
+// --type.
+var fetchDefinitionNames = map[string]string{
+	"yml":  "swagger.yaml",
+	"json": "swagger.json",
+}
+
+// fetchResult is what fetch and domain fetch print with --output json, once
+// the definition is written to --out.
+type fetchResult struct {
+	Status   string `json:"status"`
+	Api      string `json:"api"`
+	Version  string `json:"version"`
+	Revision string `json:"revision,omitempty"`
+	Out      string `json:"out"`
+}
+
+func fetchCommand(args []string) {
+	options := fetchOptions{}
+	parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+	options.useConnection(&options.SwaggerHubApi)
+	if options.NoCache {
+		swaggerHubCache = nil
+	}
+
+	if len(strings.Split(options.SwaggerHubApi, "/")) != 2 {
+		exitAndError("api is in the wrong format")
+	}
+	definitionName, ok := fetchDefinitionNames[options.Type]
+	if !ok {
+		exitAndError(fmt.Sprintf("unknown type %s, use yml or json", options.Type))
+	}
+	useDocumentOutput(options.Out)
+
+	openApi, err := getFromSwaggerHub(fmt.Sprintf("%s/%s/%s", options.SwaggerHubApi, options.Version, definitionName), options.SwaggerHubAccessToken)
+	if err != nil {
+		exitAndError(fmt.Errorf("can't fetch %s %s: %w", options.SwaggerHubApi, options.Version, err))
+	}
+
+	if options.Verify {
+		if err := verifyFetched(openApi, &options); err != nil {
+			exitAndError(fmt.Errorf("verification failed, nothing was written: %w", err))
+		}
+		log.Printf("%s %s matches the signed attestation", options.SwaggerHubApi, options.Version)
+	}
+
+	revision, err := definitionRevision(openApi)
+	if err != nil {
+		exitAndError(err)
+	}
+	log.Printf("%s %s is at revision %s", options.SwaggerHubApi, options.Version, revision)
+	if options.RevisionFile != "" {
+		if err := ioutil.WriteFile(options.RevisionFile, []byte(revision+"\n"), 0644); err != nil {
+			exitAndError(fmt.Sprintf("can't write the file %s", options.RevisionFile))
+		}
+	}
+
+	if options.Out == "" {
+		os.Stdout.Write(openApi)
+		return
+	}
+	if err := ioutil.WriteFile(options.Out, openApi, 0644); err != nil {
+		exitAndError(fmt.Sprintf("can't write the file %s", options.Out))
+	}
+	log.Printf("%s %s written to %s", options.SwaggerHubApi, options.Version, options.Out)
+	if outputJson {
+		printJson(fetchResult{Status: "fetched", Api: options.SwaggerHubApi, Version: options.Version, Revision: revision, Out: options.Out})
+	}
+}
+
+// verifyFetched checks the fetched definition against the attestation
+// written when it was published. SwaggerHub reformats stored definitions, so
+// the comparison uses the canonical digest instead of the uploaded bytes.
+func verifyFetched(openApi []byte, options *fetchOptions) error {
+	if options.Attestation == "" || options.PublicKey == "" {
+		return fmt.Errorf("--verify needs --attestation and --public-key")
+	}
+
+	publicKey, err := loadPublicKey(options.PublicKey)
+	if err != nil {
+		return err
+	}
+	envelope, err := ioutil.ReadFile(options.Attestation)
+	if err != nil {
+		return fmt.Errorf("can't read the attestation %s", options.Attestation)
+	}
+	statement, err := verifyAttestation(envelope, publicKey)
+	if err != nil {
+		return err
+	}
+
+	predicate := statement.Predicate
+	if !strings.EqualFold(predicate.Api, options.SwaggerHubApi) || predicate.Version != options.Version {
+		return fmt.Errorf("the attestation is for %s %s", predicate.Api, predicate.Version)
+	}
+	digest, err := canonicalDigest(openApi, options.Version)
+	if err != nil {
+		return err
+	}
+	if digest != predicate.CanonicalDigest {
+		return fmt.Errorf("the digest %s doesn't match the attested %s", digest, predicate.CanonicalDigest)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultGitHubApiUrl = "https://api.github.com"
+
+type gitHubRelease struct {
+	Id        int64  `json:"id"`
+	UploadUrl string `json:"upload_url"`
+	Assets    []struct {
+		Id   int64  `json:"id"`
+		Name string `json:"name"`
+	} `json:"assets"`
+}
+
+// gitHubReleaseRepository is the repository of --github-release, checked
+// with the token before the upload.
+func gitHubReleaseRepository(options *commandLineOptions) (string, error) {
+	repository := options.GitHubRepository
+	if repository == "" {
+		repository = os.Getenv("GITHUB_REPOSITORY")
+	}
+	if len(strings.Split(repository, "/")) != 2 {
+		return "", fmt.Errorf("github-repository must be owner/name")
+	}
+	if options.GitHubToken == "" {
+		return "", fmt.Errorf("uploading to a GitHub release needs --github-token or GITHUB_TOKEN")
+	}
+	return repository, nil
+}
+
+// attachToGitHubRelease uploads the published bytes as an asset of the
+// release of the given tag, creating the release when it doesn't exist and
+// replacing an asset with the same name.
+func attachToGitHubRelease(repository string, openApiPath string, openApi []byte, mediaType string, options *commandLineOptions) error {
+	apiUrl := os.Getenv("GITHUB_API_URL")
+	if apiUrl == "" {
+		apiUrl = defaultGitHubApiUrl
+	}
+
+	tag := options.GitHubRelease
+	release := gitHubRelease{}
+	status, err := gitHubRequest("GET", fmt.Sprintf("%s/repos/%s/releases/tags/%s", apiUrl, repository, neturl.PathEscape(tag)), nil, "", options.GitHubToken, &release)
+	if status == http.StatusNotFound {
+		body, _ := json.Marshal(map[string]string{"tag_name": tag, "name": tag})
+		_, err = gitHubRequest("POST", fmt.Sprintf("%s/repos/%s/releases", apiUrl, repository), body, "application/json", options.GitHubToken, &release)
+	}
+	if err != nil {
+		return fmt.Errorf("can't get the release %s of %s: %w", tag, repository, err)
+	}
+
+	name := filepath.Base(openApiPath)
+	for _, asset := range release.Assets {
+		if asset.Name == name {
+			if _, err := gitHubRequest("DELETE", fmt.Sprintf("%s/repos/%s/releases/assets/%d", apiUrl, repository, asset.Id), nil, "", options.GitHubToken, nil); err != nil {
+				return fmt.Errorf("can't replace the asset %s: %w", name, err)
+			}
+		}
+	}
+
+	uploadUrl := strings.SplitN(release.UploadUrl, "{", 2)[0] + "?name=" + neturl.QueryEscape(name)
+	if _, err := gitHubRequest("POST", uploadUrl, openApi, mediaType, options.GitHubToken, nil); err != nil {
+		return fmt.Errorf("can't upload %s to the release %s: %w", name, tag, err)
+	}
+
+	log.Printf("%s attached to the release %s of %s", name, tag, repository)
+	return nil
+}
+
+func gitHubRequest(method string, url string, body []byte, contentType string, token string, result interface{}) (int, error) {
+	request, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Accept", "application/vnd.github+json")
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	client := client()
+	resp, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("github answered %s", resp.Status)
+	}
+	if result != nil {
+		return resp.StatusCode, json.Unmarshal(responseBody, result)
+	}
+	return resp.StatusCode, nil
+}
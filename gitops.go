@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const registryManifestName = "registry.yaml"
+
+type exportOptions struct {
+	Owner  string `flag:"owner"`
+	Out    string `flag:"out" default:"registry"`
+	Output string `flag:"output" default:"text"`
+	connectionOptions
+}
+
+// exportResult is what export gitops prints with --output json.
+type exportResult struct {
+	Status  string `json:"status"`
+	Owner   string `json:"owner"`
+	Out     string `json:"out"`
+	Apis    int    `json:"apis"`
+	Domains int    `json:"domains"`
+}
+
+// registryManifest describes a directory holding the definitions of an
+// owner, one file per version under apis/<name>/ and domains/<name>/.
+type registryManifest struct {
+	Owner     string           `yaml:"owner"`
+	Retention *retentionPolicy `yaml:"retention,omitempty"`
+	Apis      []registryEntry  `yaml:"apis"`
+	Domains   []registryEntry  `yaml:"domains,omitempty"`
+}
+
+type registryEntry struct {
+	Name      string            `yaml:"name"`
+	Default   string            `yaml:"default,omitempty"`
+	Retention *retentionPolicy  `yaml:"retention,omitempty"`
+	Versions  []registryVersion `yaml:"versions"`
+}
+
+type registryVersion struct {
+	Version   string `yaml:"version"`
+	File      string `yaml:"file"`
+	Private   bool   `yaml:"private"`
+	Published bool   `yaml:"published"`
+}
+
+// exportCommand writes every API and domain of an owner into a directory
+// meant to be committed, with registry.yaml listing them and their settings.
+// Files of a previous export that are gone from SwaggerHub are removed, so
+// the directory can be regenerated in place.
+func exportCommand(args []string) {
+	options := exportOptions{}
+	positional := parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+	if len(positional) != 1 || positional[0] != "gitops" {
+		exitAndError("usage: swaggergo export gitops --owner myorg --out registry")
+	}
+	environment := options.useConnection(new(string))
+	if options.Owner == "" {
+		options.Owner = environment.Owner
+	}
+	if options.Owner == "" {
+		exitAndError("missing owner")
+	}
+
+	previous, err := loadRegistryManifest(options.Out)
+	if err != nil && !os.IsNotExist(err) {
+		exitAndError(err)
+	}
+
+	if err := os.MkdirAll(options.Out, 0755); err != nil {
+		exitAndError(fmt.Sprintf("can't create the directory %s", options.Out))
+	}
+	manifest := registryManifest{Owner: options.Owner}
+	manifest.Apis = exportDefinitions(swaggerHubUrls, "apis", "swagger.yaml", &options)
+	manifest.Domains = exportDefinitions(swaggerHubDomainUrls(), "domains", "domain.yaml", &options)
+
+	if previous != nil {
+		manifest.keepRetention(previous)
+	}
+	if err := writeRegistryManifest(options.Out, &manifest); err != nil {
+		exitAndError(err)
+	}
+
+	if previous != nil {
+		written := map[string]bool{}
+		for _, file := range manifest.files() {
+			written[file] = true
+		}
+		for _, file := range previous.files() {
+			if !written[file] {
+				os.Remove(filepath.Join(options.Out, filepath.FromSlash(file)))
+			}
+		}
+	}
+
+	log.Printf("%d APIs and %d domains of %s exported to %s", len(manifest.Apis), len(manifest.Domains), options.Owner, options.Out)
+	if outputJson {
+		printJson(exportResult{Status: "exported", Owner: options.Owner, Out: options.Out, Apis: len(manifest.Apis), Domains: len(manifest.Domains)})
+	}
+}
+
+// exportDefinitions writes every version of the APIs or domains of the owner
+// into <out>/<kind>/<name>/<version>.yaml.
+func exportDefinitions(urls []string, kind string, definitionName string, options *exportOptions) []registryEntry {
+	names, err := listOwner(urls, options.Owner, options.SwaggerHubAccessToken)
+	if err != nil {
+		exitAndError(fmt.Errorf("can't list the %s of %s: %w", kind, options.Owner, err))
+	}
+
+	progress := newProgress("exporting "+kind, len(names))
+	entries := []registryEntry{}
+	for _, name := range names {
+		item := options.Owner + "/" + name
+		progress.start(item)
+		entry, err := exportDefinition(urls, kind, definitionName, name, options)
+		if err != nil {
+			progress.finish(item, err.Error())
+			progress.close()
+			exitAndError(fmt.Errorf("can't export %s: %w", item, err))
+		}
+		progress.finish(item, "")
+		entries = append(entries, entry)
+	}
+	progress.close()
+	return entries
+}
+
+func exportDefinition(urls []string, kind string, definitionName string, name string, options *exportOptions) (registryEntry, error) {
+	entry := registryEntry{Name: name}
+	item := options.Owner + "/" + name
+	versions, err := listVersions(urls, item, options.SwaggerHubAccessToken)
+	if err != nil {
+		return entry, err
+	}
+
+	directory := filepath.Join(options.Out, kind, name)
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return entry, fmt.Errorf("can't create the directory %s", directory)
+	}
+	for _, version := range versions {
+		definition, err := getFromSwaggerHubAt(urls, fmt.Sprintf("%s/%s/%s", item, version.Version, definitionName), options.SwaggerHubAccessToken)
+		if err != nil {
+			return entry, err
+		}
+		file := filepath.ToSlash(filepath.Join(kind, name, version.Version+".yaml"))
+		if err := ioutil.WriteFile(filepath.Join(options.Out, filepath.FromSlash(file)), definition, 0644); err != nil {
+			return entry, fmt.Errorf("can't write the file %s", file)
+		}
+
+		if version.Default {
+			entry.Default = version.Version
+		}
+		entry.Versions = append(entry.Versions, registryVersion{
+			Version:   version.Version,
+			File:      file,
+			Private:   version.Private,
+			Published: version.Published,
+		})
+	}
+	return entry, nil
+}
+
+func loadRegistryManifest(directory string) (*registryManifest, error) {
+	path := filepath.Join(directory, registryManifestName)
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &registryManifest{}
+	if err := yaml.Unmarshal(content, manifest); err != nil {
+		return nil, fmt.Errorf("can't parse %s: %v", path, err)
+	}
+	return manifest, nil
+}
+
+func writeRegistryManifest(directory string, manifest *registryManifest) error {
+	var content bytes.Buffer
+	encoder := yaml.NewEncoder(&content)
+	encoder.SetIndent(2)
+	encoder.Encode(manifest)
+	if err := ioutil.WriteFile(filepath.Join(directory, registryManifestName), content.Bytes(), 0644); err != nil {
+		return fmt.Errorf("can't write into %s", directory)
+	}
+	return nil
+}
+
+// keepRetention carries the retention policies of a previous export over,
+// since SwaggerHub doesn't know about them.
+func (manifest *registryManifest) keepRetention(previous *registryManifest) {
+	manifest.Retention = previous.Retention
+	for _, kind := range []struct{ entries, previous []registryEntry }{
+		{manifest.Apis, previous.Apis},
+		{manifest.Domains, previous.Domains},
+	} {
+		policies := map[string]*retentionPolicy{}
+		for _, entry := range kind.previous {
+			policies[entry.Name] = entry.Retention
+		}
+		for i := range kind.entries {
+			kind.entries[i].Retention = policies[kind.entries[i].Name]
+		}
+	}
+}
+
+// files returns the definition files of the manifest, relative to its
+// directory and with forward slashes.
+func (manifest *registryManifest) files() []string {
+	var files []string
+	for _, entries := range [][]registryEntry{manifest.Apis, manifest.Domains} {
+		for _, entry := range entries {
+			for _, version := range entry.Versions {
+				files = append(files, version.File)
+			}
+		}
+	}
+	return files
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type graphOptions struct {
+	Owner  string `flag:"owner"`
+	Format string `flag:"format" default:"dot"`
+	Out    string `flag:"out"`
+	Output string `flag:"output" default:"text"`
+	connectionOptions
+}
+
+// registryReferencePattern matches references into the registry, as
+// https://api.swaggerhub.com/domains/mycorp/common/1.0.0#/components/schemas/Error.
+var registryReferencePattern = regexp.MustCompile(`/(apis|domains)/([^/#]+)/([^/#]+)/([^/#]+)`)
+
+// graphNode is an API or domain, "apis/owner/name" or "domains/owner/name".
+type graphNode string
+
+func (node graphNode) kind() string {
+	return strings.SplitN(string(node), "/", 2)[0]
+}
+
+func (node graphNode) name() string {
+	return strings.SplitN(string(node), "/", 2)[1]
+}
+
+// graphEdge is a reference from the default version of an API or domain to a
+// version of another one.
+type graphEdge struct {
+	From    graphNode
+	To      graphNode
+	Version string
+}
+
+// graphCommand builds the graph of the references between the APIs and
+// domains of an owner, from their default versions, as Graphviz DOT or a
+// Mermaid flowchart.
+func graphCommand(args []string) {
+	options := graphOptions{}
+	parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+	useDocumentOutput(options.Out)
+	environment := options.useConnection(new(string))
+	if options.Owner == "" {
+		options.Owner = environment.Owner
+	}
+	if options.Owner == "" {
+		exitAndError("missing owner")
+	}
+	write, ok := graphWriters[options.Format]
+	if !ok {
+		exitAndError(fmt.Sprintf("unknown format %s, use dot or mermaid", options.Format))
+	}
+
+	nodes := map[graphNode]bool{}
+	edges := map[graphEdge]bool{}
+	for _, source := range []struct {
+		urls           []string
+		kind           string
+		definitionName string
+	}{
+		{swaggerHubUrls, "apis", "swagger.yaml"},
+		{swaggerHubDomainUrls(), "domains", "domain.yaml"},
+	} {
+		names, err := listOwner(source.urls, options.Owner, options.SwaggerHubAccessToken)
+		if err != nil {
+			exitAndError(fmt.Errorf("can't list the %s of %s: %w", source.kind, options.Owner, err))
+		}
+		for _, name := range names {
+			item := options.Owner + "/" + name
+			node := graphNode(source.kind + "/" + item)
+			nodes[node] = true
+
+			references, err := registryReferences(source.urls, item, source.definitionName, options.SwaggerHubAccessToken)
+			if err != nil {
+				exitAndError(fmt.Errorf("can't read the references of %s: %w", item, err))
+			}
+			for _, reference := range references {
+				if reference.To == node {
+					continue
+				}
+				nodes[reference.To] = true
+				edges[graphEdge{From: node, To: reference.To, Version: reference.Version}] = true
+			}
+		}
+	}
+
+	var sortedNodes []graphNode
+	for node := range nodes {
+		sortedNodes = append(sortedNodes, node)
+	}
+	sort.Slice(sortedNodes, func(i, j int) bool { return sortedNodes[i] < sortedNodes[j] })
+	var sortedEdges []graphEdge
+	for edge := range edges {
+		sortedEdges = append(sortedEdges, edge)
+	}
+	sort.Slice(sortedEdges, func(i, j int) bool {
+		return fmt.Sprint(sortedEdges[i]) < fmt.Sprint(sortedEdges[j])
+	})
+
+	output := io.Writer(os.Stdout)
+	if options.Out != "" {
+		file, err := os.Create(options.Out)
+		if err != nil {
+			exitAndError(fmt.Sprintf("can't write the file %s", options.Out))
+		}
+		defer file.Close()
+		output = file
+	}
+	write(output, sortedNodes, sortedEdges)
+
+	if options.Out != "" {
+		log.Printf("graph of %d nodes and %d references written to %s", len(sortedNodes), len(sortedEdges), options.Out)
+	}
+	printWrittenResult("", options.Out)
+}
+
+// registryReferences returns the references of the default version of an
+// API or domain into the registry. Without a default version the last one
+// listed is used.
+func registryReferences(urls []string, item string, definitionName string, accessToken string) ([]graphEdge, error) {
+	versions, err := listVersions(urls, item, accessToken)
+	if err != nil || len(versions) == 0 {
+		return nil, err
+	}
+	version := versions[len(versions)-1].Version
+	for _, candidate := range versions {
+		if candidate.Default {
+			version = candidate.Version
+		}
+	}
+
+	definition, err := getFromSwaggerHubAt(urls, fmt.Sprintf("%s/%s/%s", item, version, definitionName), accessToken)
+	if err != nil {
+		return nil, err
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(definition, &root); err != nil {
+		return nil, fmt.Errorf("can't parse %s %s: %v", item, version, err)
+	}
+
+	var references []graphEdge
+	for _, ref := range externalReferences(&root) {
+		match := registryReferencePattern.FindStringSubmatch(strings.SplitN(ref, "#", 2)[0])
+		if match == nil {
+			continue
+		}
+		references = append(references, graphEdge{To: graphNode(fmt.Sprintf("%s/%s/%s", match[1], match[2], match[3])), Version: match[4]})
+	}
+	return references, nil
+}
+
+// externalReferences returns every $ref of the tree that points outside of
+// the document.
+func externalReferences(node *yaml.Node) []string {
+	var references []string
+	if ref := scalarValue(mappingValue(node, "$ref")); ref != "" && !strings.HasPrefix(ref, "#") {
+		references = append(references, ref)
+	}
+	for _, child := range node.Content {
+		references = append(references, externalReferences(child)...)
+	}
+	return references
+}
+
+var graphWriters = map[string]func(output io.Writer, nodes []graphNode, edges []graphEdge){
+	"dot": func(output io.Writer, nodes []graphNode, edges []graphEdge) {
+		fmt.Fprintln(output, "digraph swaggergo {")
+		fmt.Fprintln(output, "  rankdir=LR;")
+		for _, node := range nodes {
+			shape := "box"
+			if node.kind() == "domains" {
+				shape = "ellipse"
+			}
+			fmt.Fprintf(output, "  %q [label=%q, shape=%s];\n", node, node.name(), shape)
+		}
+		for _, edge := range edges {
+			fmt.Fprintf(output, "  %q -> %q [label=%q];\n", edge.From, edge.To, edge.Version)
+		}
+		fmt.Fprintln(output, "}")
+	},
+	"mermaid": func(output io.Writer, nodes []graphNode, edges []graphEdge) {
+		ids := map[graphNode]string{}
+		fmt.Fprintln(output, "graph LR")
+		for i, node := range nodes {
+			ids[node] = fmt.Sprintf("n%d", i)
+			if node.kind() == "domains" {
+				fmt.Fprintf(output, "  %s([%q])\n", ids[node], node.name())
+			} else {
+				fmt.Fprintf(output, "  %s[%q]\n", ids[node], node.name())
+			}
+		}
+		for _, edge := range edges {
+			fmt.Fprintf(output, "  %s -->|%q| %s\n", ids[edge.From], edge.Version, ids[edge.To])
+		}
+	},
+}
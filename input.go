@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// stdinPath reads the definition from the standard input, as in
+// `envsubst < api.yml | swaggergo publish - --api owner/name`.
+const stdinPath = "-"
+
+// readDefinition reads a definition from a file, from the standard input
+// when the path is "-", or downloads it when the path is an http(s) URL,
+// sending header ("Name: value") when given.
+func readDefinition(path string, header string) ([]byte, error) {
+	if path == stdinPath {
+		content, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("can't read the standard input: %v", err)
+		}
+		return content, nil
+	}
+	if isDefinitionUrl(path) {
+		return downloadDefinition(path, header)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the file %s", path)
+	}
+	return content, nil
+}
+
+func isDefinitionUrl(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+func downloadDefinition(url string, header string) ([]byte, error) {
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %s: %v", url, err)
+	}
+	request.Header.Set("Accept", "application/yaml, application/json;q=0.9, */*;q=0.8")
+	if name, value, ok := splitHeader(header); ok {
+		request.Header.Set(name, value)
+	}
+
+	client := client()
+	resp, err := client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("can't download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("can't download %s: %s", url, resp.Status)
+	}
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("can't download %s: %w", url, err)
+	}
+	return content, nil
+}
+
+// definitionType tells a JSON definition from a YAML one, for the
+// Content-Type of the upload. A .yml or .yaml extension is taken at its word,
+// as YAML can also start with {. Otherwise a document starting with { that
+// parses as JSON is JSON, even with a .json extension, and the rest is YAML.
+func definitionType(definitionPath string, content []byte) string {
+	extension := strings.ToLower(path.Ext(definitionPath))
+	if isDefinitionUrl(definitionPath) {
+		if url, err := neturl.Parse(definitionPath); err == nil {
+			extension = strings.ToLower(path.Ext(url.Path))
+		}
+	}
+	if extension == ".yml" || extension == ".yaml" {
+		return "yml"
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("{")) && json.Valid(content) {
+		return "json"
+	}
+	return "yml"
+}
+
+// definitionFileName is the name the definition gets as a release asset or
+// artifact: its own, the last segment of its URL, or openapi.yml (or .json,
+// by --type) when it comes from the standard input or a URL without one.
+// The extension follows the type, which --publish-as may have changed.
+func definitionFileName(definitionPath string, definitionType string) string {
+	name := "openapi." + definitionType
+	if isDefinitionUrl(definitionPath) {
+		if url, err := neturl.Parse(definitionPath); err == nil && path.Ext(url.Path) != "" {
+			name = path.Base(url.Path)
+		}
+	} else if definitionPath != stdinPath {
+		name = definitionPath
+	}
+	extension := strings.ToLower(path.Ext(name))
+	if (definitionType == "json") != (extension == ".json") && (extension == ".json" || extension == ".yml" || extension == ".yaml") {
+		name = strings.TrimSuffix(name, path.Ext(name)) + "." + definitionType
+	}
+	return name
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type inventoryOptions struct {
+	Owner   string `flag:"owner"`
+	Out     string `flag:"out"`
+	Format  string `flag:"format"`
+	Ruleset string `flag:"ruleset" env:"SWAGGERGO_RULESET"`
+	Output  string `flag:"output" default:"text"`
+	connectionOptions
+}
+
+// inventoryEntry is a version in the catalog of an owner.
+type inventoryEntry struct {
+	Api          string `json:"api"`
+	Version      string `json:"version"`
+	Default      bool   `json:"default"`
+	Visibility   string `json:"visibility"`
+	Published    bool   `json:"published"`
+	LastModified string `json:"lastModified"`
+	Score        *int   `json:"standardizationScore"`
+	Team         string `json:"team"`
+}
+
+// inventoryCommand lists every version of every API of an owner with its
+// settings, the share of the rules of the project it passes and the team
+// owning it, taken from info.x-team or else info.contact.name.
+func inventoryCommand(args []string) {
+	options := inventoryOptions{}
+	parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+	environment := options.useConnection(new(string))
+	if options.Owner == "" {
+		options.Owner = environment.Owner
+	}
+	if options.Owner == "" {
+		exitAndError("missing owner")
+	}
+
+	format := options.Format
+	if format == "" {
+		format = "csv"
+		if strings.EqualFold(filepath.Ext(options.Out), ".json") || (outputJson && options.Out == "") {
+			format = "json"
+		}
+	}
+	if format != "csv" && format != "json" {
+		exitAndError(fmt.Sprintf("unknown format %s, use csv or json", format))
+	}
+	if outputJson && options.Out == "" && format != "json" {
+		exitAndError("--output json prints the inventory as JSON, write the CSV with --out")
+	}
+
+	publishOptions := commandLineOptions{CheckSchemas: true, Ruleset: options.Ruleset}
+	publishOptions.Config = options.Config
+	rules, err := publishRules(&publishOptions)
+	if err != nil {
+		exitAndError(err)
+	}
+
+	apis, err := listOwner(swaggerHubUrls, options.Owner, options.SwaggerHubAccessToken)
+	if err != nil {
+		exitAndError(fmt.Errorf("can't list the APIs of %s: %w", options.Owner, err))
+	}
+
+	progress := newProgress("listing versions", len(apis))
+	var entries []inventoryEntry
+	for _, name := range apis {
+		api := options.Owner + "/" + name
+		progress.start(api)
+		apiEntries, err := inventoryOfApi(api, rules, &publishOptions, options.SwaggerHubAccessToken)
+		problem := ""
+		if err != nil {
+			problem = err.Error()
+		}
+		progress.finish(api, problem)
+		if err != nil {
+			progress.close()
+			exitAndError(fmt.Errorf("can't list the versions of %s: %w", api, err))
+		}
+		entries = append(entries, apiEntries...)
+	}
+	progress.close()
+
+	output := io.Writer(os.Stdout)
+	if options.Out != "" {
+		file, err := os.Create(options.Out)
+		if err != nil {
+			exitAndError(fmt.Sprintf("can't write the file %s", options.Out))
+		}
+		defer file.Close()
+		output = file
+	}
+	if format == "json" {
+		err = writeInventoryJson(output, entries)
+	} else {
+		err = writeInventoryCsv(output, entries)
+	}
+	if err != nil {
+		exitAndError(fmt.Errorf("can't write the inventory: %w", err))
+	}
+
+	if options.Out != "" {
+		log.Printf("%d versions of %d APIs of %s written to %s", len(entries), len(apis), options.Owner, options.Out)
+		printWrittenResult("", options.Out)
+	}
+}
+
+func inventoryOfApi(api string, rules []lintRule, options *commandLineOptions, accessToken string) ([]inventoryEntry, error) {
+	versions, err := listVersions(swaggerHubUrls, api, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []inventoryEntry
+	for _, version := range versions {
+		entry := inventoryEntry{
+			Api:          api,
+			Version:      version.Version,
+			Default:      version.Default,
+			Visibility:   "public",
+			Published:    version.Published,
+			LastModified: version.Modified,
+		}
+		if version.Private {
+			entry.Visibility = "private"
+		}
+
+		openApi, err := getFromSwaggerHub(fmt.Sprintf("%s/%s/swagger.yaml", api, version.Version), accessToken)
+		if err != nil {
+			return nil, err
+		}
+		document, err := parseOpenApiDocument(api+"/"+version.Version, openApi)
+		if err != nil {
+			return nil, err
+		}
+		entry.Score = standardizationScore(document, rules, options)
+		entry.Team = scalarValue(document.lookup("info", "x-team"))
+		if entry.Team == "" {
+			entry.Team = scalarValue(document.lookup("info", "contact", "name"))
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// standardizationScore is the percentage of the rules without findings in
+// the document, counting the built-in checks of its kind. It's nil when there
+// are no rules or the document is of an unknown kind.
+func standardizationScore(document *openApiDocument, rules []lintRule, options *commandLineOptions) *int {
+	handler, err := detectSpecHandler(document, "")
+	if err != nil {
+		return nil
+	}
+	rules = append(append(handler.validation(), handler.rules(options)...), rules...)
+	if len(rules) == 0 {
+		return nil
+	}
+	failed := map[string]bool{}
+	for _, finding := range lintDocument(document, rules, options) {
+		failed[finding.Rule] = true
+	}
+	score := 100 * (len(rules) - len(failed)) / len(rules)
+	return &score
+}
+
+func writeInventoryJson(output io.Writer, entries []inventoryEntry) error {
+	if entries == nil {
+		entries = []inventoryEntry{}
+	}
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
+
+func writeInventoryCsv(output io.Writer, entries []inventoryEntry) error {
+	writer := csv.NewWriter(output)
+	writer.Write([]string{"api", "version", "default", "visibility", "published", "last_modified", "standardization_score", "team"})
+	for _, entry := range entries {
+		score := ""
+		if entry.Score != nil {
+			score = strconv.Itoa(*entry.Score)
+		}
+		writer.Write([]string{
+			entry.Api,
+			entry.Version,
+			strconv.FormatBool(entry.Default),
+			entry.Visibility,
+			strconv.FormatBool(entry.Published),
+			entry.LastModified,
+			score,
+			entry.Team,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// jsonPathMatch is a node selected by a JSONPath expression, together with
+// the key it has in its parent and the path from the document root.
+type jsonPathMatch struct {
+	Node *yaml.Node
+	Key  string
+	Path []string
+	Line int
+}
+
+func (match jsonPathMatch) pointer() string {
+	if len(match.Path) == 0 {
+		return "#"
+	}
+	return joinPointer(match.Path...)
+}
+
+type jsonPathStep struct {
+	Recursive bool
+	Wildcard  bool
+	Name      string
+	Index     int
+	IsIndex   bool
+	Filter    string
+	Property  bool
+}
+
+// queryJsonPath evaluates the subset of JSONPath used by Spectral rulesets:
+// child and recursive descent (by name, index or wildcard) and simple filters
+// over @property and fields of @. A trailing ~ selects property names
+// instead of values, as in JSONPath-Plus.
+func queryJsonPath(root *yaml.Node, expression string) ([]jsonPathMatch, error) {
+	steps, err := parseJsonPath(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []jsonPathMatch{{Node: root, Line: root.Line}}
+	for _, step := range steps {
+		var next []jsonPathMatch
+		for _, match := range matches {
+			if step.Property {
+				key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: match.Key, Line: match.Line}
+				next = append(next, jsonPathMatch{Node: key, Key: match.Key, Path: match.Path, Line: match.Line})
+				continue
+			}
+			candidates := []jsonPathMatch{match}
+			if step.Recursive {
+				candidates = descendants(match)
+			}
+			for _, candidate := range candidates {
+				selected, err := applyJsonPathStep(candidate, step)
+				if err != nil {
+					return nil, err
+				}
+				next = append(next, selected...)
+			}
+		}
+		matches = next
+	}
+	return matches, nil
+}
+
+func parseJsonPath(expression string) ([]jsonPathStep, error) {
+	expression = strings.TrimSpace(expression)
+	if !strings.HasPrefix(expression, "$") {
+		return nil, fmt.Errorf("invalid JSONPath %q: must start with $", expression)
+	}
+
+	var steps []jsonPathStep
+	rest := expression[1:]
+	for rest != "" {
+		step := jsonPathStep{}
+		switch {
+		case rest == "~":
+			steps, rest = append(steps, jsonPathStep{Property: true}), ""
+			continue
+		case strings.HasPrefix(rest, ".."):
+			step.Recursive = true
+			rest = rest[2:]
+			if strings.HasPrefix(rest, "[") {
+				break
+			}
+			name, remaining := splitJsonPathName(rest)
+			step.Name, step.Wildcard = name, name == "*"
+			steps, rest = append(steps, step), remaining
+			continue
+		case strings.HasPrefix(rest, "."):
+			name, remaining := splitJsonPathName(rest[1:])
+			if name == "" {
+				return nil, fmt.Errorf("invalid JSONPath %q: empty name", expression)
+			}
+			step.Name, step.Wildcard = name, name == "*"
+			steps, rest = append(steps, step), remaining
+			continue
+		case !strings.HasPrefix(rest, "["):
+			return nil, fmt.Errorf("invalid JSONPath %q near %q", expression, rest)
+		}
+
+		end := matchingBracket(rest)
+		if end < 0 {
+			return nil, fmt.Errorf("invalid JSONPath %q: unclosed bracket", expression)
+		}
+		selector := strings.TrimSpace(rest[1:end])
+		rest = rest[end+1:]
+
+		switch {
+		case selector == "*":
+			step.Wildcard = true
+		case strings.HasPrefix(selector, "?(") && strings.HasSuffix(selector, ")"):
+			step.Filter = strings.TrimSpace(selector[2 : len(selector)-1])
+		case strings.HasPrefix(selector, "'") || strings.HasPrefix(selector, `"`):
+			step.Name = strings.Trim(selector, `'"`)
+		default:
+			index, err := strconv.Atoi(selector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSONPath %q: unsupported selector [%s]", expression, selector)
+			}
+			step.Index, step.IsIndex = index, true
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func splitJsonPathName(path string) (string, string) {
+	end := strings.IndexAny(path, ".[~")
+	if end < 0 {
+		return path, ""
+	}
+	return path[:end], path[end:]
+}
+
+func matchingBracket(path string) int {
+	depth, quote := 0, byte(0)
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func children(match jsonPathMatch) []jsonPathMatch {
+	var result []jsonPathMatch
+	child := func(key string, line int, node *yaml.Node) {
+		path := append(append([]string{}, match.Path...), key)
+		result = append(result, jsonPathMatch{Node: node, Key: key, Path: path, Line: line})
+	}
+	switch match.Node.Kind {
+	case yaml.MappingNode:
+		eachMapping(match.Node, func(key *yaml.Node, value *yaml.Node) {
+			child(key.Value, key.Line, value)
+		})
+	case yaml.SequenceNode:
+		for i, item := range match.Node.Content {
+			child(strconv.Itoa(i), item.Line, item)
+		}
+	}
+	return result
+}
+
+func descendants(match jsonPathMatch) []jsonPathMatch {
+	result := []jsonPathMatch{match}
+	for _, child := range children(match) {
+		result = append(result, descendants(child)...)
+	}
+	return result
+}
+
+func applyJsonPathStep(match jsonPathMatch, step jsonPathStep) ([]jsonPathMatch, error) {
+	var selected []jsonPathMatch
+	for i, child := range children(match) {
+		switch {
+		case step.Wildcard:
+		case step.Filter != "":
+			ok, err := evaluateJsonPathFilter(child, step.Filter)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		case step.IsIndex:
+			if match.Node.Kind != yaml.SequenceNode || (i != step.Index && i != len(match.Node.Content)+step.Index) {
+				continue
+			}
+		case child.Key != step.Name:
+			continue
+		}
+		selected = append(selected, child)
+	}
+	return selected, nil
+}
+
+// evaluateJsonPathFilter supports expressions joined with && or ||, each of
+// them being "@property", "@.field" or "@.field.nested" either on their own
+// (truthiness), negated with "!", or compared with ==, ===, != or !== to a
+// quoted string, a number, true, false or null.
+func evaluateJsonPathFilter(match jsonPathMatch, filter string) (bool, error) {
+	for _, alternative := range strings.Split(filter, "||") {
+		all := true
+		for _, condition := range strings.Split(alternative, "&&") {
+			ok, err := evaluateJsonPathCondition(match, strings.Trim(strings.TrimSpace(condition), "()"))
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func evaluateJsonPathCondition(match jsonPathMatch, condition string) (bool, error) {
+	for _, operator := range []string{"!==", "===", "!=", "=="} {
+		parts := strings.SplitN(condition, operator, 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value, defined, err := filterOperand(match, strings.TrimSpace(parts[0]))
+		if err != nil {
+			return false, err
+		}
+		expected := strings.Trim(strings.TrimSpace(parts[1]), `'"`)
+		equal := defined && value == expected
+		if expected == "null" {
+			equal = !defined || value == "null"
+		}
+		if strings.HasPrefix(operator, "!") {
+			return !equal, nil
+		}
+		return equal, nil
+	}
+
+	negate := strings.HasPrefix(condition, "!")
+	value, defined, err := filterOperand(match, strings.TrimSpace(strings.TrimPrefix(condition, "!")))
+	if err != nil {
+		return false, err
+	}
+	truthy := defined && value != "" && value != "false" && value != "0" && value != "null"
+	return truthy != negate, nil
+}
+
+func filterOperand(match jsonPathMatch, operand string) (string, bool, error) {
+	if operand == "@property" {
+		return match.Key, true, nil
+	}
+	if operand != "@" && !strings.HasPrefix(operand, "@.") {
+		return "", false, fmt.Errorf("unsupported JSONPath filter operand %q", operand)
+	}
+
+	node := match.Node
+	for _, key := range strings.Split(strings.TrimPrefix(strings.TrimPrefix(operand, "@"), "."), ".") {
+		if key == "" {
+			continue
+		}
+		if node = mappingValue(node, key); node == nil {
+			return "", false, nil
+		}
+	}
+	if node.Kind != yaml.ScalarNode {
+		return "[object]", true, nil
+	}
+	return node.Value, true, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const jsonPathDocument = `
+openapi: 3.0.0
+info: {title: Pets, version: "1.0"}
+paths:
+  /pets:
+    get: {operationId: listPets, deprecated: true}
+    post: {operationId: createPet}
+  /pets/{id}:
+    get: {operationId: getPet}
+tags: [{name: pets}, {name: admin}]
+`
+
+func TestQueryJsonPath(t *testing.T) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(jsonPathDocument), &root); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expression string
+		want       []string
+	}{
+		{"$", []string{"#"}},
+		{"$.info.title", []string{"#/info/title"}},
+		{"$.info.missing", nil},
+		{"$.paths[*].get", []string{"#/paths/~1pets/get", "#/paths/~1pets~1{id}/get"}},
+		{"$.paths['/pets'].post.operationId", []string{"#/paths/~1pets/post/operationId"}},
+		{"$..operationId", []string{"#/paths/~1pets/get/operationId", "#/paths/~1pets/post/operationId", "#/paths/~1pets~1{id}/get/operationId"}},
+		{"$.tags[0].name", []string{"#/tags/0/name"}},
+		{"$.tags[-1].name", []string{"#/tags/1/name"}},
+		{"$.paths[*][?(@.deprecated == true)]", []string{"#/paths/~1pets/get"}},
+		{"$.paths[*][?(!@.deprecated)]", []string{"#/paths/~1pets/post", "#/paths/~1pets~1{id}/get"}},
+		{"$.paths[*][?(@property === 'post' || @.operationId == 'getPet')]", []string{"#/paths/~1pets/post", "#/paths/~1pets~1{id}/get"}},
+		{"$.paths[?(@.get && @.post)]", []string{"#/paths/~1pets"}},
+		{"$.tags[?(@.name != null)]", []string{"#/tags/0", "#/tags/1"}},
+		{"$.paths[*]~", []string{"#/paths/~1pets", "#/paths/~1pets~1{id}"}},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			matches, err := queryJsonPath(root.Content[0], test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var pointers []string
+			for _, match := range matches {
+				pointers = append(pointers, match.pointer())
+			}
+			if !reflect.DeepEqual(pointers, test.want) {
+				t.Errorf("got %q, want %q", pointers, test.want)
+			}
+		})
+	}
+}
+
+func TestQueryJsonPathPropertyNames(t *testing.T) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(jsonPathDocument), &root); err != nil {
+		t.Fatal(err)
+	}
+	matches, err := queryJsonPath(root.Content[0], "$.paths[*]~")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, match := range matches {
+		names = append(names, match.Node.Value)
+	}
+	if want := []string{"/pets", "/pets/{id}"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got %q, want %q", names, want)
+	}
+}
+
+func TestParseJsonPathErrors(t *testing.T) {
+	for _, expression := range []string{
+		"info.title",
+		"$.",
+		"$.paths[*",
+		"$.tags[first]",
+		"$info",
+	} {
+		t.Run(expression, func(t *testing.T) {
+			if _, err := parseJsonPath(expression); err == nil {
+				t.Errorf("%q parsed without an error", expression)
+			}
+		})
+	}
+}
+
+func TestQueryJsonPathFilterErrors(t *testing.T) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(jsonPathDocument), &root); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := queryJsonPath(root.Content[0], "$.paths[?(deprecated)]"); err == nil {
+		t.Error("a filter without @ was accepted")
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import "errors"
+
+// keychainService names the API keys of swaggergo in the keychain of the OS,
+// one per profile.
+const keychainService = "swaggergo"
+
+// errNotInKeychain is returned when there's no API key for an account.
+var errNotInKeychain = errors.New("there is no API key in the keychain")
+
+// keychainAccount is the keychain entry of a profile, "default" without one.
+func keychainAccount(profile string) string {
+	if profile == "" {
+		return "default"
+	}
+	return profile
+}
+
+// keychainToken is the API key `login` stored for the profile, or nothing
+// when there's none or no keychain to read it from.
+func keychainToken(profile string) string {
+	token, err := readKeychain(keychainAccount(profile))
+	if err != nil {
+		return ""
+	}
+	return token
+}
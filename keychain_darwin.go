@@ -0,0 +1,39 @@
+//go:build darwin
+// +build darwin
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const keychainName = "macOS keychain"
+
+// writeKeychain stores the API key with security(1). The command goes
+// through its standard input, so the key isn't in its arguments.
+func writeKeychain(account string, token string) error {
+	if strings.ContainsAny(token, "\"\\\n") {
+		return errors.New("the API key has characters the keychain can't be given")
+	}
+	command := exec.Command("security", "-i")
+	command.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a \"%s\" -w \"%s\"\n", keychainService, account, token))
+	if output, err := command.CombinedOutput(); err != nil || len(strings.TrimSpace(string(output))) > 0 {
+		return fmt.Errorf("security: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func readKeychain(account string) (string, error) {
+	output, err := exec.Command("security", "find-generic-password", "-s", keychainService, "-a", account, "-w").Output()
+	var exitError *exec.ExitError
+	if errors.As(err, &exitError) && exitError.ExitCode() == 44 {
+		return "", errNotInKeychain
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
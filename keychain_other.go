@@ -0,0 +1,40 @@
+//go:build !darwin && !windows
+// +build !darwin,!windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const keychainName = "Secret Service"
+
+// writeKeychain stores the API key in the Secret Service (GNOME Keyring,
+// KWallet) with secret-tool, which reads it from its standard input.
+func writeKeychain(account string, token string) error {
+	command := exec.Command("secret-tool", "store", "--label", fmt.Sprintf("%s (%s)", keychainService, account), "service", keychainService, "account", account)
+	command.Stdin = strings.NewReader(token)
+	output, err := command.CombinedOutput()
+	if errors.Is(err, exec.ErrNotFound) {
+		return errors.New("secret-tool isn't installed, it comes with libsecret (libsecret-tools on Debian and Ubuntu)")
+	}
+	if err != nil {
+		return fmt.Errorf("secret-tool: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func readKeychain(account string) (string, error) {
+	output, err := exec.Command("secret-tool", "lookup", "service", keychainService, "account", account).Output()
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(output))
+	if token == "" {
+		return "", errNotInKeychain
+	}
+	return token, nil
+}
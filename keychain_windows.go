@@ -0,0 +1,76 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+const keychainName = "Windows Credential Manager"
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	errorNotFound           = 1168
+)
+
+var (
+	advapi32  = syscall.NewLazyDLL("advapi32.dll")
+	credWrite = advapi32.NewProc("CredWriteW")
+	credRead  = advapi32.NewProc("CredReadW")
+	credFree  = advapi32.NewProc("CredFree")
+)
+
+// credential is the CREDENTIALW of wincred.h.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+func credentialTarget(account string) *uint16 {
+	target, _ := syscall.UTF16PtrFromString(keychainService + ":" + account)
+	return target
+}
+
+// writeKeychain stores the API key as a generic credential of the user.
+func writeKeychain(account string, token string) error {
+	blob := []byte(token)
+	userName, _ := syscall.UTF16PtrFromString(account)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         credentialTarget(account),
+		CredentialBlobSize: uint32(len(blob)),
+		CredentialBlob:     &blob[0],
+		Persist:            credPersistLocalMachine,
+		UserName:           userName,
+	}
+	if result, _, err := credWrite.Call(uintptr(unsafe.Pointer(&cred)), 0); result == 0 {
+		return err
+	}
+	return nil
+}
+
+func readKeychain(account string) (string, error) {
+	var cred *credential
+	result, _, err := credRead.Call(uintptr(unsafe.Pointer(credentialTarget(account))), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if result == 0 {
+		if errno, ok := err.(syscall.Errno); ok && errno == errorNotFound {
+			return "", errNotInKeychain
+		}
+		return "", err
+	}
+	defer credFree.Call(uintptr(unsafe.Pointer(cred)))
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
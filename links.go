@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var linkPattern = regexp.MustCompile("https?://[^\\s<>()\\[\\]\"'`]+")
+
+var linkRules = []lintRule{
+	{Name: "external-links", Severity: "error", Check: checkExternalLinks},
+}
+
+type documentLink struct {
+	Url     string
+	Pointer string
+	Line    int
+}
+
+func documentLinks(document *openApiDocument) []documentLink {
+	var links []documentLink
+	add := func(query string, embedded bool) {
+		matches, _ := queryJsonPath(document.Root, query)
+		for _, match := range matches {
+			value := scalarValue(match.Node)
+			urls := []string{value}
+			if embedded {
+				urls = linkPattern.FindAllString(value, -1)
+			}
+			for _, url := range urls {
+				url = strings.TrimRight(url, ".,;:!?")
+				if url != "" {
+					links = append(links, documentLink{Url: url, Pointer: match.pointer(), Line: match.Line})
+				}
+			}
+		}
+	}
+
+	add("$.info.termsOfService", false)
+	add("$..externalDocs.url", false)
+	add("$..description", true)
+	return links
+}
+
+// linkSettings are the timeout and concurrency of the link checks, checked
+// by publishDefinition before the rules run.
+func linkSettings(options *commandLineOptions) (time.Duration, int, error) {
+	timeout, err := time.ParseDuration(options.LinkTimeout)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid link-timeout %s", options.LinkTimeout)
+	}
+	concurrency, err := strconv.Atoi(options.LinkConcurrency)
+	if err != nil || concurrency < 1 {
+		return 0, 0, fmt.Errorf("invalid link-concurrency %s", options.LinkConcurrency)
+	}
+	return timeout, concurrency, nil
+}
+
+func checkExternalLinks(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	timeout, concurrency, err := linkSettings(options)
+	if err != nil {
+		exitAndError(err)
+	}
+
+	links := documentLinks(document)
+	results := map[string]string{}
+	for _, link := range links {
+		results[link.Url] = ""
+	}
+
+	var mutex sync.Mutex
+	var wait sync.WaitGroup
+	urls := make(chan string)
+	httpClient := client()
+	httpClient.Timeout = timeout
+	progress := newPlainProgress("checking links", len(results))
+	if !options.inBatch {
+		progress = newProgress("checking links", len(results))
+	}
+	for i := 0; i < concurrency; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for url := range urls {
+				progress.start(url)
+				problem := checkLink(&httpClient, url)
+				progress.finish(url, problem)
+				mutex.Lock()
+				results[url] = problem
+				mutex.Unlock()
+			}
+		}()
+	}
+	for url := range results {
+		urls <- url
+	}
+	close(urls)
+	wait.Wait()
+	progress.close()
+
+	var findings []lintFinding
+	for _, link := range links {
+		if problem := results[link.Url]; problem != "" {
+			findings = append(findings, lintFinding{
+				Message: fmt.Sprintf("%s is unreachable: %s", link.Url, problem),
+				Pointer: link.Pointer,
+				Line:    link.Line,
+			})
+		}
+	}
+	return findings
+}
+
+// checkLink sends a HEAD request, falling back to GET for servers that don't
+// implement HEAD, and describes why the link is broken.
+func checkLink(httpClient *http.Client, url string) string {
+	status := 0
+	for _, method := range []string{"HEAD", "GET"} {
+		request, err := http.NewRequest(method, url, nil)
+		if err != nil {
+			return err.Error()
+		}
+		request.Header.Set("User-Agent", fmt.Sprintf("%s/%s", commandLineName, commandLineVersion))
+
+		response, err := httpClient.Do(request)
+		if err != nil {
+			return err.Error()
+		}
+		response.Body.Close()
+
+		status = response.StatusCode
+		if status != http.StatusMethodNotAllowed && status != http.StatusNotImplemented {
+			break
+		}
+	}
+
+	if status >= 400 {
+		return fmt.Sprintf("%d %s", status, http.StatusText(status))
+	}
+	return ""
+}
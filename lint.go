@@ -0,0 +1,345 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type lintFinding struct {
+	Rule     string
+	Severity string
+	Message  string
+	Pointer  string
+	Line     int
+}
+
+type lintRule struct {
+	Name     string
+	Severity string
+	Check    func(document *openApiDocument, options *commandLineOptions) []lintFinding
+}
+
+var errorResponseRules = []lintRule{
+	{Name: "error-response-schema", Severity: "error", Check: checkErrorResponseSchema},
+	{Name: "error-response-content-type", Severity: "error", Check: checkErrorResponseContentType},
+}
+
+var errorStatusCode = regexp.MustCompile(`^[45]([0-9]{2}|XX)$`)
+
+func lintDocument(document *openApiDocument, rules []lintRule, options *commandLineOptions) []lintFinding {
+	var findings []lintFinding
+	for _, rule := range rules {
+		severity := rule.Severity
+		if override, ok := options.ruleOverrides[rule.Name]; ok {
+			if override == "off" {
+				continue
+			}
+			severity = override
+		}
+		for _, finding := range rule.Check(document, options) {
+			finding.Rule = rule.Name
+			finding.Severity = severity
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// reportFindings logs every finding and returns how many of them are errors.
+func reportFindings(document *openApiDocument, findings []lintFinding) int {
+	errors := 0
+	for _, finding := range findings {
+		log.Printf("%s:%d: %s %s: %s", document.Path, finding.Line, finding.Severity, finding.Rule, finding.Message)
+		if finding.Severity == "error" {
+			errors++
+		}
+	}
+	return errors
+}
+
+type errorResponse struct {
+	Operation openApiOperation
+	Code      string
+	Line      int
+	Node      *yaml.Node
+}
+
+func (response errorResponse) String() string {
+	return fmt.Sprintf("%s response of %s", response.Code, response.Operation)
+}
+
+func (response errorResponse) pointer() string {
+	return response.Operation.pointer("responses", response.Code)
+}
+
+func errorResponses(document *openApiDocument) []errorResponse {
+	var responses []errorResponse
+	for _, operation := range document.operations() {
+		eachMapping(mappingValue(operation.Node, "responses"), func(code *yaml.Node, response *yaml.Node) {
+			if !errorStatusCode.MatchString(strings.ToUpper(code.Value)) {
+				return
+			}
+			responses = append(responses, errorResponse{
+				Operation: operation,
+				Code:      code.Value,
+				Line:      code.Line,
+				Node:      document.resolve(response),
+			})
+		})
+	}
+	return responses
+}
+
+func errorSchemaRef(document *openApiDocument, options *commandLineOptions) string {
+	if document.isSwagger2() {
+		return "#/definitions/" + options.ErrorSchema
+	}
+	return "#/components/schemas/" + options.ErrorSchema
+}
+
+func checkErrorResponseSchema(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	var findings []lintFinding
+	expected := errorSchemaRef(document, options)
+
+	for _, response := range errorResponses(document) {
+		var schemas []*yaml.Node
+		if document.isSwagger2() {
+			if schema := mappingValue(response.Node, "schema"); schema != nil {
+				schemas = append(schemas, schema)
+			}
+		} else {
+			eachMapping(mappingValue(response.Node, "content"), func(_ *yaml.Node, mediaType *yaml.Node) {
+				schemas = append(schemas, mappingValue(mediaType, "schema"))
+			})
+		}
+
+		if len(schemas) == 0 {
+			findings = append(findings, lintFinding{
+				Message: fmt.Sprintf("%s has no body, expected %s", response, expected),
+				Pointer: response.pointer(),
+				Line:    response.Line,
+			})
+			continue
+		}
+
+		for _, schema := range schemas {
+			if !referencesSchema(schema, expected) {
+				findings = append(findings, lintFinding{
+					Message: fmt.Sprintf("%s does not reference %s", response, expected),
+					Pointer: response.pointer(),
+					Line:    response.Line,
+				})
+				break
+			}
+		}
+	}
+	return findings
+}
+
+// referencesSchema accepts the error schema itself or a composition that
+// extends it through allOf.
+func referencesSchema(schema *yaml.Node, ref string) bool {
+	if scalarValue(mappingValue(schema, "$ref")) == ref {
+		return true
+	}
+	allOf := mappingValue(schema, "allOf")
+	if allOf == nil {
+		return false
+	}
+	for _, item := range allOf.Content {
+		if scalarValue(mappingValue(item, "$ref")) == ref {
+			return true
+		}
+	}
+	return false
+}
+
+func checkErrorResponseContentType(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	responses := errorResponses(document)
+	contentTypes := make([]string, len(responses))
+	for i, response := range responses {
+		contentTypes[i] = strings.Join(errorResponseContentTypes(document, response), ", ")
+	}
+
+	expectedTypes := splitList(options.ErrorContentType)
+	sort.Strings(expectedTypes)
+	expected := strings.Join(expectedTypes, ", ")
+	if expected == "" {
+		expected = mostCommon(contentTypes)
+	}
+
+	var findings []lintFinding
+	for i, response := range responses {
+		if contentTypes[i] == "" || contentTypes[i] == expected {
+			continue
+		}
+		findings = append(findings, lintFinding{
+			Message: fmt.Sprintf("%s declares %s, expected %s", response, contentTypes[i], expected),
+			Pointer: response.pointer(),
+			Line:    response.Line,
+		})
+	}
+	return findings
+}
+
+func errorResponseContentTypes(document *openApiDocument, response errorResponse) []string {
+	var contentTypes []string
+	if document.isSwagger2() {
+		if mappingValue(response.Node, "schema") == nil {
+			return nil
+		}
+		produces := mappingValue(response.Operation.Node, "produces")
+		if produces == nil {
+			produces = document.lookup("produces")
+		}
+		if produces != nil {
+			for _, item := range produces.Content {
+				contentTypes = append(contentTypes, item.Value)
+			}
+		}
+	} else {
+		eachMapping(mappingValue(response.Node, "content"), func(mediaType *yaml.Node, _ *yaml.Node) {
+			contentTypes = append(contentTypes, mediaType.Value)
+		})
+	}
+	sort.Strings(contentTypes)
+	return contentTypes
+}
+
+func mostCommon(values []string) string {
+	counts := map[string]int{}
+	common := ""
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		counts[value]++
+		if counts[value] > counts[common] {
+			common = value
+		}
+	}
+	return common
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func ruleSeverity(value string) (string, error) {
+	switch {
+	case value == "" || value == "warning":
+		return "warning", nil
+	case spectralSeverities[value] != "":
+		return spectralSeverities[value], nil
+	}
+	return "", fmt.Errorf("invalid severity %q", value)
+}
+
+func expressionRule(definition expressionRuleConfig) (lintRule, error) {
+	severity, err := ruleSeverity(definition.Severity)
+	if err != nil {
+		return lintRule{}, err
+	}
+
+	given := definition.Given
+	if given == "" {
+		given = "$"
+	}
+	if _, err := parseJsonPath(given); err != nil {
+		return lintRule{}, err
+	}
+	if definition.Assert == "" {
+		return lintRule{}, fmt.Errorf("assert is required")
+	}
+	assert, err := compileExpression(definition.Assert)
+	if err != nil {
+		return lintRule{}, err
+	}
+
+	check := func(document *openApiDocument, options *commandLineOptions) []lintFinding {
+		matches, _ := queryJsonPath(document.Root, given)
+		var findings []lintFinding
+		for _, match := range matches {
+			if value, err := assert(match.Node, match.Key); err == nil && truthy(value) {
+				continue
+			}
+			message := fmt.Sprintf("%s does not satisfy %s", match.pointer(), definition.Assert)
+			if definition.Message != "" {
+				message = strings.NewReplacer("{{property}}", match.Key, "{{path}}", match.pointer()).Replace(definition.Message)
+			}
+			findings = append(findings, lintFinding{Message: message, Pointer: match.pointer(), Line: match.Line})
+		}
+		return findings
+	}
+	return lintRule{Name: definition.Name, Severity: severity, Check: check}, nil
+}
+
+type lintOptions struct {
+	Ruleset    string `flag:"ruleset" env:"SWAGGERGO_RULESET" config:"lint.ruleset"`
+	Config     string `flag:"config" env:"SWAGGERGO_CONFIG"`
+	Report     string `flag:"report"`
+	ReportFile string `flag:"report-file"`
+	Output     string `flag:"output" default:"text"`
+}
+
+// lintCommand checks definitions with the rules --lint adds to a
+// publication: the spectral:oas rules, or the ruleset given instead, and the
+// rules of the config. Only errors make it fail.
+func lintCommand(args []string) {
+	options := lintOptions{}
+	paths := parseArgs(&options, args)
+	if len(paths) == 0 {
+		exitAndError("lint needs the path to the OpenAPI definition")
+	}
+	checkReportFormat(options.Report)
+	useOutput(options.Output, "text", "github", "json")
+	publishOptions := commandLineOptions{Ruleset: options.Ruleset, Lint: true}
+	publishOptions.Config = options.Config
+	rules, err := publishRules(&publishOptions)
+	if err != nil {
+		exitAndError(err)
+	}
+
+	var results []definitionFindings
+	failed := 0
+	for _, path := range paths {
+		document, err := readOpenApiDocument(path)
+		var findings []lintFinding
+		if err == nil {
+			findings, err = lintSpec(document, rules, &publishOptions)
+		}
+		if err != nil {
+			log.Print(err)
+			results = append(results, definitionFindings{Path: path, Err: err})
+			failed++
+			continue
+		}
+		results = append(results, definitionFindings{Path: path, Findings: findings})
+		if reportFindings(document, findings) > 0 {
+			failed++
+		}
+		log.Printf("%s: %d findings", path, len(findings))
+	}
+	writeFindingsReport(options.Report, options.ReportFile, results)
+	if options.Output == "github" {
+		writeFindingsReport("github", "", results)
+	}
+	if outputJson {
+		printCheckResult(results)
+		return
+	}
+	if failed > 0 {
+		exitWithCode(exitInvalid, fmt.Sprintf("%d of %d definitions have errors", failed, len(paths)))
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const errorSchemaComponents = `
+components:
+  schemas:
+    Error: {type: object}
+    Other: {type: object}
+  responses:
+    NotFound:
+      description: not found
+      content:
+        application/json:
+          schema: {$ref: '#/components/schemas/Error'}
+    Conflict:
+      description: conflict
+      content:
+        application/json:
+          schema: {$ref: '#/components/schemas/Other'}
+`
+
+func TestCheckErrorResponseSchema(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses string
+		want      []string
+	}{
+		{"error schema", `"400": {description: bad, content: {application/json: {schema: {$ref: '#/components/schemas/Error'}}}}`, nil},
+		{"other schema", `"400": {description: bad, content: {application/json: {schema: {$ref: '#/components/schemas/Other'}}}}`, []string{"400 response of GET /pets does not reference #/components/schemas/Error"}},
+		{"extended with allOf", `"400": {description: bad, content: {application/json: {schema: {allOf: [{$ref: '#/components/schemas/Error'}, {type: object}]}}}}`, nil},
+		{"referenced response", `"404": {$ref: '#/components/responses/NotFound'}`, nil},
+		{"referenced response with another schema", `"409": {$ref: '#/components/responses/Conflict'}`, []string{"409 response of GET /pets does not reference #/components/schemas/Error"}},
+		{"no body", `"401": {description: unauthorized}`, []string{"401 response of GET /pets has no body, expected #/components/schemas/Error"}},
+		{"4XX range", `"4XX": {description: client error, content: {application/json: {schema: {type: object}}}}`, []string{"4XX response of GET /pets does not reference #/components/schemas/Error"}},
+		{"5XX range", `"5XX": {description: server error, content: {application/json: {schema: {type: object}}}}`, []string{"5XX response of GET /pets does not reference #/components/schemas/Error"}},
+		{"lower case range", `"5xx": {description: server error}`, []string{"5xx response of GET /pets has no body, expected #/components/schemas/Error"}},
+		{"default", `default: {description: anything, content: {application/json: {schema: {type: object}}}}`, nil},
+		{"success", `"200": {description: ok, content: {application/json: {schema: {type: array}}}}`, nil},
+		{"one of several media types", `"400": {description: bad, content: {application/json: {schema: {$ref: '#/components/schemas/Error'}}, application/xml: {schema: {type: object}}}}`, []string{"400 response of GET /pets does not reference #/components/schemas/Error"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document := lintTestDocument(t, "openapi: 3.0.0\ninfo: {title: Pets, version: \"1.0\"}\npaths:\n  /pets:\n    get:\n      responses:\n        "+test.responses+"\n"+errorSchemaComponents)
+			got := findingMessages(checkErrorResponseSchema(document, &commandLineOptions{ErrorSchema: "Error"}))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckErrorResponseSchemaSwagger2(t *testing.T) {
+	document := lintTestDocument(t, `
+swagger: "2.0"
+info: {title: Pets, version: "1.0"}
+paths:
+  /pets:
+    get:
+      responses:
+        "400": {description: bad, schema: {$ref: '#/definitions/Error'}}
+        "500": {description: failed, schema: {$ref: '#/definitions/Other'}}
+definitions:
+  Error: {type: object}
+  Other: {type: object}
+`)
+	got := findingMessages(checkErrorResponseSchema(document, &commandLineOptions{ErrorSchema: "Error"}))
+	if want := []string{"500 response of GET /pets does not reference #/definitions/Error"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCheckErrorResponseContentType(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses string
+		expected  string
+		want      []string
+	}{
+		{"consistent", `{"400": {description: bad, content: {application/problem+json: {}}}, "500": {description: failed, content: {application/problem+json: {}}}}`, "", nil},
+		{"most common wins", `{"400": {description: bad, content: {application/problem+json: {}}}, "404": {description: missing, content: {application/problem+json: {}}}, "500": {description: failed, content: {application/json: {}}}}`, "", []string{"500 response of GET /pets declares application/json, expected application/problem+json"}},
+		{"configured", `{"400": {description: bad, content: {application/json: {}}}}`, "application/problem+json", []string{"400 response of GET /pets declares application/json, expected application/problem+json"}},
+		{"configured list", `{"400": {description: bad, content: {application/problem+json: {}, application/problem+xml: {}}}}`, "application/problem+xml, application/problem+json", nil},
+		{"5XX range", `{"400": {description: bad, content: {application/problem+json: {}}}, "5XX": {description: failed, content: {application/vnd.error+json: {}}}}`, "application/problem+json", []string{"5XX response of GET /pets declares application/vnd.error+json, expected application/problem+json"}},
+		{"default left out", `{"400": {description: bad, content: {application/problem+json: {}}}, default: {description: other, content: {text/plain: {}}}}`, "", nil},
+		{"no body left out", `{"400": {description: bad, content: {application/problem+json: {}}}, "401": {description: unauthorized}}`, "", nil},
+		{"referenced response", `{"400": {description: bad, content: {application/problem+json: {}}}, "404": {$ref: '#/components/responses/NotFound'}}`, "application/problem+json", []string{"404 response of GET /pets declares application/json, expected application/problem+json"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document := lintTestDocument(t, "openapi: 3.0.0\ninfo: {title: Pets, version: \"1.0\"}\npaths:\n  /pets:\n    get:\n      responses: "+test.responses+"\n"+errorSchemaComponents)
+			got := findingMessages(checkErrorResponseContentType(document, &commandLineOptions{ErrorContentType: test.expected}))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckErrorResponseContentTypeSwagger2(t *testing.T) {
+	document := lintTestDocument(t, `
+swagger: "2.0"
+info: {title: Pets, version: "1.0"}
+produces: [application/problem+json]
+paths:
+  /pets:
+    get:
+      responses:
+        "400": {description: bad, schema: {type: object}}
+    post:
+      produces: [application/json]
+      responses:
+        "400": {description: bad, schema: {type: object}}
+`)
+	got := findingMessages(checkErrorResponseContentType(document, &commandLineOptions{ErrorContentType: "application/problem+json"}))
+	if want := []string{"400 response of POST /pets declares application/json, expected application/problem+json"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func lintTestDocument(t *testing.T, content string) *openApiDocument {
+	document, err := parseOpenApiDocument("openapi.yml", []byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return document
+}
+
+func findingMessages(findings []lintFinding) []string {
+	var messages []string
+	for _, finding := range findings {
+		messages = append(messages, finding.Message)
+	}
+	return messages
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+type listOptions struct {
+	Owner  string `flag:"owner"`
+	Output string `flag:"output" default:"text"`
+	Format string `flag:"format"`
+	connectionOptions
+}
+
+// listEntry is an API of the owner as printed by list.
+type listEntry struct {
+	Name         string `json:"name"`
+	Version      string `json:"version"`
+	Visibility   string `json:"visibility"`
+	LastModified string `json:"lastModified"`
+}
+
+// listCommand prints the APIs of an owner with their current version, from
+// a single listing of the registry, as a table or JSON for scripts.
+func listCommand(args []string) {
+	options := listOptions{}
+	parseArgs(&options, args)
+	useFormat(options.Format, &options.Output)
+	useOutput(options.Output, "text", "json")
+	environment := options.useConnection(new(string))
+	if options.Owner == "" {
+		options.Owner = environment.Owner
+	}
+	if options.Owner == "" {
+		exitAndError("missing owner")
+	}
+
+	apis, err := swaggerHub(swaggerHubUrls, options.SwaggerHubAccessToken).ListOwner(runContext, options.Owner)
+	if err != nil {
+		exitAndError(fmt.Errorf("can't list the APIs of %s: %w", options.Owner, err))
+	}
+	entries := []listEntry{}
+	for _, api := range apis {
+		entries = append(entries, listEntry{
+			Name:         options.Owner + "/" + api.Name,
+			Version:      api.Version,
+			Visibility:   visibilityName(api.Private),
+			LastModified: api.Modified,
+		})
+	}
+
+	if outputJson {
+		printJson(entries)
+		return
+	}
+	rows := [][]string{{"NAME", "VERSION", "VISIBILITY", "LAST MODIFIED"}}
+	for _, entry := range entries {
+		rows = append(rows, []string{entry.Name, entry.Version, entry.Visibility, entry.LastModified})
+	}
+	printTable(rows)
+}
+
+// printTable prints rows aligned in columns, the first one being the header.
+func printTable(rows [][]string) {
+	table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintln(table, strings.Join(row, "\t"))
+	}
+	table.Flush()
+}
+
+func printJson(value interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		exitAndError(fmt.Errorf("can't write the output: %w", err))
+	}
+}
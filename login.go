@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+type loginOptions struct {
+	AccessTokenFile  string `flag:"access-token-file" env:"SWAGGERHUB_ACCESS_TOKEN_FILE"`
+	AccessTokenStdin bool   `flag:"access-token-stdin"`
+	Profile          string `flag:"profile" env:"SWAGGERGO_PROFILE"`
+	Output           string `flag:"output" default:"text"`
+}
+
+// loginResult is what login prints with --output json.
+type loginResult struct {
+	Status   string `json:"status"`
+	Profile  string `json:"profile"`
+	Keychain string `json:"keychain"`
+}
+
+// loginCommand stores a SwaggerHub API key in the keychain of the OS, for
+// the profile given with --profile or the default one. The commands use it
+// when no token is given with flags, variables or the profile.
+func loginCommand(args []string) {
+	options := loginOptions{}
+	parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+
+	token, _, err := readAccessTokenSource(options.AccessTokenFile, options.AccessTokenStdin)
+	if err != nil {
+		exitAndError(err)
+	}
+	if token == "" {
+		if token, err = promptSecret("SwaggerHub API key: "); err != nil {
+			exitAndError(err)
+		}
+		if token == "" {
+			exitAndError("no API key was given, nothing was stored")
+		}
+	}
+
+	account := keychainAccount(options.Profile)
+	if err := writeKeychain(account, token); err != nil {
+		exitAndError(fmt.Errorf("can't store the API key in the %s: %w", keychainName, err))
+	}
+	log.Printf("the API key of the %s profile is stored in the %s", account, keychainName)
+	if outputJson {
+		printJson(loginResult{Status: "stored", Profile: account, Keychain: keychainName})
+	}
+}
+
+// promptSecret reads a line from the terminal without showing it, and fails
+// when the standard input isn't a terminal that can hide it.
+func promptSecret(prompt string) (string, error) {
+	if err := setEcho(os.Stdin, false); err != nil {
+		return "", errors.New("there's no terminal to type the API key on, use --access-token-stdin or --access-token-file")
+	}
+	// a Ctrl-C doesn't leave the terminal without echo
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-runContext.Done():
+			setEcho(os.Stdin, true)
+			exitAndError("nothing was stored")
+		case <-done:
+		}
+	}()
+	defer func() {
+		close(done)
+		setEcho(os.Stdin, true)
+		fmt.Fprintln(os.Stderr)
+	}()
+
+	fmt.Fprint(os.Stderr, prompt)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("can't read the API key: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// logJson is set by --log-format json: the logs are JSON lines with a level,
+// a timestamp and the fields of logFields, for log aggregators to index.
+var logJson bool
+
+// logFields are added to every JSON log line, as the API and version being
+// published.
+var logFields = map[string]interface{}{}
+
+// useLogFormat applies --log-format (or SWAGGERGO_LOG_FORMAT), text or json,
+// and returns args without it, which every command accepts. The format goes
+// on in the environment for the publications of a batch.
+func useLogFormat(args []string) []string {
+	format := os.Getenv("SWAGGERGO_LOG_FORMAT")
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case (args[i] == "--log-format" || args[i] == "-log-format") && i+1 < len(args):
+			format = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--log-format=") || strings.HasPrefix(args[i], "-log-format="):
+			format = args[i][strings.Index(args[i], "=")+1:]
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+
+	switch format {
+	case "", "text":
+	case "json":
+		logJson = true
+		log.SetFlags(0)
+		log.SetOutput(jsonLogWriter{os.Stderr})
+		os.Setenv("SWAGGERGO_LOG_FORMAT", format)
+	default:
+		exitAndError(fmt.Sprintf("unknown log format %s, use text or json", format))
+	}
+	return rest
+}
+
+// logWith logs a message with fields of its own, as the duration of a
+// publication, which only the JSON lines carry.
+func logWith(fields map[string]interface{}, format string, args ...interface{}) {
+	if !logJson {
+		log.Printf(format, args...)
+		return
+	}
+	writeLogEntry(os.Stderr, "info", fmt.Sprintf(format, args...), fields)
+}
+
+func writeLogEntry(out io.Writer, level string, message string, fields map[string]interface{}) {
+	entry := map[string]interface{}{}
+	for name, value := range logFields {
+		entry[name] = value
+	}
+	for name, value := range fields {
+		entry[name] = value
+	}
+	entry["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	entry["level"] = level
+	entry["message"] = message
+	line, _ := json.Marshal(entry)
+	out.Write(append(line, '\n'))
+}
+
+// jsonLogWriter turns the lines of the log package, one per Write, into JSON
+// lines.
+type jsonLogWriter struct {
+	out io.Writer
+}
+
+func (writer jsonLogWriter) Write(line []byte) (int, error) {
+	if message := strings.TrimSpace(string(line)); message != "" {
+		writeLogEntry(writer.out, "info", message, nil)
+	}
+	return len(line), nil
+}
+
+// logDuration is the duration field of the JSON log lines, in milliseconds.
+func logDuration(started time.Time) map[string]interface{} {
+	return map[string]interface{}{"duration": time.Since(started).Milliseconds()}
+}
@@ -1,33 +1,260 @@
 package main
 
 import (
-	"bytes"
+	"crypto"
+	"errors"
 	"flag"
 	"fmt"
+	"github.com/mijailr/swaggergo/pkg/swaggerhub"
 	"github.com/oleiade/reflections"
-	"io/ioutil"
 	"log"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 )
 
-const swaggerHubUrl = "https://api.swaggerhub.com/apis"
+const swaggerHubUrl = swaggerhub.DefaultUrl
 
 var commandLineName = "swaggergo"
+
 var commandLineVersion = "1.0.0"
 var commandLineUsage = `swaggergo is an utility for publishing OpenAPI definitions to SwaggerHub.
 
 Usage:
-  $ swaggergo path/to/openapi.yml --type (yml | json) --oas 3.0.0 --api mijailr/sample-api --access-token [...]
+  $ swaggergo publish path/to/openapi.yml --api mijailr/sample-api --access-token [...]
+
+The publish command can be omitted, as in the examples below. Every command
+lists its flags with:
+
+  $ swaggergo help <command>
+
+The definition can also come from the standard input or a URL:
+
+  $ envsubst < api.yml | swaggergo publish - --api mijailr/sample-api
+  $ swaggergo publish https://specs.internal/orders/openapi.json --file-header "Authorization: Bearer ..." --api mijailr/sample-api
+
+The access token can be read from a file, as a mounted secret, or from the
+standard input, as piped by a secret manager:
+
+  $ swaggergo publish path/to/openapi.yml --api mijailr/sample-api --access-token-file /run/secrets/swaggerhub
+  $ vault read -field=token secret/swaggerhub | swaggergo publish path/to/openapi.yml --api mijailr/sample-api --access-token-stdin
+
+Or stored once in the keychain of the OS, for every command to use:
+
+  $ swaggergo login [--profile name]
+
+Several definitions, or glob patterns, are published one after the other with
+a summary at the end, each to the API of its x-swaggerhub.api, of the publish
+mappings of the config or of --api:
+
+  $ swaggergo publish 'specs/**/*.yml' --access-token [...]
+
+The settings can also come from swaggergo.yml (api, file, type, oas, version,
+visibility and lint), with flags winning over environment variables and both
+over the config:
+
+  $ swaggergo publish
+
+The options a command would use, and where each one comes from, are shown
+with:
+
+  $ swaggergo config show [command] [flags ...]
+
+Accounts can be kept as profiles in the user config and picked by name:
+
+  $ swaggergo publish path/to/openapi.yml --api orders --profile staging
+
+SwaggerHub On-Premise is used by its URL, which defaults to the /v1/apis
+endpoint when it has no path:
 
-Environment variables can also be used:
+  $ swaggergo path/to/openapi.yml --api mycorp/orders --registry-url https://swaggerhub.mycorp.com
+
+Environment variables can also be used, and read from .env (or the file given
+with --env-file) when they aren't set:
 
   $ export SWAGGERHUB_ACCESS_TOKEN="..."
   $ export SWAGGERHUB_API="..."
-  $ swaggergo --file path/to/openapi.yml --type (yml | json)
+  $ swaggergo --file path/to/openapi.yml
+  $ swaggergo --file path/to/openapi.yml --env-file local.env
+
+Error responses can be checked against a shared schema before publishing:
+
+  $ swaggergo path/to/openapi.yml --api mijailr/sample-api --error-schema Error --error-content-type application/problem+json
+
+Schemas can be checked against the JSON Schema dialect of the document (the
+OpenAPI subset for 2.0/3.0, JSON Schema 2020-12 for 3.1):
+
+  $ swaggergo path/to/openapi.yml --api mijailr/sample-api --check-schemas
+
+Existing Spectral rulesets can be used to gate the publication:
+
+  $ swaggergo path/to/openapi.yml --api mijailr/sample-api --ruleset .spectral.yaml
+
+Links in externalDocs, info.termsOfService and descriptions can be checked:
+
+  $ swaggergo path/to/openapi.yml --api mijailr/sample-api --check-links --link-timeout 5s --link-concurrency 4
+
+The servers of the document can be probed to avoid publishing decommissioned
+hosts (dns: the host resolves, tls: it also accepts connections with a valid
+certificate, http: it also answers without a 5xx status):
+
+  $ swaggergo path/to/openapi.yml --api mijailr/sample-api --check-servers --server-check (dns | tls | http)
+
+In pull requests, the checks can be limited to the operations added or changed
+since a git reference:
+
+  $ swaggergo path/to/openapi.yml --api mijailr/sample-api --ruleset .spectral.yaml --changed-only --git-ref main
+
+The published bytes can be signed, writing a detached signature and an in-toto
+attestation next to the definition:
+
+  $ swaggergo path/to/openapi.yml --api mijailr/sample-api --sign --signing-key path/to/key.pem
+
+The published definition can be attached to the GitHub release of a tag:
+
+  $ swaggergo path/to/openapi.yml --api mijailr/sample-api --github-release v1.4.0 --github-repository mijailr/sample-api --github-token [...]
+
+A copy of the published definition can be archived into S3 or GCS:
+
+  $ swaggergo path/to/openapi.yml --api mijailr/sample-api --artifact-store s3://bucket/prefix
+
+A CloudEvent can be sent to a sink or broker after every publication:
+
+  $ swaggergo path/to/openapi.yml --api mijailr/sample-api --events-url https://broker.example.com/events [--events-header "Authorization: Bearer ..."]
+
+Publication metrics can be sent to a StatsD or DogStatsD agent:
+
+  $ swaggergo path/to/openapi.yml --api mijailr/sample-api --statsd 127.0.0.1:8125 [--statsd-tags team:payments,env:prod]
+
+Uploads can be throttled so big publications don't saturate shared links:
+
+  $ swaggergo path/to/openapi.yml --api mijailr/sample-api --max-upload-rate 1MiB/s
+
+New APIs can land private or public instead of with the default of the account:
+
+  $ swaggergo path/to/openapi.yml --api mijailr/sample-api --visibility private
+
+Everything can be checked without uploading, reporting what would be sent:
+
+  $ swaggergo path/to/openapi.yml --api mijailr/sample-api --dry-run
+
+Published versions are read only, --force overwrites them anyway:
+
+  $ swaggergo path/to/openapi.yml --api mijailr/sample-api --force
+
+The published version can be made the default one shown in the portal, and
+its lifecycle flipped to published so consumers see it:
+
+  $ swaggergo path/to/openapi.yml --api mijailr/sample-api --set-default --publish-lifecycle
+
+Hosts missing from the DNS can be resolved by hand, like curl does:
+
+  $ swaggergo path/to/openapi.yml --api mijailr/sample-api --resolve swaggerhub.internal:443:10.1.2.3 [--dns-server 10.0.0.53]
+
+Named environments of the config give the SwaggerHub URL, owner, token and
+visibility of each stage:
+
+  $ swaggergo path/to/openapi.yml --api sample-api --env prod
+
+Custom rules are read from swaggergo.yml (or the file given with --config):
+
+  $ swaggergo path/to/openapi.yml --api mijailr/sample-api --config path/to/swaggergo.yml
+
+Lint definitions with the spectral:oas rules, or a ruleset, and the rules of
+the config (--lint runs the same rules before publishing):
+
+  $ swaggergo lint path/to/openapi.yml [--ruleset .spectral.yaml]
+
+Check that definitions are valid OpenAPI without publishing them (the same
+checks run before every publication):
+
+  $ swaggergo validate path/to/openapi.yml [--report junit --report-file results.xml]
+
+Fetch a definition, optionally verifying it against a signed attestation:
+
+  $ swaggergo fetch --api mijailr/sample-api --version 1.0.0 --out openapi.yml [--verify --attestation openapi.yml.intoto.json --public-key cosign.pub]
+
+Verify that a published version matches the local definition:
+
+  $ swaggergo verify --api mijailr/sample-api --api-version 1.0.0 path/to/openapi.yml
+
+Compare a local definition with the default version on SwaggerHub, or another one:
+
+  $ swaggergo diff path/to/openapi.yml --api mijailr/sample-api [--api-version 1.0.0] [--fail-on-breaking]
+
+Promote a version between the accounts of two profiles:
+
+  $ swaggergo promote --from-profile staging --to-profile prod --api mijailr/sample-api --api-version 1.0.0
+
+List the APIs of an owner with their version, visibility and last change:
+
+  $ swaggergo list --owner mijailr [--output json]
+
+List the versions of an API with their OAS level, lifecycle and default flag:
+
+  $ swaggergo versions --api mijailr/sample-api [--output json]
+
+Delete a version of an API, confirming on the terminal or with --yes:
+
+  $ swaggergo delete --api mijailr/sample-api --version 1.0.0 [--yes]
+
+The whole API is deleted with every version, repeating its name to confirm:
+
+  $ swaggergo delete --api mijailr/sample-api --all-versions --confirm mijailr/sample-api
+
+Publish, fetch and list domains, the shared components APIs reference:
+
+  $ swaggergo domain publish shared-models.yml --domain mijailr/common-models --version 1.0.0 [--set-default]
+  $ swaggergo domain fetch --domain mijailr/common-models --version 1.0.0 --out shared-models.yml
+  $ swaggergo domain list (--owner mijailr | --domain mijailr/common-models) [--output json]
+
+Export the catalog of the APIs of an owner as CSV or JSON:
+
+  $ swaggergo inventory --owner mijailr (--out inventory.csv | --output json)
+
+Export every API and domain of an owner into a directory to commit:
+
+  $ swaggergo export gitops --owner mijailr --out registry
+
+Reconcile SwaggerHub with an exported directory, showing the plan first:
+
+  $ swaggergo apply --dir registry [--prune] [--plan | --auto-approve]
+
+Graph the references between the APIs and domains of an owner:
+
+  $ swaggergo graph --owner mijailr --format (dot | mermaid) [--out graph.dot]
+
+Report the versions created lately, emailing the notification recipients:
+
+  $ swaggergo digest --owner mijailr [--since 168h] [--no-email]
+
+Delete the versions beyond a retention window:
+
+  $ swaggergo retention apply --api mijailr/sample-api --keep 10 [--keep-published] [--plan | --auto-approve]
+
+Probe a live environment, checking that GET operations answer as documented:
+
+  $ swaggergo probe --base-url https://api.example.com path/to/openapi.yml [--operations listPets,getPet] [--header "Authorization: Bearer ..."]
+
+Build static HTML docs with Redoc or Swagger UI:
+
+  $ swaggergo docs build path/to/openapi.yml --out site [--renderer swagger-ui]
+
+Generate a client with a locally installed generator, after checking the definition:
+
+  $ swaggergo codegen local path/to/openapi.yml --lang go [--generator openapi-generator] [--config gen.yaml] [--out generated/go]
+
+Push a definition to an OCI registry as an artifact:
+
+  $ swaggergo push oci://registry.example.com/apis/orders:1.4.0 path/to/openapi.yml [--username ... --password ...] [--plain-http]
+
+Shell completion for PowerShell or bash:
+
+  $ swaggergo completion powershell | Out-String | Invoke-Expression
 
 Version:
   $ swaggergo --version
@@ -38,36 +265,139 @@ Help:
 See https://github.com/mijailr/swaggergo for more information.`
 
 type commandLineOptions struct {
-	SwaggerHubAccessToken string `flag:"access-token" env:"SWAGGERHUB_ACCESS_TOKEN" required:"true"`
-	SwaggerHubApi         string `flag:"api" env:"SWAGGERHUB_API" required:"true"`
-	Type                  string `flag:"type" default:"yml"`
-	Oas                   string `flag:"oas" default:"3.0.0"`
+	SwaggerHubApi    string `flag:"api" env:"SWAGGERHUB_API" config:"api"`
+	ApiFromSpec      bool   `flag:"api-from-spec"`
+	Bundle           bool   `flag:"bundle" env:"SWAGGERGO_BUNDLE" config:"bundle"`
+	RefHeader        string `flag:"ref-header" env:"SWAGGERGO_REF_HEADER"`
+	NoRemoteRefs     bool   `flag:"no-remote-refs" env:"SWAGGERGO_NO_REMOTE_REFS" config:"noRemoteRefs"`
+	File             string `flag:"file" config:"file"`
+	FileHeader       string `flag:"file-header" env:"SWAGGERGO_FILE_HEADER"`
+	Concurrency      string `flag:"concurrency" env:"SWAGGERGO_CONCURRENCY" default:"1"`
+	Type             string `flag:"type" config:"type"`
+	PublishAs        string `flag:"publish-as" env:"SWAGGERGO_PUBLISH_AS" config:"publishAs"`
+	SpecType         string `flag:"spec-type" env:"SWAGGERGO_SPEC_TYPE" config:"specType"`
+	Oas              string `flag:"oas" config:"oas"`
+	ApiVersion       string `flag:"api-version" config:"version"`
+	VersionFrom      string `flag:"version-from" env:"SWAGGERGO_VERSION_FROM" config:"versionFrom"`
+	VersionSuffix    string `flag:"version-suffix" env:"SWAGGERGO_VERSION_SUFFIX" config:"versionSuffix"`
+	ErrorSchema      string `flag:"error-schema" env:"SWAGGERGO_ERROR_SCHEMA" config:"lint.errorSchema"`
+	ErrorContentType string `flag:"error-content-type" env:"SWAGGERGO_ERROR_CONTENT_TYPE" config:"lint.errorContentType"`
+	CheckSchemas     bool   `flag:"check-schemas" config:"lint.checkSchemas"`
+	Ruleset          string `flag:"ruleset" env:"SWAGGERGO_RULESET" config:"lint.ruleset"`
+	Lint             bool   `flag:"lint" config:"lint.enabled"`
+	CheckLinks       bool   `flag:"check-links" config:"lint.checkLinks"`
+	LinkTimeout      string `flag:"link-timeout" default:"5s"`
+	LinkConcurrency  string `flag:"link-concurrency" default:"4"`
+	CheckServers     bool   `flag:"check-servers" config:"lint.checkServers"`
+	ServerCheck      string `flag:"server-check" default:"http"`
+	ServerTimeout    string `flag:"server-timeout" default:"5s"`
+	ChangedOnly      bool   `flag:"changed-only"`
+	SkipUnchanged    bool   `flag:"skip-unchanged" env:"SWAGGERGO_SKIP_UNCHANGED"`
+	IfMatch          string `flag:"if-match" env:"SWAGGERGO_IF_MATCH"`
+	Output           string `flag:"output" env:"SWAGGERGO_OUTPUT" default:"text"`
+	GitRef           string `flag:"git-ref" default:"main"`
+	Sign             bool   `flag:"sign"`
+	SigningKey       string `flag:"signing-key" env:"SWAGGERGO_SIGNING_KEY"`
+	GitHubRelease    string `flag:"github-release"`
+	GitHubRepository string `flag:"github-repository"`
+	GitHubToken      string `flag:"github-token" env:"GITHUB_TOKEN"`
+	ArtifactStore    string `flag:"artifact-store" env:"SWAGGERGO_ARTIFACT_STORE"`
+	EventsUrl        string `flag:"events-url" env:"SWAGGERGO_EVENTS_URL"`
+	EventsHeader     string `flag:"events-header" env:"SWAGGERGO_EVENTS_HEADER"`
+	Statsd           string `flag:"statsd" env:"SWAGGERGO_STATSD"`
+	StatsdPrefix     string `flag:"statsd-prefix" default:"swaggergo"`
+	StatsdTags       string `flag:"statsd-tags" env:"SWAGGERGO_STATSD_TAGS"`
+	MaxUploadRate    string `flag:"max-upload-rate" env:"SWAGGERGO_MAX_UPLOAD_RATE"`
+	Retries          string `flag:"retries" env:"SWAGGERGO_RETRIES" default:"3"`
+	NoCompress       bool   `flag:"no-compress" env:"SWAGGERGO_NO_COMPRESS"`
+	Visibility       string `flag:"visibility" env:"SWAGGERGO_VISIBILITY" config:"visibility"`
+	Force            bool   `flag:"force"`
+	DryRun           bool   `flag:"dry-run"`
+	SetDefault       bool   `flag:"set-default"`
+	PublishLifecycle bool   `flag:"publish-lifecycle"`
+	connectionOptions
+
+	// ruleOverrides are the severities the ruleset gives to swaggergo's own
+	// checks, set by publishRules. "off" disables the rule.
+	ruleOverrides map[string]string
+	// inBatch is set by publishInBatch, the live view of the batch is then
+	// the only one on the terminal.
+	inBatch bool
 }
 
 func main() {
 	if len(os.Args) == 1 {
 		exitAndError("invalid usage")
 	}
+	handleSignals()
+	os.Args = useEnvFile(os.Args)
+	os.Args = useLogFormat(os.Args)
+	if len(os.Args) == 1 {
+		exitAndError("invalid usage")
+	}
 
-	if os.Args[1] == "--version" {
+	switch os.Args[1] {
+	case "--version":
 		fmt.Printf("%s version %s\n", commandLineName, commandLineVersion)
 		os.Exit(0)
+	case "--help", "-h":
+		helpCommand(os.Args[1:2])
+		return
 	}
 
-	openApiFile := os.Args[1]
-	if strings.HasPrefix(openApiFile, "--") {
-		exitAndError("invalid usage")
+	if command, ok := commands[os.Args[1]]; ok {
+		command.Run(os.Args[1:])
+		return
 	}
+	publishCommand(os.Args)
+}
 
-	options := commandLineOptions{}
-	parseArgs(&options, os.Args)
+// parseArgs fills opts from the flags in args (falling back to environment
+// variables, the project config and defaults) and returns the positional
+// arguments, the ones placed before the flags and the ones left after them.
+func parseArgs(opts interface{}, args []string) []string {
+	positional, values := resolveArgs(opts, args)
+	for _, value := range values {
+		if value.Required && value.Value == "" {
+			exitAndError(fmt.Sprintf("missing %s", value.Flag))
+		}
+	}
+	return positional
+}
 
-	publish(openApiFile, &options)
+// optionFields are the names of the fields of opts, with the ones of the
+// option structs it embeds, as connectionOptions, in their place.
+func optionFields(opts interface{}) []string {
+	var fields []string
+	var walk func(structType reflect.Type)
+	walk = func(structType reflect.Type) {
+		for i := 0; i < structType.NumField(); i++ {
+			field := structType.Field(i)
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				walk(field.Type)
+			} else if field.PkgPath == "" {
+				fields = append(fields, field.Name)
+			}
+		}
+	}
+	walk(reflect.Indirect(reflect.ValueOf(opts)).Type())
+	return fields
 }
 
-func parseArgs(opts *commandLineOptions, args []string) {
-	flags := flag.NewFlagSet(commandLineName, flag.ExitOnError)
-	fields, _ := reflections.Fields(opts)
+// optionValue is the value an option got and where it came from: flag,
+// env NAME, config key or default, or nothing when it has no value.
+type optionValue struct {
+	Flag     string
+	Value    string
+	Source   string
+	Required bool
+}
+
+// resolveArgs is parseArgs without stopping on missing options, returning
+// the value of every option with its source.
+func resolveArgs(opts interface{}, args []string) ([]string, []optionValue) {
+	flags := flag.NewFlagSet(commandLineName, flag.ContinueOnError)
+	fields := optionFields(opts)
 
 	for i := 0; i < len(fields); i++ {
 		fieldName := fields[i]
@@ -84,10 +414,15 @@ func parseArgs(opts *commandLineOptions, args []string) {
 	}
 
 	flags.Usage = func() {
-		fmt.Printf("%s\n", commandLineUsage)
+		if _, ok := commands[args[0]]; ok {
+			printCommandUsage(args[0])
+		} else {
+			fmt.Printf("%s\n", commandLineUsage)
+		}
 	}
 
 	var argumentFlags []string
+	var positional []string
 	started := false
 	for i := 0; i < len(args); i++ {
 		if strings.HasPrefix(args[i], "--") {
@@ -96,10 +431,21 @@ func parseArgs(opts *commandLineOptions, args []string) {
 
 		if started {
 			argumentFlags = append(argumentFlags, args[i])
+		} else if i > 0 {
+			positional = append(positional, args[i])
 		}
 	}
 
-	flags.Parse(argumentFlags)
+	if err := flags.Parse(argumentFlags); err == flag.ErrHelp {
+		os.Exit(exitOk)
+	} else if err != nil {
+		os.Exit(exitFailure)
+	}
+
+	var values []optionValue
+	given := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	config := optionsConfigValues(opts, flags)
 
 	for i := 0; i < len(fields); i++ {
 		fieldName := fields[i]
@@ -107,22 +453,33 @@ func parseArgs(opts *commandLineOptions, args []string) {
 
 		flagName, _ := reflections.GetFieldTag(opts, fieldName, "flag")
 		envName, _ := reflections.GetFieldTag(opts, fieldName, "env")
+		configName, _ := reflections.GetFieldTag(opts, fieldName, "config")
 		required, _ := reflections.GetFieldTag(opts, fieldName, "required")
 		defaultValue, _ := reflections.GetFieldTag(opts, fieldName, "default")
 
-		value := flags.Lookup(flagName).Value.String()
+		value, source := "", ""
+		if given[flagName] {
+			value, source = flags.Lookup(flagName).Value.String(), "flag"
+		}
 
-		if value == "" {
-			value = os.Getenv(envName)
+		if value == "" && envName != "" {
+			value, source = os.Getenv(envName), "env "+envName
+			if path, ok := envFileVariables[envName]; ok && value != "" {
+				source += " (" + path + ")"
+			}
 		}
 
-		if required == "true" && value == "" {
-			exitAndError(fmt.Sprintf("missing %s", flagName))
+		if value == "" && configName != "" {
+			value, source = config[configName], "config "+configName
 		}
 
-		if value == "" && defaultValue != "" {
-			value = defaultValue
+		if value == "" && defaultValue != "" && required != "true" {
+			value, source = defaultValue, "default"
+		}
+		if value == "" {
+			source = ""
 		}
+		values = append(values, optionValue{Flag: flagName, Value: value, Source: source, Required: required == "true"})
 
 		if fieldKind == reflect.String {
 			reflections.SetField(opts, fieldName, value)
@@ -137,66 +494,546 @@ func parseArgs(opts *commandLineOptions, args []string) {
 			exitAndError(fmt.Sprintf("Could not set value of %s", fieldName))
 		}
 	}
+
+	return append(positional, flags.Args()...), values
 }
 
+// exitHooks run before exiting on an error, to report failed publications.
+var exitHooks []func(message interface{})
+
+// exitAndError reports the failure and exits with the code of the errors
+// message wraps, or exitFailure.
 func exitAndError(message interface{}) {
-	fmt.Printf("%s: %s\nSee '%s --help'\n", commandLineName, message, commandLineName)
-	os.Exit(1)
+	exitWithCode(exitCode(message), message)
+}
+
+func exitWithCode(code int, message interface{}) {
+	reportFailure(message)
+	if interrupted() {
+		reason := "interrupted"
+		if maxTimeExceeded {
+			reason = timeLimit + " exceeded"
+		}
+		if outputJson {
+			printJsonError(reason, message)
+		} else {
+			fmt.Printf("%s: %s: %s\n", commandLineName, reason, message)
+		}
+		os.Exit(interruptedExitCode())
+	}
+	if outputJson {
+		printJsonError("failed", message)
+	} else if code == exitFailure {
+		fmt.Printf("%s: %s\nSee '%s --help'\n", commandLineName, message, commandLineName)
+	} else {
+		fmt.Printf("%s: %s\n", commandLineName, message)
+	}
+	os.Exit(code)
+}
+
+// reportFailure runs the exit hooks and writes the error to the JSON logs,
+// before exiting on a failure.
+func reportFailure(message interface{}) {
+	hooks := exitHooks
+	exitHooks = nil
+	for _, hook := range hooks {
+		hook(message)
+	}
+	if logJson {
+		writeLogEntry(os.Stderr, "error", fmt.Sprint(message), nil)
+	}
 }
 
 func publish(openApiPath string, options *commandLineOptions) {
+	if options.SwaggerHubApi == "" && !options.ApiFromSpec {
+		exitAndError("missing api")
+	}
+	if openApiPath == stdinPath && options.AccessTokenStdin {
+		exitAndError("the definition and the access token can't both come from the standard input")
+	}
+	useProjectConnections(options.Config, httpConfig{Resolve: splitList(options.Resolve), DnsServer: options.DnsServer, CaCert: options.CaCert, ClientCert: options.ClientCert, ClientKey: options.ClientKey, Proxy: options.Proxy, Timeout: options.Timeout, Verbose: options.Verbose})
+	useAccessTokenSource(options.AccessTokenFile, options.AccessTokenStdin, &options.SwaggerHubAccessToken)
+	if (openApiPath == stdinPath || isDefinitionUrl(openApiPath)) && options.Sign {
+		exitAndError("--sign writes next to the definition, it needs a file")
+	}
+	openApi, err := readPublication(openApiPath, options)
+	if err != nil {
+		exitAndError(err)
+	}
+	if options.ApiFromSpec {
+		if options.SwaggerHubApi, err = specApi(openApiPath, definitionTitle(openApi), options.SwaggerHubApi); err != nil {
+			exitAndError(err)
+		}
+	}
+	environment := useEnvironment(options.Config, options.Environment, options.Profile, options.RegistryUrl, &options.SwaggerHubApi, &options.SwaggerHubAccessToken)
+	if options.ApiFromSpec && !strings.Contains(options.SwaggerHubApi, "/") {
+		exitAndError("--api-from-spec needs an owner, give it with --api or the environment or profile")
+	}
+	current := &publication{registry: publishRegistry(options), logFields: logFields, errorFields: jsonErrorFields, hooks: &exitHooks}
+	result, err := publishDefinition(openApiPath, openApi, environment, options, current)
+	if err != nil {
+		exitAndError(err)
+	}
+	printPublishResult(result)
+}
+
+// readPublication reads the definition to publish, with the version it's
+// published as.
+func readPublication(openApiPath string, options *commandLineOptions) ([]byte, error) {
+	openApi, err := readDefinition(openApiPath, options.FileHeader)
+	if err != nil {
+		return nil, err
+	}
+	if openApi, err = useVersionFrom(options.VersionFrom, openApiPath, openApi, options); err != nil {
+		return nil, err
+	}
+	return useVersionSuffix(options.VersionSuffix, openApiPath, openApi, options)
+}
+
+// publishDefinition checks and uploads a definition read by readPublication
+// and returns how it went. It doesn't exit on failures but returns them,
+// with their exit code, and current tells where they're reported.
+func publishDefinition(openApiPath string, openApi []byte, environment *environmentConfig, options *commandLineOptions, current *publication) (publishResult, error) {
+	var err error
+	// after the environment, which tells the references SwaggerHub resolves
+	if options.Bundle {
+		if openApi, err = bundleDefinition(openApiPath, openApi, options.RefHeader, !options.NoRemoteRefs); err != nil {
+			return publishResult{}, err
+		}
+	}
+	if options.SwaggerHubAccessToken == "" && !options.DryRun {
+		return publishResult{}, errors.New("missing access-token")
+	}
+	if options.Oas == "" {
+		options.Oas = environment.Oas
+	}
+
+	started := time.Now()
+	current.logFields["api"] = options.SwaggerHubApi
 	log.Printf("Creating release %s for repository: %s", openApiPath, options.SwaggerHubApi)
 
+	var metrics *publishMetrics
+	if options.Statsd != "" && !options.DryRun {
+		if metrics, err = newPublishMetrics(options); err != nil {
+			return publishResult{}, err
+		}
+		current.onFailure(func(interface{}) { metrics.finish("failure") })
+	}
+	if config, err := loadProjectConfig(options.Config); err == nil && config.Notifications.Email.Smtp != "" && !options.DryRun {
+		current.onFailure(func(reason interface{}) {
+			notifyFailure(&config.Notifications.Email, openApiPath, options.SwaggerHubApi, reason)
+		})
+	}
+
 	repositoryParts := strings.Split(options.SwaggerHubApi, "/")
 	if len(repositoryParts) != 2 {
-		exitAndError("api is in the wrong format")
+		return publishResult{}, errors.New("api is in the wrong format")
+	}
+	if options.Visibility != "" && options.Visibility != "private" && options.Visibility != "public" {
+		return publishResult{}, fmt.Errorf("invalid visibility %s, use private or public", options.Visibility)
+	}
+	if options.Type != "" && options.Type != "yml" && options.Type != "json" {
+		return publishResult{}, fmt.Errorf("unknown type %s, use yml or json", options.Type)
+	}
+	if _, ok := specTypeNames[options.SpecType]; options.SpecType != "" && !ok {
+		return publishResult{}, fmt.Errorf("unknown spec type %s, use openapi or asyncapi", options.SpecType)
+	}
+
+	if options.Type == "" {
+		options.Type = definitionType(openApiPath, openApi)
+	}
+	publishAs := options.Type
+	if options.PublishAs != "" {
+		if publishAs, err = definitionFormat(options.PublishAs); err != nil {
+			return publishResult{}, err
+		}
 	}
 
-	openApi, err := ioutil.ReadFile(openApiPath)
+	// the rules can't fail a publication, their settings are checked here
+	if _, _, err := linkSettings(options); err != nil {
+		return publishResult{}, err
+	}
+	if _, _, err := serverSettings(options); err != nil {
+		return publishResult{}, err
+	}
+	rules, err := publishRules(options)
 	if err != nil {
-		exitAndError(fmt.Sprintf("can't read the file %s", openApiPath))
+		return publishResult{}, err
 	}
+	document, handler, findings, err := checkDocument(openApiPath, openApi, rules, options)
+	if err != nil {
+		return publishResult{}, err
+	}
+	// converted after the checks, whose lines are the ones of the file
+	if openApi, err = convertDefinition(openApiPath, openApi, options.Type, publishAs); err != nil {
+		return publishResult{}, withExitCode(exitInvalid, err)
+	}
+	options.Type = publishAs
+	if metrics != nil {
+		metrics.size = len(openApi)
+	}
+	result := publishResult{
+		Api:      options.SwaggerHubApi,
+		Version:  publicationVersion(openApi, options),
+		Url:      fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(swaggerHubUrls[0], "/"), options.SwaggerHubApi, publicationVersion(openApi, options)),
+		Warnings: jsonFindings(findings),
+	}
+	current.errorFields["api"], current.errorFields["version"] = result.Api, result.Version
+	current.logFields["version"] = result.Version
 
 	mediaType := "application/yaml"
 	if options.Type == "json" {
 		mediaType = "application/json"
 	}
 
-	response, err := postToSwaggerHub(openApi, mediaType, options)
+	query := handler.publishQuery(document, options)
+	// the provenance records the OAS level it was published as
+	options.Oas = query.Get("oas")
+	if options.ApiVersion != "" {
+		query.Set("version", options.ApiVersion)
+	}
+	visibility := options.Visibility
+	if visibility == "" {
+		visibility = environment.Visibility
+	}
+	if visibility != "" {
+		query.Set("isPrivate", fmt.Sprint(visibility == "private"))
+	}
+	if options.Force {
+		log.Printf("forcing the upload, %s %s is overwritten even if published", options.SwaggerHubApi, publicationVersion(openApi, options))
+		query.Set("force", "true")
+	}
+	if options.IfMatch != "" {
+		if err := checkRevision(current.registry, options.IfMatch, openApi, options); err != nil {
+			return publishResult{}, err
+		}
+	}
+	var previous *openApiDocument
+	if options.SkipUnchanged {
+		previous = publishedVersion(current.registry, openApi, options)
+		if previous != nil && unchangedDefinition(document, previous, options) {
+			logWith(logDuration(started), "no changes, %s %s is already published with this definition", options.SwaggerHubApi, publicationVersion(openApi, options))
+			if metrics != nil {
+				metrics.finish("unchanged")
+			}
+			result.Status = "unchanged"
+			return result, nil
+		}
+	}
+	if options.DryRun {
+		reportDryRun(openApi, mediaType, query, options)
+		result.Status = "dry-run"
+		return result, nil
+	}
+
+	if options.EventsUrl != "" && previous == nil {
+		previous = publishedVersion(current.registry, openApi, options)
+	}
+	// checked before the upload, a publication can't be taken back
+	var signer crypto.Signer
+	if options.Sign {
+		if signer, err = publicationSigner(options); err != nil {
+			return publishResult{}, err
+		}
+	}
+	var repository string
+	if options.GitHubRelease != "" {
+		if repository, err = gitHubReleaseRepository(options); err != nil {
+			return publishResult{}, err
+		}
+	}
+	var store *neturl.URL
+	if options.ArtifactStore != "" {
+		if store, err = artifactStore(options.ArtifactStore); err != nil {
+			return publishResult{}, err
+		}
+	}
+	response, err := postToSwaggerHub(current.registry, openApi, mediaType, query, options)
 	if err != nil {
-		exitAndError("problem connecting to swaggerhub")
+		if interrupted() {
+			return publishResult{}, fmt.Errorf("the upload of %s %s was cancelled, SwaggerHub may have received it already", result.Api, result.Version)
+		}
+		return publishResult{}, withExitCode(exitNetwork, errors.New("problem connecting to swaggerhub"))
+	}
+
+	fields := logDuration(started)
+	fields["statusCode"] = response.StatusCode
+	logWith(fields, "OpenApi sended with response: %s", response.Status)
+	current.errorFields["statusCode"] = response.StatusCode
+	result.StatusCode = response.StatusCode
+	if response.StatusCode == http.StatusTooManyRequests {
+		return publishResult{}, errors.New("swaggerhub is still limiting the requests after the retries, try again later or publish with a lower --concurrency")
+	}
+	if response.StatusCode >= 400 {
+		return publishResult{}, withExitCode(statusExitCode(response.StatusCode, response.Body), fmt.Errorf("swaggerhub rejected %s %s: %s", result.Api, result.Version, response.Status))
+	}
+
+	if options.PublishLifecycle || options.SetDefault {
+		if err := updateVersionSettings(current.registry, openApi, response, options); err != nil {
+			return publishResult{}, err
+		}
+	}
+
+	if options.Sign {
+		if err := signPublication(openApiPath, openApi, mediaType, signer, options); err != nil {
+			return publishResult{}, err
+		}
+	}
+
+	if options.GitHubRelease != "" {
+		if err := attachToGitHubRelease(repository, definitionFileName(openApiPath, options.Type), openApi, mediaType, options); err != nil {
+			return publishResult{}, err
+		}
+	}
+
+	if store != nil {
+		if err := storeArtifact(store, definitionFileName(openApiPath, options.Type), openApi, mediaType, options); err != nil {
+			return publishResult{}, err
+		}
+	}
+
+	if options.EventsUrl != "" {
+		if err := sendPublishedEvent(openApiPath, openApi, previous, options); err != nil {
+			return publishResult{}, err
+		}
+	}
+
+	if metrics != nil {
+		metrics.finish("success")
 	}
+	result.Status = "published"
+	return result, nil
+}
 
-	log.Printf("OpenApi sended with response: %s", response)
+// publishResult is the JSON output of a publication.
+type publishResult struct {
+	Status     string        `json:"status"`
+	Api        string        `json:"api"`
+	Version    string        `json:"version"`
+	Url        string        `json:"url"`
+	StatusCode int           `json:"statusCode,omitempty"`
+	Warnings   []jsonFinding `json:"warnings"`
 }
 
-func postToSwaggerHub(openApi []byte, mediaType string, options *commandLineOptions) (response string, err error) {
-	apiUrl := fmt.Sprintf("%s/%s?oas=%s", swaggerHubUrl, options.SwaggerHubApi, options.Oas)
-	request, _ := http.NewRequest("POST", apiUrl, bytes.NewBuffer(openApi))
-	request.Header.Set("Authorization", options.SwaggerHubAccessToken)
-	request.Header.Set("accept", "application/json")
-	request.Header.Set("Content-Type", mediaType)
+func printPublishResult(result publishResult) {
+	if outputJson {
+		printJson(result)
+	}
+}
 
-	log.Printf("sending requiest to: %s", apiUrl)
+// publication is where the publication of a definition reports to: the
+// client it publishes with, the fields of its log lines and JSON error and
+// the hooks run when it fails. On its own those are the ones of the run, in
+// a batch every publication has its own, see publishBatch.
+type publication struct {
+	registry    *swaggerhub.Client
+	logFields   map[string]interface{}
+	errorFields map[string]interface{}
+	hooks       *[]func(message interface{})
+}
 
-	client := client()
-	resp, err := client.Do(request)
+func (current *publication) onFailure(hook func(message interface{})) {
+	*current.hooks = append(*current.hooks, hook)
+}
+
+// publishRegistry is the client publications upload with, retrying as
+// --retries asks. Its uploads share the --max-upload-rate, however many
+// publications use it.
+func publishRegistry(options *commandLineOptions) *swaggerhub.Client {
+	registry := swaggerHub(swaggerHubUrls, options.SwaggerHubAccessToken)
+	var err error
+	if registry.Retries, err = strconv.Atoi(options.Retries); err != nil || registry.Retries < 0 {
+		exitAndError(fmt.Sprintf("invalid retries %s", options.Retries))
+	}
+	if options.MaxUploadRate != "" {
+		rate, err := parseRate(options.MaxUploadRate)
+		if err != nil {
+			exitAndError(err)
+		}
+		registry.UploadLimiter = swaggerhub.NewUploadLimiter(rate)
+	}
+	return registry
+}
+
+// publishRules are the rules of the ruleset (or the spectral:oas ones with
+// --lint) and of the config, checked after the built-in ones of the kind of
+// definition.
+func publishRules(options *commandLineOptions) ([]lintRule, error) {
+	var rules []lintRule
+	if options.Ruleset != "" {
+		rulesetRules, overrides, err := loadSpectralRuleset(options.Ruleset)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rulesetRules...)
+		options.ruleOverrides = overrides
+	} else if options.Lint {
+		rules = append(rules, spectralOasRules...)
+	}
+
+	config, err := loadProjectConfig(options.Config)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	bodyBytes, err := ioutil.ReadAll(request.Body)
+	configRules, err := config.lintRules()
+	if err != nil {
+		return nil, err
+	}
+	return append(rules, configRules...), nil
+}
+
+// checkDocument detects the kind of the definition and fails when its checks
+// find errors, returning the other findings. --changed-only leaves out the
+// findings of the lint, ruleset and config rules outside the changed
+// operations, never the ones of the validation of the structure.
+func checkDocument(openApiPath string, openApi []byte, rules []lintRule, options *commandLineOptions) (*openApiDocument, specHandler, []lintFinding, error) {
+	document, err := parseOpenApiDocument(openApiPath, openApi)
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, nil, withExitCode(exitInvalid, err)
 	}
-	bodyString := string(bodyBytes)
-	log.Print(bodyString)
+	handler, err := detectSpecHandler(document, options.SpecType)
+	if err != nil {
+		return nil, nil, nil, withExitCode(exitInvalid, err)
+	}
+
+	findings := lintDocument(document, handler.validation(), options)
+	checks := lintDocument(document, append(handler.rules(options), rules...), options)
+	if options.ChangedOnly {
+		changed, err := changedOperations(document, options.GitRef)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		checks = onlyChangedOperations(checks, changed)
+	}
+	findings = append(findings, checks...)
+
+	if errors := reportFindings(document, findings); errors > 0 {
+		return nil, nil, nil, withExitCode(exitInvalid, fmt.Errorf("found %d problems in %s", errors, openApiPath))
+	}
+	return document, handler, findings, nil
+}
+
+// reportDryRun logs what would be sent to SwaggerHub instead of sending it.
+func reportDryRun(openApi []byte, mediaType string, query neturl.Values, options *commandLineOptions) {
+	repositoryParts := strings.Split(options.SwaggerHubApi, "/")
+	log.Printf("dry run, nothing was uploaded")
+	log.Printf("  url:        %s/%s?%s", strings.TrimSuffix(swaggerHubUrls[0], "/"), options.SwaggerHubApi, query.Encode())
+	log.Printf("  owner:      %s", repositoryParts[0])
+	log.Printf("  api:        %s", repositoryParts[1])
+	log.Printf("  version:    %s", publicationVersion(openApi, options))
+	if oas := query.Get("oas"); oas != "" {
+		log.Printf("  oas:        %s", oas)
+	}
+	log.Printf("  size:       %d bytes", len(openApi))
+	log.Printf("  media type: %s", mediaType)
+	if !options.NoCompress {
+		log.Printf("  encoding:   gzip")
+	}
+}
+
+// publicationVersion is the version a definition is published as: the one of
+// --api-version (or the config) or else its info.version.
+func publicationVersion(openApi []byte, options *commandLineOptions) string {
+	if options.ApiVersion != "" {
+		return options.ApiVersion
+	}
+	return definitionVersion(openApi)
+}
+
+// specApi is the API --api-from-spec publishes to: the owner given with
+// --api, if any, and info.title in lower case with dashes for anything else
+// than letters and digits, as "Orders API" becomes orders-api. Without an
+// owner the one of the environment or profile is used.
+func specApi(definitionPath string, title string, owner string) (string, error) {
+	if strings.Contains(owner, "/") {
+		return "", fmt.Errorf("--api-from-spec names the API after info.title, --api only takes the owner, not %s", owner)
+	}
+	var name strings.Builder
+	dash := false
+	for _, char := range strings.ToLower(title) {
+		if (char >= 'a' && char <= 'z') || (char >= '0' && char <= '9') {
+			if dash && name.Len() > 0 {
+				name.WriteRune('-')
+			}
+			name.WriteRune(char)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	if name.Len() == 0 {
+		return "", fmt.Errorf("%s has no info.title to name the API after", definitionPath)
+	}
+	if owner == "" {
+		return name.String(), nil
+	}
+	return owner + "/" + name.String(), nil
+}
+
+// unchangedDefinition tells whether the published version has the content
+// of the local definition, comparing their values as verify does. The
+// version given with --api-version replaces the one of the definition, as
+// SwaggerHub does when publishing it.
+func unchangedDefinition(local *openApiDocument, published *openApiDocument, options *commandLineOptions) bool {
+	return len(semanticDifferences(publishedValue(local.Root, options.ApiVersion), withoutMockServers(nodeValue(published.Root)), "")) == 0
+}
+
+// updateVersionSettings publishes the lifecycle of the version just uploaded
+// and makes it the default one, as asked. Nothing is changed when the
+// publication was refused.
+func updateVersionSettings(registry *swaggerhub.Client, openApi []byte, response *swaggerhub.PublishResponse, options *commandLineOptions) error {
+	version := publicationVersion(openApi, options)
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("the settings of %s weren't changed, the publication failed with %s", version, response.Status)
+	}
+
+	if options.PublishLifecycle {
+		if err := registry.SetPublished(runContext, options.SwaggerHubApi, version, true); err != nil {
+			return fmt.Errorf("can't publish the lifecycle of %s: %w", version, err)
+		}
+		log.Printf("%s of %s is now published", version, options.SwaggerHubApi)
+	}
+	if options.SetDefault {
+		if err := registry.SetDefault(runContext, options.SwaggerHubApi, version); err != nil {
+			return fmt.Errorf("can't make %s the default version: %w", version, err)
+		}
+		log.Printf("%s is now the default version of %s", version, options.SwaggerHubApi)
+	}
+	return nil
+}
+
+// postToSwaggerHub uploads the definition with the query parameters of its
+// kind. Errors are only returned when SwaggerHub couldn't be reached, the
+// response tells whether the publication was accepted.
+func postToSwaggerHub(registry *swaggerhub.Client, openApi []byte, mediaType string, query neturl.Values, options *commandLineOptions) (response *swaggerhub.PublishResponse, err error) {
+	request := swaggerhub.PublishRequest{
+		Api:        options.SwaggerHubApi,
+		Definition: openApi,
+		MediaType:  mediaType,
+		Query:      query,
+		Compress:   !options.NoCompress,
+	}
+	response, err = registry.Publish(runContext, request)
+	var statusError *swaggerhub.StatusError
+	if err != nil && !errors.As(err, &statusError) {
+		return nil, err
+	}
+	log.Print(string(response.Body))
+
+	return response, nil
+}
+
+func getFromSwaggerHub(path string, accessToken string) ([]byte, error) {
+	return getFromSwaggerHubAt(swaggerHubUrls, path, accessToken)
+}
 
-	return resp.Status, nil
+func getFromSwaggerHubAt(urls []string, path string, accessToken string) ([]byte, error) {
+	return swaggerHub(urls, accessToken).Get(runContext, path)
 }
 
 func client() http.Client {
-	timeount := 10 * time.Second
 	return http.Client{
-		Timeout: timeount,
+		Timeout:   requestTimeout,
+		Transport: cancellableTransport{transport},
 	}
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type precedenceOptions struct {
+	Config string `flag:"config"`
+	Api    string `flag:"api" env:"SWAGGERGO_TEST_API" config:"swaggerhub.api" default:"owner/default-api"`
+	Dry    bool   `flag:"dry-run" env:"SWAGGERGO_TEST_DRY_RUN" config:"dryRun"`
+}
+
+func TestResolveArgsPrecedence(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "swaggergo.yml")
+	if err := ioutil.WriteFile(config, []byte("swaggerhub:\n  api: owner/config-api\ndryRun: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(t.TempDir(), "swaggergo.yml")
+	if err := ioutil.WriteFile(empty, []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		env        string
+		wantApi    string
+		wantSource string
+		wantDry    bool
+	}{
+		{"flag over env and config", []string{"publish", "--config", config, "--api", "owner/flag-api"}, "owner/env-api", "owner/flag-api", "flag", true},
+		{"env over config", []string{"publish", "--config", config}, "owner/env-api", "owner/env-api", "env SWAGGERGO_TEST_API", true},
+		{"config over default", []string{"publish", "--config", config}, "", "owner/config-api", "config swaggerhub.api", true},
+		{"default", []string{"publish", "--config", empty}, "", "owner/default-api", "default", false},
+		{"flag over default", []string{"publish", "--config", empty, "--api", "owner/flag-api", "--dry-run"}, "", "owner/flag-api", "flag", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("SWAGGERGO_TEST_API", test.env)
+			options := precedenceOptions{}
+			positional, values := resolveArgs(&options, test.args)
+			if len(positional) != 0 {
+				t.Errorf("got the positional arguments %q", positional)
+			}
+			if options.Api != test.wantApi || options.Dry != test.wantDry {
+				t.Errorf("got api %s and dry-run %v, want %s and %v", options.Api, options.Dry, test.wantApi, test.wantDry)
+			}
+			for _, value := range values {
+				if value.Flag == "api" && value.Source != test.wantSource {
+					t.Errorf("got the source %q, want %q", value.Source, test.wantSource)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveArgsPositional(t *testing.T) {
+	options := precedenceOptions{}
+	positional, _ := resolveArgs(&options, []string{"publish", "a.yml", "b.yml", "--api", "owner/api", "--", "c.yml"})
+	if want := []string{"a.yml", "b.yml", "c.yml"}; !reflect.DeepEqual(positional, want) {
+		t.Errorf("got %q, want %q", positional, want)
+	}
+}
+
+type embeddingOptions struct {
+	precedenceOptions
+	Out string `flag:"out"`
+}
+
+func TestOptionFieldsEmbedded(t *testing.T) {
+	if got, want := optionFields(&embeddingOptions{}), []string{"Config", "Api", "Dry", "Out"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// publishMetrics sends the metrics of one publication to a StatsD agent,
+// with tags in the DogStatsD format (also understood by Telegraf and
+// statsd_exporter). Metrics are sent over UDP and losing them never fails a
+// publication.
+type publishMetrics struct {
+	conn    net.Conn
+	prefix  string
+	tags    []string
+	started time.Time
+	size    int
+}
+
+func newPublishMetrics(options *commandLineOptions) (*publishMetrics, error) {
+	conn, err := net.Dial("udp", options.Statsd)
+	if err != nil {
+		return nil, fmt.Errorf("invalid statsd address %s", options.Statsd)
+	}
+	tags := []string{"api:" + options.SwaggerHubApi}
+	tags = append(tags, splitList(options.StatsdTags)...)
+	return &publishMetrics{conn: conn, prefix: options.StatsdPrefix, tags: tags, started: time.Now()}, nil
+}
+
+// finish sends the count, duration and payload size of the publication
+// tagged with its result.
+func (metrics *publishMetrics) finish(result string) {
+	tags := "|#" + strings.Join(append(metrics.tags, "result:"+result), ",")
+	lines := []string{
+		fmt.Sprintf("%s.publish.count:1|c%s", metrics.prefix, tags),
+		fmt.Sprintf("%s.publish.duration:%d|ms%s", metrics.prefix, time.Since(metrics.started).Milliseconds(), tags),
+	}
+	if metrics.size > 0 {
+		lines = append(lines, fmt.Sprintf("%s.publish.payload_size:%d|g%s", metrics.prefix, metrics.size, tags))
+	}
+	metrics.conn.Write([]byte(strings.Join(lines, "\n")))
+	metrics.conn.Close()
+}
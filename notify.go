@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// emailConfig is the notifications.email section of the config. Every entry
+// of Recipients whose Apis pattern matches the API gets the notification.
+type emailConfig struct {
+	Smtp       string `yaml:"smtp"`
+	From       string `yaml:"from"`
+	Username   string `yaml:"username"`
+	Recipients []struct {
+		Apis string   `yaml:"apis"`
+		To   []string `yaml:"to"`
+	} `yaml:"recipients"`
+}
+
+func (config *emailConfig) recipients(api string) []string {
+	seen := map[string]bool{}
+	var to []string
+	for _, recipient := range config.Recipients {
+		if matched, _ := path.Match(recipient.Apis, api); !matched {
+			continue
+		}
+		for _, address := range recipient.To {
+			if !seen[address] {
+				seen[address] = true
+				to = append(to, address)
+			}
+		}
+	}
+	return to
+}
+
+// notifyFailure emails the recipients of the API about a failed publication.
+func notifyFailure(config *emailConfig, openApiPath string, api string, reason interface{}) {
+	to := config.recipients(api)
+	if config.Smtp == "" || len(to) == 0 {
+		return
+	}
+
+	body := strings.Join([]string{
+		fmt.Sprintf("Publishing %s to %s failed:", openApiPath, api),
+		"",
+		fmt.Sprint(reason),
+		"",
+		"Actor: " + publishActor(),
+		"",
+	}, "\r\n")
+
+	if err := sendEmail(config, to, fmt.Sprintf("[swaggergo] publishing %s failed", api), body); err != nil {
+		log.Printf("can't send the failure notification: %v", err)
+		return
+	}
+	log.Printf("failure notified to %s", strings.Join(to, ", "))
+}
+
+// sendEmail sends a plain text message through the SMTP server of the config.
+// The password of the SMTP account is read from SWAGGERGO_SMTP_PASSWORD.
+func sendEmail(config *emailConfig, to []string, subject string, body string) error {
+	var auth smtp.Auth
+	if config.Username != "" {
+		host, _, _ := net.SplitHostPort(config.Smtp)
+		auth = smtp.PlainAuth("", config.Username, os.Getenv("SWAGGERGO_SMTP_PASSWORD"), host)
+	}
+
+	message := strings.Join([]string{
+		"From: " + config.From,
+		"To: " + strings.Join(to, ", "),
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		body,
+	}, "\r\n")
+	return smtp.SendMail(config.Smtp, auth, config.From, to, []byte(message))
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ociEmptyMediaType    = "application/vnd.oci.empty.v1+json"
+	openApiArtifactType  = "application/vnd.oai.openapi"
+)
+
+type pushOptions struct {
+	Username  string `flag:"username" env:"SWAGGERGO_OCI_USERNAME"`
+	Password  string `flag:"password" env:"SWAGGERGO_OCI_PASSWORD"`
+	PlainHttp bool   `flag:"plain-http"`
+	Output    string `flag:"output" default:"text"`
+	transportOptions
+}
+
+// pushResult is what push prints with --output json.
+type pushResult struct {
+	Status    string `json:"status"`
+	Path      string `json:"path"`
+	Reference string `json:"reference"`
+	Digest    string `json:"digest"`
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int               `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType"`
+	Config        ociDescriptor     `json:"config"`
+	Layers        []ociDescriptor   `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// ociRegistry talks the OCI distribution API for one repository, keeping the
+// bearer token once the registry asked for one.
+type ociRegistry struct {
+	BaseUrl    string
+	Repository string
+	Username   string
+	Password   string
+	token      string
+}
+
+var ociReferencePattern = regexp.MustCompile(`^oci://([^/]+)/([a-z0-9._/-]+?)(?::([A-Za-z0-9_][A-Za-z0-9._-]*))?$`)
+
+// pushCommand packages the definition as an OCI artifact, the way
+// `oras push` does: an empty config and the definition as the only layer.
+func pushCommand(args []string) {
+	options := pushOptions{}
+	positional := parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+	options.useTransport()
+	if len(positional) != 2 {
+		exitAndError("usage: swaggergo push oci://registry.example.com/apis/orders:1.4.0 path/to/openapi.yml")
+	}
+
+	match := ociReferencePattern.FindStringSubmatch(positional[0])
+	if match == nil {
+		exitAndError(fmt.Sprintf("invalid reference %s, use oci://registry/repository:tag", positional[0]))
+	}
+	openApiPath := positional[1]
+	openApi, err := ioutil.ReadFile(openApiPath)
+	if err != nil {
+		exitAndError(fmt.Sprintf("can't read the file %s", openApiPath))
+	}
+	tag := match[3]
+	if tag == "" {
+		tag = definitionVersion(openApi)
+	}
+	if tag == "" {
+		exitAndError("the reference has no tag and the definition has no info.version")
+	}
+
+	scheme := "https"
+	if options.PlainHttp {
+		scheme = "http"
+	}
+	registry := &ociRegistry{
+		BaseUrl:    fmt.Sprintf("%s://%s", scheme, match[1]),
+		Repository: match[2],
+		Username:   options.Username,
+		Password:   options.Password,
+	}
+
+	mediaType := openApiArtifactType
+	if strings.EqualFold(filepath.Ext(openApiPath), ".json") {
+		mediaType += "+json"
+	}
+	config := []byte("{}")
+	manifest := ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestMediaType,
+		ArtifactType:  openApiArtifactType,
+		Config:        ociDescriptor{MediaType: ociEmptyMediaType, Digest: ociDigest(config), Size: len(config)},
+		Layers: []ociDescriptor{{
+			MediaType:   mediaType,
+			Digest:      ociDigest(openApi),
+			Size:        len(openApi),
+			Annotations: map[string]string{"org.opencontainers.image.title": filepath.Base(openApiPath)},
+		}},
+		Annotations: map[string]string{"org.opencontainers.image.created": time.Now().UTC().Format(time.RFC3339)},
+	}
+
+	for _, blob := range [][]byte{config, openApi} {
+		if err := registry.pushBlob(blob); err != nil {
+			exitAndError(fmt.Errorf("can't push to %s: %w", positional[0], err))
+		}
+	}
+	manifestJson, _ := json.Marshal(manifest)
+	if _, err := registry.do("PUT", "/manifests/"+tag, manifestJson, ociManifestMediaType); err != nil {
+		exitAndError(fmt.Errorf("can't push the manifest to %s: %w", positional[0], err))
+	}
+
+	reference := fmt.Sprintf("%s/%s:%s", match[1], registry.Repository, tag)
+	log.Printf("pushed %s to %s@%s", openApiPath, reference, ociDigest(manifestJson))
+	if outputJson {
+		printJson(pushResult{Status: "pushed", Path: openApiPath, Reference: reference, Digest: ociDigest(manifestJson)})
+	}
+}
+
+func (registry *ociRegistry) pushBlob(blob []byte) error {
+	digest := ociDigest(blob)
+	if response, err := registry.do("HEAD", "/blobs/"+digest, nil, ""); err == nil && response.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	response, err := registry.do("POST", "/blobs/uploads/", nil, "")
+	if err != nil {
+		return err
+	}
+	location, err := neturl.Parse(response.Header.Get("Location"))
+	if err != nil || response.Header.Get("Location") == "" {
+		return fmt.Errorf("the registry didn't return an upload location")
+	}
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+
+	base, _ := neturl.Parse(registry.BaseUrl)
+	_, err = registry.doUrl("PUT", base.ResolveReference(location).String(), blob, "application/octet-stream")
+	return err
+}
+
+func (registry *ociRegistry) do(method string, path string, body []byte, contentType string) (*http.Response, error) {
+	return registry.doUrl(method, fmt.Sprintf("%s/v2/%s%s", registry.BaseUrl, registry.Repository, path), body, contentType)
+}
+
+// doUrl sends a request, getting a token and retrying once when the registry
+// answers with a bearer challenge.
+func (registry *ociRegistry) doUrl(method string, url string, body []byte, contentType string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		request, err := http.NewRequest(method, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if contentType != "" {
+			request.Header.Set("Content-Type", contentType)
+		}
+		if registry.token != "" {
+			request.Header.Set("Authorization", "Bearer "+registry.token)
+		} else if registry.Username != "" {
+			request.SetBasicAuth(registry.Username, registry.Password)
+		}
+
+		client := client()
+		response, err := client.Do(request)
+		if err != nil {
+			return nil, err
+		}
+		response.Body.Close()
+
+		challenge := response.Header.Get("Www-Authenticate")
+		if response.StatusCode == http.StatusUnauthorized && attempt == 0 && strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+			if err := registry.authenticate(challenge); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if response.StatusCode >= 300 && method != "HEAD" {
+			return response, fmt.Errorf("%s %s answered %s", method, url, response.Status)
+		}
+		return response, nil
+	}
+}
+
+var challengeParameterPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+func (registry *ociRegistry) authenticate(challenge string) error {
+	parameters := map[string]string{}
+	for _, match := range challengeParameterPattern.FindAllStringSubmatch(challenge, -1) {
+		parameters[match[1]] = match[2]
+	}
+	if parameters["realm"] == "" {
+		return fmt.Errorf("the registry asked for a token without a realm")
+	}
+
+	query := neturl.Values{}
+	for _, name := range []string{"service", "scope"} {
+		if parameters[name] != "" {
+			query.Set(name, parameters[name])
+		}
+	}
+	request, err := http.NewRequest("GET", parameters["realm"]+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if registry.Username != "" {
+		request.SetBasicAuth(registry.Username, registry.Password)
+	}
+
+	client := client()
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("can't get a registry token: %s", response.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+		return fmt.Errorf("can't read the registry token: %v", err)
+	}
+	registry.token = token.Token
+	if registry.token == "" {
+		registry.token = token.AccessToken
+	}
+	return nil
+}
+
+func ociDigest(content []byte) string {
+	digest := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(digest[:])
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// outputJson is set by --output json: the result of the command is printed
+// as a JSON document on the standard output, errors included, while the
+// logs stay on the standard error.
+var outputJson bool
+
+// jsonErrorFields are added to the document exitAndError prints with
+// --output json, as the API and version of a failed publication.
+var jsonErrorFields = map[string]interface{}{}
+
+// useOutput checks --output against the formats of the command.
+func useOutput(output string, formats ...string) {
+	if !containsString(formats, output) {
+		exitAndError(fmt.Sprintf("unknown output %s, use %s", output, joinOr(formats)))
+	}
+	outputJson = output == "json"
+}
+
+// useFormat applies --format, the flag list, versions, whoami and domain
+// list printed JSON with before --output, as --output. It's deprecated.
+func useFormat(format string, output *string) {
+	if format == "" {
+		return
+	}
+	log.Printf("--format is deprecated, use --output %s", strings.Replace(format, "table", "text", 1))
+	if format == "table" {
+		format = "text"
+	}
+	*output = format
+}
+
+// useDocumentOutput stops a command that writes a document to the standard
+// output without --out when its result is printed there with --output json.
+func useDocumentOutput(out string) {
+	if outputJson && out == "" {
+		exitAndError("--output json prints the result on the standard output, write the document with --out")
+	}
+}
+
+// writtenResult is what the commands writing a document or a directory, as
+// bundle or docs, print with --output json.
+type writtenResult struct {
+	Status string `json:"status"`
+	Path   string `json:"path,omitempty"`
+	Out    string `json:"out"`
+}
+
+// printWrittenResult prints where the document of path was written with
+// --output json.
+func printWrittenResult(path string, out string) {
+	if outputJson {
+		printJson(writtenResult{Status: "written", Path: path, Out: out})
+	}
+}
+
+// printJsonError prints the message of exitAndError as a JSON document.
+func printJsonError(status string, message interface{}) {
+	document := map[string]interface{}{}
+	for name, value := range jsonErrorFields {
+		document[name] = value
+	}
+	document["status"] = status
+	document["error"] = fmt.Sprint(message)
+	printJson(document)
+}
+
+// printJsonResult prints the result of a command and exits with code unless
+// it's exitOk. The message is already part of the result, it only goes to
+// the exit hooks and the JSON logs.
+func printJsonResult(result interface{}, code int, message string) {
+	printJson(result)
+	if code != exitOk {
+		reportFailure(message)
+		os.Exit(code)
+	}
+}
+
+// jsonFinding is a lint finding in the JSON output.
+type jsonFinding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Pointer  string `json:"pointer"`
+	Line     int    `json:"line,omitempty"`
+}
+
+func jsonFindings(findings []lintFinding) []jsonFinding {
+	result := []jsonFinding{}
+	for _, finding := range findings {
+		result = append(result, jsonFinding{Rule: finding.Rule, Severity: finding.Severity, Message: finding.Message, Pointer: finding.Pointer, Line: finding.Line})
+	}
+	return result
+}
+
+func joinOr(values []string) string {
+	if len(values) < 2 {
+		return strings.Join(values, "")
+	}
+	return strings.Join(values[:len(values)-1], ", ") + " or " + values[len(values)-1]
+}
@@ -0,0 +1,304 @@
+// Package swaggerhub is a client of the SwaggerHub registry API, the one
+// swaggergo publishes and fetches definitions with. Programs can use it
+// instead of running the swaggergo binary:
+//
+//	client := swaggerhub.NewClient(os.Getenv("SWAGGERHUB_ACCESS_TOKEN"))
+//	response, err := client.Publish(ctx, swaggerhub.PublishRequest{
+//		Api:        "mijailr/sample-api",
+//		Definition: definition,
+//		MediaType:  "application/yaml",
+//		Oas:        "3.0.0",
+//	})
+package swaggerhub
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultUrl is the API of the SwaggerHub SaaS.
+const DefaultUrl = "https://api.swaggerhub.com/apis"
+
+// Client calls the registry API. Urls are tried in order, moving on to the
+// next one only when the connection can't be established, as with the nodes
+// of an on-premise installation. Requests that reached a server are only
+// repeated when Retries is set.
+type Client struct {
+	Urls        []string
+	AccessToken string
+	HttpClient  *http.Client
+	// Retries is how many times a request is sent again after an error, a
+	// 5xx answer or a 429 (too many requests), waiting what the Retry-After
+	// of the answer asks or an exponential backoff with jitter, from
+	// RetryWait (a second by default) up to a minute. Publishing the same
+	// definition again overwrites the version with the same content.
+	Retries   int
+	RetryWait time.Duration
+	// Breaker, when set, is asked before sending a request to a base URL and
+	// told how it went.
+	Breaker Breaker
+	// Cache, when set, makes the GET requests conditional.
+	Cache Cache
+	// Logf, when set, logs every request sent.
+	Logf func(format string, args ...interface{})
+	// UploadLimiter, when set, limits the uploads of Publish, all of them
+	// together.
+	UploadLimiter *UploadLimiter
+}
+
+// Breaker keeps requests away from a failing base URL. Allow returns an
+// error to skip the URL.
+type Breaker interface {
+	Allow(baseUrl string) error
+	Record(baseUrl string, success bool)
+}
+
+// Cache keeps GET responses. Prepare adds the validators of the cached
+// response to the request and returns its body, served when the registry
+// answers 304.
+type Cache interface {
+	Prepare(request *http.Request) (body []byte, ok bool)
+	Store(response *http.Response, body []byte)
+}
+
+// StatusError is returned when the registry answers with an error status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+func (err *StatusError) Error() string {
+	return fmt.Sprintf("swaggerhub answered %s", err.Status)
+}
+
+// NewClient returns a client of the SwaggerHub SaaS.
+func NewClient(accessToken string) *Client {
+	return &Client{
+		Urls:        []string{DefaultUrl},
+		AccessToken: accessToken,
+		HttpClient:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// DomainUrls are the domains endpoints next to the APIs ones, the registry
+// serves both from the same base path.
+func DomainUrls(apiUrls []string) []string {
+	var urls []string
+	for _, url := range apiUrls {
+		urls = append(urls, strings.TrimSuffix(strings.TrimSuffix(url, "/"), "/apis")+"/domains")
+	}
+	return urls
+}
+
+// Domains returns a copy of the client calling the domains endpoints.
+func (client *Client) Domains() *Client {
+	domains := *client
+	domains.Urls = DomainUrls(client.Urls)
+	return &domains
+}
+
+func (client *Client) httpClient() *http.Client {
+	if client.HttpClient == nil {
+		return http.DefaultClient
+	}
+	return client.HttpClient
+}
+
+func (client *Client) logf(format string, args ...interface{}) {
+	if client.Logf != nil {
+		client.Logf(format, args...)
+	}
+}
+
+// Do sends the request built by newRequest for path to the first base URL
+// that can be reached. The caller closes the body of the response.
+func (client *Client) Do(ctx context.Context, path string, newRequest func(ctx context.Context, apiUrl string) (*http.Request, error)) (*http.Response, error) {
+	return client.do(ctx, client.httpClient(), path, newRequest)
+}
+
+func (client *Client) do(ctx context.Context, httpClient *http.Client, path string, newRequest func(ctx context.Context, apiUrl string) (*http.Request, error)) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, sent, err := client.send(ctx, httpClient, path, newRequest)
+		if !sent || attempt > client.Retries || ctx.Err() != nil || (err == nil && !retryStatus(resp.StatusCode)) {
+			return resp, err
+		}
+
+		reason := fmt.Sprint(err)
+		wait := client.backoff(attempt)
+		if err == nil {
+			reason = "swaggerhub answered " + resp.Status
+			if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				wait = after
+			}
+			resp.Body.Close()
+		}
+		client.logf("%s, retrying in %s (%d of %d)", reason, wait.Round(time.Millisecond), attempt, client.Retries)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+const maxRetryWait = time.Minute
+
+func retryStatus(statusCode int) bool {
+	return statusCode >= 500 || statusCode == http.StatusTooManyRequests
+}
+
+// retryAfter reads a Retry-After header, given in seconds or as a date.
+func retryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// backoff doubles RetryWait with every attempt, waiting a random time
+// between half and all of it so parallel runs don't retry together.
+func (client *Client) backoff(attempt int) time.Duration {
+	wait := client.RetryWait
+	if wait <= 0 {
+		wait = time.Second
+	}
+	for i := 1; i < attempt && wait < maxRetryWait; i++ {
+		wait *= 2
+	}
+	if wait > maxRetryWait {
+		wait = maxRetryWait
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// send tries the base URLs in order, telling whether a request was sent.
+func (client *Client) send(ctx context.Context, httpClient *http.Client, path string, newRequest func(ctx context.Context, apiUrl string) (*http.Request, error)) (*http.Response, bool, error) {
+	if len(client.Urls) == 0 {
+		return nil, false, errors.New("no SwaggerHub URL to send the request to")
+	}
+	var err error
+	sent := false
+	for i, baseUrl := range client.Urls {
+		apiUrl := fmt.Sprintf("%s/%s", strings.TrimSuffix(baseUrl, "/"), path)
+		var request *http.Request
+		request, err = newRequest(ctx, apiUrl)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if client.Breaker != nil {
+			if err = client.Breaker.Allow(baseUrl); err != nil {
+				continue
+			}
+		}
+
+		client.logf("sending request to: %s", apiUrl)
+		var resp *http.Response
+		resp, err = httpClient.Do(request)
+		sent = true
+		if client.Breaker != nil {
+			client.Breaker.Record(baseUrl, err == nil && resp.StatusCode < 500)
+		}
+		if err == nil || !isConnectionError(err) {
+			return resp, true, err
+		}
+		if i+1 < len(client.Urls) {
+			client.logf("can't connect to %s, failing over to %s: %v", baseUrl, client.Urls[i+1], err)
+		}
+	}
+	return nil, sent, err
+}
+
+func isConnectionError(err error) bool {
+	var opError *net.OpError
+	return errors.As(err, &opError) && opError.Op == "dial"
+}
+
+// Get returns the body of path, going through the cache when there's one.
+func (client *Client) Get(ctx context.Context, path string) ([]byte, error) {
+	var cached []byte
+	var isCached bool
+	resp, err := client.Do(ctx, path, func(ctx context.Context, apiUrl string) (*http.Request, error) {
+		request, err := http.NewRequestWithContext(ctx, "GET", apiUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+		if client.AccessToken != "" {
+			request.Header.Set("Authorization", client.AccessToken)
+		}
+		if client.Cache != nil {
+			cached, isCached = client.Cache.Prepare(request)
+		}
+		return request, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && isCached {
+		return cached, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: body}
+	}
+	if client.Cache != nil {
+		client.Cache.Store(resp, body)
+	}
+	return body, nil
+}
+
+// Call sends a request to path and returns the body of the answer. Bodies
+// are YAML definitions for POST and JSON settings otherwise.
+func (client *Client) Call(ctx context.Context, method string, path string, body []byte) ([]byte, error) {
+	resp, err := client.Do(ctx, path, func(ctx context.Context, apiUrl string) (*http.Request, error) {
+		request, err := http.NewRequestWithContext(ctx, method, apiUrl, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Authorization", client.AccessToken)
+		request.Header.Set("accept", "application/json")
+		if method == "POST" {
+			request.Header.Set("Content-Type", "application/yaml")
+		} else if body != nil {
+			request.Header.Set("Content-Type", "application/json")
+		}
+		return request, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: responseBody}
+	}
+	return responseBody, nil
+}
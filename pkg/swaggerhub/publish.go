@@ -0,0 +1,161 @@
+package swaggerhub
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"time"
+)
+
+// PublishRequest creates or updates a version of an API with a definition.
+type PublishRequest struct {
+	// Api is owner/name.
+	Api        string
+	Definition []byte
+	// MediaType is application/yaml or application/json.
+	MediaType string
+	// Oas is the version of the definition, as 3.0.0.
+	Oas string
+	// Version overrides the version of the definition.
+	Version string
+	// Private, when set, makes the version private or public.
+	Private *bool
+	Force   bool
+	// Query holds any other parameter of the registry.
+	Query neturl.Values
+	// UploadRate limits this upload alone to that many bytes per second, when
+	// the client has no UploadLimiter.
+	UploadRate int
+	// Compress sends the definition gzipped, with Content-Encoding: gzip.
+	Compress bool
+}
+
+// PublishResponse is the answer of the registry to a publication.
+type PublishResponse struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+func (request *PublishRequest) query() neturl.Values {
+	query := neturl.Values{}
+	for name, values := range request.Query {
+		query[name] = values
+	}
+	if request.Oas != "" {
+		query.Set("oas", request.Oas)
+	}
+	if request.Version != "" {
+		query.Set("version", request.Version)
+	}
+	if request.Private != nil {
+		query.Set("isPrivate", strconv.FormatBool(*request.Private))
+	}
+	if request.Force {
+		query.Set("force", "true")
+	}
+	return query
+}
+
+// Publish uploads the definition of the request. When the registry answers
+// with an error status the response is returned along with a *StatusError.
+func (client *Client) Publish(ctx context.Context, request PublishRequest) (*PublishResponse, error) {
+	definition := request.Definition
+	if request.Compress {
+		var err error
+		if definition, err = gzipBody(definition); err != nil {
+			return nil, err
+		}
+	}
+
+	limiter := client.UploadLimiter
+	if limiter == nil && request.UploadRate > 0 {
+		limiter = NewUploadLimiter(request.UploadRate)
+	}
+	httpClient := *client.httpClient()
+	timeout := httpClient.Timeout
+	if limiter != nil {
+		// the timeout is pushed back by the waits of the limiter instead,
+		// they're long when other uploads share it
+		httpClient.Timeout = 0
+	}
+	var stops []func()
+	defer func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}()
+
+	path := fmt.Sprintf("%s?%s", request.Api, request.query().Encode())
+	resp, err := client.do(ctx, &httpClient, path, func(ctx context.Context, apiUrl string) (*http.Request, error) {
+		var body io.Reader = bytes.NewBuffer(definition)
+		if limiter != nil {
+			throttled := &throttledReader{ctx: ctx, reader: body, limiter: limiter}
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithCancel(ctx)
+				deadline := time.Now().Add(timeout)
+				timer := time.AfterFunc(timeout, cancel)
+				throttled.ctx = ctx
+				throttled.waiting = func(wait time.Duration) {
+					deadline = deadline.Add(wait)
+					timer.Reset(time.Until(deadline))
+				}
+				stops = append(stops, func() {
+					timer.Stop()
+					cancel()
+				})
+			}
+			body = throttled
+		}
+		httpRequest, err := http.NewRequestWithContext(ctx, "POST", apiUrl, body)
+		if err != nil {
+			return nil, err
+		}
+		httpRequest.ContentLength = int64(len(definition))
+		httpRequest.Header.Set("Authorization", client.AccessToken)
+		httpRequest.Header.Set("accept", "application/json")
+		httpRequest.Header.Set("Content-Type", request.MediaType)
+		if request.Compress {
+			httpRequest.Header.Set("Content-Encoding", "gzip")
+		}
+		return httpRequest, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	response := &PublishResponse{StatusCode: resp.StatusCode, Status: resp.Status, Body: body}
+	if resp.StatusCode >= 300 {
+		return response, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: body}
+	}
+	return response, nil
+}
+
+func gzipBody(body []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(body); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+// Fetch returns the YAML definition of a version of owner/name.
+func (client *Client) Fetch(ctx context.Context, api string, version string) ([]byte, error) {
+	return client.Get(ctx, fmt.Sprintf("%s/%s/swagger.yaml", api, version))
+}
@@ -0,0 +1,161 @@
+package swaggerhub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const pageSize = 100
+
+// Specs is the APISpecsJson returned when listing APIs, domains or their
+// versions.
+type Specs struct {
+	TotalCount int `json:"totalCount"`
+	Apis       []struct {
+		Name       string `json:"name"`
+		Properties []struct {
+			Type  string `json:"type"`
+			Url   string `json:"url"`
+			Value string `json:"value"`
+		} `json:"properties"`
+	} `json:"apis"`
+}
+
+// Property returns the value of a property of the i-th listed item, as
+// X-Version.
+func (specs *Specs) Property(i int, name string) string {
+	for _, property := range specs.Apis[i].Properties {
+		if property.Type == name {
+			return property.Value
+		}
+	}
+	return ""
+}
+
+// Version is a version as listed by the registry, with its settings. Oas
+// is the specification level, as 3.0.0.
+type Version struct {
+	Version   string
+	Oas       string
+	Default   bool
+	Private   bool
+	Published bool
+	Created   string
+	Modified  string
+}
+
+func (client *Client) getJson(ctx context.Context, path string, value interface{}) error {
+	body, err := client.Get(ctx, path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, value)
+}
+
+// Api is an API (or domain) as listed for its owner. Version is the one
+// the registry shows for it, its default version.
+type Api struct {
+	Name      string
+	Version   string
+	Private   bool
+	Published bool
+	Created   string
+	Modified  string
+}
+
+// ListOwner returns every API (or domain, with Domains) of an owner, going
+// through all the pages.
+func (client *Client) ListOwner(ctx context.Context, owner string) ([]Api, error) {
+	var apis []Api
+	for page := 0; ; page++ {
+		specs := Specs{}
+		if err := client.getJson(ctx, fmt.Sprintf("%s?page=%d&limit=%d&sort=NAME", owner, page, pageSize), &specs); err != nil {
+			return nil, err
+		}
+		for i, api := range specs.Apis {
+			apis = append(apis, Api{
+				Name:      api.Name,
+				Version:   specs.Property(i, "X-Version"),
+				Private:   specs.Property(i, "X-Private") == "true",
+				Published: specs.Property(i, "X-Published") == "true",
+				Created:   specs.Property(i, "X-Created"),
+				Modified:  specs.Property(i, "X-Modified"),
+			})
+		}
+		if len(specs.Apis) < pageSize || len(apis) >= specs.TotalCount {
+			return apis, nil
+		}
+	}
+}
+
+// ListApis returns the names of every API (or domain, with Domains) of an
+// owner.
+func (client *Client) ListApis(ctx context.Context, owner string) ([]string, error) {
+	apis, err := client.ListOwner(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, api := range apis {
+		names = append(names, api.Name)
+	}
+	return names, nil
+}
+
+// ListVersions returns the versions of owner/name.
+func (client *Client) ListVersions(ctx context.Context, api string) ([]Version, error) {
+	specs := Specs{}
+	if err := client.getJson(ctx, api, &specs); err != nil {
+		return nil, err
+	}
+	var defaultVersion struct {
+		Version string `json:"version"`
+	}
+	if err := client.getJson(ctx, api+"/settings/default", &defaultVersion); err != nil {
+		return nil, err
+	}
+
+	var versions []Version
+	for i := range specs.Apis {
+		version := specs.Property(i, "X-Version")
+		versions = append(versions, Version{
+			Version:   version,
+			Oas:       specs.Property(i, "X-OASVersion"),
+			Default:   version == defaultVersion.Version,
+			Private:   specs.Property(i, "X-Private") == "true",
+			Published: specs.Property(i, "X-Published") == "true",
+			Created:   specs.Property(i, "X-Created"),
+			Modified:  specs.Property(i, "X-Modified"),
+		})
+	}
+	return versions, nil
+}
+
+// DeleteVersion deletes a version of owner/name.
+func (client *Client) DeleteVersion(ctx context.Context, api string, version string) error {
+	_, err := client.Call(ctx, "DELETE", fmt.Sprintf("%s/%s", api, version), nil)
+	return err
+}
+
+// DeleteApi deletes owner/name with all its versions.
+func (client *Client) DeleteApi(ctx context.Context, api string) error {
+	_, err := client.Call(ctx, "DELETE", api, nil)
+	return err
+}
+
+// SetDefault makes a version the default one of owner/name, the one shown
+// in the portal.
+func (client *Client) SetDefault(ctx context.Context, api string, version string) error {
+	body, _ := json.Marshal(map[string]string{"version": version})
+	_, err := client.Call(ctx, "PUT", api+"/settings/default", body)
+	return err
+}
+
+// SetPublished changes the lifecycle of a version of owner/name. Published
+// versions are read only and visible to consumers.
+func (client *Client) SetPublished(ctx context.Context, api string, version string, published bool) error {
+	body, _ := json.Marshal(map[string]bool{"published": published})
+	_, err := client.Call(ctx, "PUT", fmt.Sprintf("%s/%s/settings/lifecycle", api, version), body)
+	return err
+}
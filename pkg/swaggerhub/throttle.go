@@ -0,0 +1,66 @@
+package swaggerhub
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// UploadLimiter keeps the uploads sharing it, as the publications of a batch
+// do through their Client, under Rate bytes per second all together.
+type UploadLimiter struct {
+	rate  int
+	mutex sync.Mutex
+	// next is when the bytes taken so far are all sent
+	next time.Time
+}
+
+// NewUploadLimiter returns a limiter of rate bytes per second.
+func NewUploadLimiter(rate int) *UploadLimiter {
+	return &UploadLimiter{rate: rate}
+}
+
+// reserve takes n bytes out of the rate and returns how long to wait until
+// they're sent. Time left unused isn't saved up for a burst later.
+func (limiter *UploadLimiter) reserve(n int) time.Duration {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	now := time.Now()
+	if limiter.next.Before(now) {
+		limiter.next = now
+	}
+	limiter.next = limiter.next.Add(time.Duration(float64(n) / float64(limiter.rate) * float64(time.Second)))
+	return limiter.next.Sub(now)
+}
+
+// throttledReader reads through a limiter, in small chunks so the upload is
+// smooth instead of bursting every second. Its waits end with ctx.
+type throttledReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *UploadLimiter
+	// waiting, when set, is told of every wait before it starts
+	waiting func(wait time.Duration)
+}
+
+func (throttled *throttledReader) Read(buffer []byte) (int, error) {
+	if chunk := throttled.limiter.rate/10 + 1; len(buffer) > chunk {
+		buffer = buffer[:chunk]
+	}
+
+	n, err := throttled.reader.Read(buffer)
+	if n == 0 {
+		return n, err
+	}
+	wait := throttled.limiter.reserve(n)
+	if throttled.waiting != nil {
+		throttled.waiting(wait)
+	}
+	select {
+	case <-time.After(wait):
+	case <-throttled.ctx.Done():
+		return n, throttled.ctx.Err()
+	}
+	return n, err
+}
@@ -0,0 +1,59 @@
+package swaggerhub
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUploadLimiterReserve(t *testing.T) {
+	limiter := NewUploadLimiter(1000)
+	tests := []struct {
+		bytes int
+		want  time.Duration
+	}{
+		{100, 100 * time.Millisecond},
+		{400, 500 * time.Millisecond},
+		{500, time.Second},
+	}
+	for _, test := range tests {
+		if got := limiter.reserve(test.bytes); got < test.want-20*time.Millisecond || got > test.want {
+			t.Errorf("reserving %d bytes waits %s, want %s", test.bytes, got, test.want)
+		}
+	}
+}
+
+func TestUploadLimiterShared(t *testing.T) {
+	limiter := NewUploadLimiter(10000)
+	started := time.Now()
+	var wait sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			reader := &throttledReader{ctx: context.Background(), reader: bytes.NewReader(make([]byte, 1000)), limiter: limiter}
+			ioutil.ReadAll(reader)
+		}()
+	}
+	wait.Wait()
+	// 3000 bytes at 10000 per second together, not 1000 each
+	if elapsed := time.Since(started); elapsed < 250*time.Millisecond {
+		t.Errorf("the uploads took %s, they didn't share the rate", elapsed)
+	}
+}
+
+func TestThrottledReaderCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := &throttledReader{ctx: ctx, reader: bytes.NewReader(make([]byte, 1000)), limiter: NewUploadLimiter(10)}
+	time.AfterFunc(50*time.Millisecond, cancel)
+	started := time.Now()
+	if _, err := ioutil.ReadAll(reader); err != context.Canceled {
+		t.Errorf("got %v, want the cancellation", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("the read stopped after %s", elapsed)
+	}
+}
@@ -0,0 +1,47 @@
+package swaggerhub
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Organization is an organization the owner of the API key is a member of.
+type Organization struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserManagementUrls are the User Management API endpoints next to the APIs
+// ones: https://api.swaggerhub.com/user-management/v1 for SwaggerHub, and
+// https://host/v1/user-management/v1 for On-Premise.
+func UserManagementUrls(apiUrls []string) []string {
+	var urls []string
+	for _, url := range apiUrls {
+		urls = append(urls, strings.TrimSuffix(strings.TrimSuffix(url, "/"), "/apis")+"/user-management/v1")
+	}
+	return urls
+}
+
+// Organizations returns the organizations of the owner of the API key, going
+// through all the pages. It's an authenticated call, an invalid or expired
+// key gets a *StatusError with 401 or 403.
+func (client *Client) Organizations(ctx context.Context) ([]Organization, error) {
+	users := *client
+	users.Urls = UserManagementUrls(client.Urls)
+
+	var organizations []Organization
+	for page := 0; ; page++ {
+		var list struct {
+			TotalCount int            `json:"totalCount"`
+			Items      []Organization `json:"items"`
+		}
+		if err := users.getJson(ctx, fmt.Sprintf("orgs?page=%d&pageSize=%d&sortBy=NAME&order=ASC", page, pageSize), &list); err != nil {
+			return nil, err
+		}
+		organizations = append(organizations, list.Items...)
+		if len(list.Items) < pageSize || len(organizations) >= list.TotalCount {
+			return organizations, nil
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type probeOptions struct {
+	BaseUrl    string `flag:"base-url" env:"SWAGGERGO_PROBE_BASE_URL" required:"true"`
+	Operations string `flag:"operations"`
+	Header     string `flag:"header" env:"SWAGGERGO_PROBE_HEADER"`
+	Timeout    string `flag:"timeout" default:"10s"`
+	MaxTime    string `flag:"max-time" env:"SWAGGERGO_MAX_TIME"`
+	Deadline   string `flag:"deadline" env:"SWAGGERGO_DEADLINE"`
+	Output     string `flag:"output" default:"text"`
+}
+
+// probeResult is what probe prints with --output json.
+type probeResult struct {
+	Status     string            `json:"status"`
+	Path       string            `json:"path"`
+	BaseUrl    string            `json:"baseUrl"`
+	Probed     int               `json:"probed"`
+	Failures   int               `json:"failures"`
+	Operations []probedOperation `json:"operations"`
+}
+
+// probedOperation is an operation of probeResult, ok, failed with the
+// problems of the response, or skipped with the reason.
+type probedOperation struct {
+	Operation string   `json:"operation"`
+	Status    string   `json:"status"`
+	Problems  []string `json:"problems,omitempty"`
+}
+
+// probeCommand calls the GET operations of the document against a live
+// environment and validates the responses against the documented ones.
+// Operations with required parameters are only probed when every one of
+// them has an example (or a default) to use.
+func probeCommand(args []string) {
+	options := probeOptions{}
+	positional := parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+	limitRunTime(options.MaxTime, options.Deadline)
+	if len(positional) != 1 {
+		exitAndError("probe needs the path to the OpenAPI definition")
+	}
+
+	openApiPath := positional[0]
+	openApi, err := ioutil.ReadFile(openApiPath)
+	if err != nil {
+		exitAndError(fmt.Sprintf("can't read the file %s", openApiPath))
+	}
+	document, err := parseOpenApiDocument(openApiPath, openApi)
+	if err != nil {
+		exitAndError(err)
+	}
+
+	timeout, err := time.ParseDuration(options.Timeout)
+	if err != nil {
+		exitAndError(fmt.Sprintf("invalid timeout %s", options.Timeout))
+	}
+	httpClient := client()
+	httpClient.Timeout = timeout
+
+	selected := map[string]bool{}
+	for _, operation := range splitList(options.Operations) {
+		selected[operation] = true
+	}
+
+	result := probeResult{Status: "ok", Path: positional[0], BaseUrl: options.BaseUrl, Operations: []probedOperation{}}
+	failures := 0
+	probed := 0
+	for _, operation := range document.operations() {
+		if interrupted() {
+			break
+		}
+		if operation.Method != "get" {
+			continue
+		}
+		operationId := scalarValue(mappingValue(operation.Node, "operationId"))
+		if len(selected) > 0 && !selected[operationId] && !selected[operation.String()] {
+			continue
+		}
+
+		url, err := probeUrl(document, operation, options.BaseUrl)
+		if err != nil {
+			log.Printf("skipping %s: %v", operation, err)
+			result.Operations = append(result.Operations, probedOperation{Operation: operation.String(), Status: "skipped", Problems: []string{err.Error()}})
+			continue
+		}
+
+		probed++
+		problems := probeOperation(&httpClient, document, operation, url, options.Header)
+		if len(problems) == 0 {
+			log.Printf("%s: ok", operation)
+			result.Operations = append(result.Operations, probedOperation{Operation: operation.String(), Status: "ok"})
+			continue
+		}
+		result.Operations = append(result.Operations, probedOperation{Operation: operation.String(), Status: "failed", Problems: problems})
+		failures++
+		for _, problem := range problems {
+			log.Printf("%s: %s", operation, problem)
+		}
+	}
+
+	message := fmt.Sprintf("probed %d operations, %d don't match the definition", probed, failures)
+	log.Print(message)
+	result.Probed, result.Failures = probed, failures
+	code := exitOk
+	switch {
+	case interrupted():
+		log.Printf("interrupted, the other operations were not probed")
+		result.Status, code = "interrupted", interruptedExitCode()
+	case failures > 0:
+		result.Status, code = "failed", exitFailure
+	}
+	if outputJson {
+		printJsonResult(result, code, message)
+	}
+	if code != exitOk {
+		os.Exit(code)
+	}
+}
+
+func probeUrl(document *openApiDocument, operation openApiOperation, baseUrl string) (string, error) {
+	path := operation.Path
+	query := neturl.Values{}
+
+	var parameters []*yaml.Node
+	for _, list := range []*yaml.Node{document.lookup("paths", operation.Path, "parameters"), mappingValue(operation.Node, "parameters")} {
+		if list != nil {
+			parameters = append(parameters, list.Content...)
+		}
+	}
+
+	for _, parameter := range parameters {
+		parameter = document.resolve(parameter)
+		name := scalarValue(mappingValue(parameter, "name"))
+		in := scalarValue(mappingValue(parameter, "in"))
+		required := in == "path" || scalarValue(mappingValue(parameter, "required")) == "true"
+		if !required {
+			continue
+		}
+
+		example, ok := parameterExample(document, parameter)
+		if !ok {
+			return "", fmt.Errorf("the required parameter %s has no example", name)
+		}
+		switch in {
+		case "path":
+			path = strings.Replace(path, "{"+name+"}", neturl.PathEscape(example), -1)
+		case "query":
+			query.Set(name, example)
+		default:
+			return "", fmt.Errorf("the required %s parameter %s is not supported", in, name)
+		}
+	}
+
+	url := strings.TrimSuffix(baseUrl, "/") + path
+	if len(query) > 0 {
+		url += "?" + query.Encode()
+	}
+	return url, nil
+}
+
+func parameterExample(document *openApiDocument, parameter *yaml.Node) (string, bool) {
+	candidates := []*yaml.Node{mappingValue(parameter, "example"), mappingValue(parameter, "x-example")}
+	eachMapping(mappingValue(parameter, "examples"), func(_ *yaml.Node, example *yaml.Node) {
+		candidates = append(candidates, mappingValue(document.resolve(example), "value"))
+	})
+
+	schema := document.resolve(mappingValue(parameter, "schema"))
+	if schema == nil {
+		schema = parameter
+	}
+	candidates = append(candidates, mappingValue(schema, "example"), mappingValue(schema, "default"))
+	if enum := mappingValue(schema, "enum"); enum != nil && len(enum.Content) > 0 {
+		candidates = append(candidates, enum.Content[0])
+	}
+
+	for _, candidate := range candidates {
+		if candidate != nil && candidate.Kind == yaml.ScalarNode {
+			return candidate.Value, true
+		}
+	}
+	return "", false
+}
+
+func probeOperation(httpClient *http.Client, document *openApiDocument, operation openApiOperation, url string, header string) []string {
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	request.Header.Set("accept", "application/json")
+	if name, value, ok := splitHeader(header); ok {
+		request.Header.Set(name, value)
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	responses := mappingValue(operation.Node, "responses")
+	definition := mappingValue(responses, fmt.Sprint(response.StatusCode))
+	if definition == nil {
+		definition = mappingValue(responses, fmt.Sprintf("%dXX", response.StatusCode/100))
+	}
+	if definition == nil {
+		definition = mappingValue(responses, "default")
+	}
+	if definition == nil {
+		return []string{fmt.Sprintf("the status %s is not documented", response.Status)}
+	}
+	definition = document.resolve(definition)
+
+	schema, documented := responseSchema(document, definition, response.Header.Get("Content-Type"))
+	if !documented {
+		return []string{fmt.Sprintf("the content type %s is not documented for %s", response.Header.Get("Content-Type"), response.Status)}
+	}
+	if schema == nil {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []string{fmt.Sprintf("the %s response is not valid JSON: %v", response.Status, err)}
+	}
+	return validateSchemaValue(document, schema, value, "#")
+}
+
+// responseSchema finds the schema documented for the content type of a
+// response. It returns a nil schema when the body can't be validated.
+func responseSchema(document *openApiDocument, definition *yaml.Node, contentType string) (*yaml.Node, bool) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	jsonBody := mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+
+	if document.isSwagger2() {
+		if !jsonBody {
+			return nil, true
+		}
+		return mappingValue(definition, "schema"), true
+	}
+
+	content := mappingValue(definition, "content")
+	if content == nil {
+		return nil, true
+	}
+	var schema *yaml.Node
+	documented := false
+	eachMapping(content, func(key *yaml.Node, value *yaml.Node) {
+		if documented {
+			return
+		}
+		if key.Value == mediaType || key.Value == "*/*" || (strings.HasSuffix(key.Value, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(key.Value, "*"))) {
+			documented = true
+			schema = mappingValue(value, "schema")
+		}
+	})
+	if !jsonBody {
+		schema = nil
+	}
+	return schema, documented
+}
+
+func splitHeader(header string) (string, string, bool) {
+	parts := strings.SplitN(header, ":", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), true
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// swaggerHubProfile is an account on a SwaggerHub installation, kept in the
+// user config so tokens stay out of repositories.
+type swaggerHubProfile struct {
+	Url   string `yaml:"url"`
+	Owner string `yaml:"owner"`
+	Token string `yaml:"token"`
+	Oas   string `yaml:"oas"`
+}
+
+type userConfig struct {
+	Profiles map[string]swaggerHubProfile `yaml:"profiles"`
+}
+
+// userConfigPath is ~/.config/swaggergo/config on Linux, and the equivalent
+// user config directory elsewhere. SWAGGERGO_USER_CONFIG overrides it.
+func userConfigPath() string {
+	if path := os.Getenv("SWAGGERGO_USER_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, commandLineName, "config")
+}
+
+func loadProfile(name string) (*swaggerHubProfile, error) {
+	path := userConfigPath()
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the profiles from %s", path)
+	}
+	var config userConfig
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("can't parse %s: %v", path, err)
+	}
+
+	profile, ok := config.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("there is no profile %s in %s", name, path)
+	}
+	if profile.Url == "" {
+		profile.Url = swaggerHubUrl
+	}
+	if profile.Url, err = registryApiUrl(profile.Url); err != nil {
+		return nil, fmt.Errorf("profile %s: %v", name, err)
+	}
+	return &profile, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// progress reports concurrent work. On a terminal it keeps a live view with a
+// spinner per running item and a totals line, printing failures above it as
+// they happen. Elsewhere, as in CI logs, every finished item is a plain log
+// line.
+type progress struct {
+	mutex   sync.Mutex
+	title   string
+	total   int
+	done    int
+	failed  int
+	running []string
+	tty     bool
+	drawn   int
+	frame   int
+	stop    chan bool
+	stopped chan bool
+}
+
+func newProgress(title string, total int) *progress {
+	progress := &progress{title: title, total: total, tty: isTerminal(os.Stderr)}
+	if progress.tty {
+		progress.stop = make(chan bool)
+		progress.stopped = make(chan bool)
+		go progress.animate()
+	}
+	return progress
+}
+
+// newPlainProgress reports without a live view, logging every finished item,
+// for work done inside another live view it would garble.
+func newPlainProgress(title string, total int) *progress {
+	return &progress{title: title, total: total}
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0 && os.Getenv("TERM") != "dumb" && enableAnsi(file)
+}
+
+func (progress *progress) start(item string) {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+	progress.running = append(progress.running, item)
+}
+
+func (progress *progress) finish(item string, problem string) {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+
+	for i, running := range progress.running {
+		if running == item {
+			progress.running = append(progress.running[:i], progress.running[i+1:]...)
+			break
+		}
+	}
+	progress.done++
+	if problem != "" {
+		progress.failed++
+	}
+
+	status := "ok"
+	if problem != "" {
+		status = problem
+	}
+	if !progress.tty {
+		log.Printf("%s: %s", item, status)
+		return
+	}
+	if problem != "" {
+		progress.clear()
+		fmt.Fprintf(os.Stderr, "✗ %s: %s\n", item, problem)
+		progress.draw()
+	}
+}
+
+// logTo returns where the logs written to out go while the live view is
+// shown: above it, instead of through it.
+func (progress *progress) logTo(out io.Writer) io.Writer {
+	if !progress.tty {
+		return out
+	}
+	return progressLog{progress, out}
+}
+
+type progressLog struct {
+	progress *progress
+	out      io.Writer
+}
+
+func (logs progressLog) Write(line []byte) (int, error) {
+	logs.progress.mutex.Lock()
+	defer logs.progress.mutex.Unlock()
+	logs.progress.clear()
+	n, err := logs.out.Write(line)
+	logs.progress.draw()
+	return n, err
+}
+
+// close stops the live view, leaving the totals line on the terminal.
+func (progress *progress) close() {
+	if !progress.tty {
+		log.Printf("%s: %d done, %d failed", progress.title, progress.done, progress.failed)
+		return
+	}
+	close(progress.stop)
+	<-progress.stopped
+}
+
+func (progress *progress) animate() {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-progress.stop:
+			progress.mutex.Lock()
+			progress.running = nil
+			progress.clear()
+			progress.draw()
+			progress.drawn = 0
+			progress.mutex.Unlock()
+			close(progress.stopped)
+			return
+		case <-ticker.C:
+			progress.mutex.Lock()
+			progress.frame++
+			progress.clear()
+			progress.draw()
+			progress.mutex.Unlock()
+		}
+	}
+}
+
+// clear erases the lines of the live view, moving the cursor back to where
+// it started.
+func (progress *progress) clear() {
+	if progress.drawn > 0 {
+		fmt.Fprintf(os.Stderr, "\x1b[%dF\x1b[J", progress.drawn)
+	}
+	progress.drawn = 0
+}
+
+func (progress *progress) draw() {
+	spinner := spinnerFrames[progress.frame%len(spinnerFrames)]
+	for _, item := range progress.running {
+		fmt.Fprintf(os.Stderr, "%s %s\n", spinner, item)
+	}
+	fmt.Fprintf(os.Stderr, "%s: %d/%d done, %d failed\n", progress.title, progress.done, progress.total, progress.failed)
+	progress.drawn = len(progress.running) + 1
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	neturl "net/url"
+	"strings"
+)
+
+type promoteOptions struct {
+	FromProfile   string `flag:"from-profile" required:"true"`
+	ToProfile     string `flag:"to-profile" required:"true"`
+	SwaggerHubApi string `flag:"api" env:"SWAGGERHUB_API" required:"true"`
+	ApiVersion    string `flag:"api-version" required:"true"`
+	Output        string `flag:"output" default:"text"`
+	transportOptions
+}
+
+// promoteResult is what promote prints with --output json.
+type promoteResult struct {
+	Status    string `json:"status"`
+	Api       string `json:"api"`
+	Version   string `json:"version"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Target    string `json:"target"`
+	Private   bool   `json:"private"`
+	Published bool   `json:"published"`
+	Default   bool   `json:"default"`
+}
+
+// promoteCommand copies a version from the account of a profile to the one
+// of another, keeping whether it's private, published and the default
+// version. The owner changes to the one of the target profile when it has
+// one.
+func promoteCommand(args []string) {
+	options := promoteOptions{}
+	parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+	options.useTransport()
+
+	parts := strings.Split(options.SwaggerHubApi, "/")
+	if len(parts) != 2 {
+		exitAndError("api is in the wrong format")
+	}
+	from, err := loadProfile(options.FromProfile)
+	if err != nil {
+		exitAndError(err)
+	}
+	to, err := loadProfile(options.ToProfile)
+	if err != nil {
+		exitAndError(err)
+	}
+	source := options.SwaggerHubApi
+	target := source
+	if to.Owner != "" {
+		target = to.Owner + "/" + parts[1]
+	}
+	version := options.ApiVersion
+
+	definition, err := profileRequest(from, "GET", fmt.Sprintf("%s/%s/swagger.yaml", source, version), nil)
+	if err != nil {
+		exitAndError(fmt.Errorf("can't fetch %s %s: %w", source, version, err))
+	}
+	var private struct {
+		Private bool `json:"private"`
+	}
+	var lifecycle struct {
+		Published bool `json:"published"`
+	}
+	var defaultVersion struct {
+		Version string `json:"version"`
+	}
+	for setting, value := range map[string]interface{}{
+		fmt.Sprintf("%s/%s/settings/private", source, version):   &private,
+		fmt.Sprintf("%s/%s/settings/lifecycle", source, version): &lifecycle,
+		fmt.Sprintf("%s/settings/default", source):               &defaultVersion,
+	} {
+		body, err := profileRequest(from, "GET", setting, nil)
+		if err == nil {
+			err = json.Unmarshal(body, value)
+		}
+		if err != nil {
+			exitAndError(fmt.Errorf("can't read %s: %w", setting, err))
+		}
+	}
+
+	query := neturl.Values{}
+	query.Set("isPrivate", fmt.Sprint(private.Private))
+	query.Set("version", version)
+	query.Set("force", "true")
+	if _, err := profileRequest(to, "POST", fmt.Sprintf("%s?%s", target, query.Encode()), definition); err != nil {
+		exitAndError(fmt.Errorf("can't create %s %s: %w", target, version, err))
+	}
+	if lifecycle.Published {
+		body, _ := json.Marshal(map[string]bool{"published": true})
+		if _, err := profileRequest(to, "PUT", fmt.Sprintf("%s/%s/settings/lifecycle", target, version), body); err != nil {
+			exitAndError(fmt.Errorf("can't publish %s %s: %w", target, version, err))
+		}
+	}
+	if defaultVersion.Version == version {
+		body, _ := json.Marshal(map[string]string{"version": version})
+		if _, err := profileRequest(to, "PUT", fmt.Sprintf("%s/settings/default", target), body); err != nil {
+			exitAndError(fmt.Errorf("can't make %s the default version of %s: %w", version, target, err))
+		}
+	}
+
+	log.Printf("promoted %s %s from %s to %s as %s (private: %t, published: %t, default: %t)",
+		source, version, options.FromProfile, options.ToProfile, target, private.Private, lifecycle.Published, defaultVersion.Version == version)
+	if outputJson {
+		printJson(promoteResult{
+			Status:    "promoted",
+			Api:       source,
+			Version:   version,
+			From:      options.FromProfile,
+			To:        options.ToProfile,
+			Target:    target,
+			Private:   private.Private,
+			Published: lifecycle.Published,
+			Default:   defaultVersion.Version == version,
+		})
+	}
+}
+
+// profileRequest calls the SwaggerHub API of a profile.
+func profileRequest(profile *swaggerHubProfile, method string, path string, body []byte) ([]byte, error) {
+	return requestSwaggerHub([]string{profile.Url}, profile.Token, method, path, body)
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	inTotoStatementType   = "https://in-toto.io/Statement/v0.1"
+	inTotoPayloadType     = "application/vnd.in-toto+json"
+	publishPredicateType  = "https://github.com/mijailr/swaggergo/publish/v1"
+	signatureSuffix       = ".sig"
+	attestationFileSuffix = ".intoto.json"
+)
+
+type provenanceStatement struct {
+	Type          string              `json:"_type"`
+	Subject       []provenanceSubject `json:"subject"`
+	PredicateType string              `json:"predicateType"`
+	Predicate     publishPredicate    `json:"predicate"`
+}
+
+type provenanceSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// publishPredicate records what was published. CanonicalDigest hashes the
+// document re-encoded as sorted JSON, with the mock servers SwaggerHub adds
+// left out and the published version, which survives the normalization
+// SwaggerHub applies to stored definitions.
+type publishPredicate struct {
+	Api             string `json:"api"`
+	Version         string `json:"version"`
+	Oas             string `json:"oas"`
+	MediaType       string `json:"mediaType"`
+	CanonicalDigest string `json:"canonicalDigest"`
+	PublishedAt     string `json:"publishedAt"`
+}
+
+type dsseEnvelope struct {
+	PayloadType string          `json:"payloadType"`
+	Payload     string          `json:"payload"`
+	Signatures  []dsseSignature `json:"signatures"`
+}
+
+type dsseSignature struct {
+	KeyId string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// publicationSigner loads the key of --signing-key, before the upload so a
+// missing or unreadable key doesn't leave an unsigned publication.
+func publicationSigner(options *commandLineOptions) (crypto.Signer, error) {
+	if options.SigningKey == "" {
+		return nil, fmt.Errorf("signing needs a key, use --signing-key")
+	}
+	return loadSigningKey(options.SigningKey)
+}
+
+// signPublication writes, next to the definition, a detached signature of
+// the uploaded bytes (verifiable with `cosign verify-blob --key`) and a
+// signed in-toto attestation describing the publication.
+func signPublication(openApiPath string, openApi []byte, mediaType string, signer crypto.Signer, options *commandLineOptions) error {
+	signature, err := signMessage(signer, openApi)
+	if err != nil {
+		return err
+	}
+	signaturePath := openApiPath + signatureSuffix
+	if err := ioutil.WriteFile(signaturePath, []byte(base64.StdEncoding.EncodeToString(signature)), 0644); err != nil {
+		return fmt.Errorf("can't write the signature %s: %v", signaturePath, err)
+	}
+
+	canonical, err := canonicalDigest(openApi, options.ApiVersion)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(openApi)
+
+	statement := provenanceStatement{
+		Type: inTotoStatementType,
+		Subject: []provenanceSubject{{
+			Name:   options.SwaggerHubApi,
+			Digest: map[string]string{"sha256": hex.EncodeToString(digest[:])},
+		}},
+		PredicateType: publishPredicateType,
+		Predicate: publishPredicate{
+			Api:             options.SwaggerHubApi,
+			Version:         publicationVersion(openApi, options),
+			Oas:             options.Oas,
+			MediaType:       mediaType,
+			CanonicalDigest: canonical,
+			PublishedAt:     time.Now().UTC().Format(time.RFC3339),
+		},
+	}
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return err
+	}
+	envelopeSignature, err := signMessage(signer, dssePreAuthEncoding(inTotoPayloadType, payload))
+	if err != nil {
+		return err
+	}
+	keyId, err := publicKeyId(signer.Public())
+	if err != nil {
+		return err
+	}
+
+	envelope, _ := json.MarshalIndent(dsseEnvelope{
+		PayloadType: inTotoPayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures:  []dsseSignature{{KeyId: keyId, Sig: base64.StdEncoding.EncodeToString(envelopeSignature)}},
+	}, "", "  ")
+	attestationPath := openApiPath + attestationFileSuffix
+	if err := ioutil.WriteFile(attestationPath, envelope, 0644); err != nil {
+		return fmt.Errorf("can't write the attestation %s: %v", attestationPath, err)
+	}
+
+	log.Printf("signed sha256:%s into %s and %s", hex.EncodeToString(digest[:]), signaturePath, attestationPath)
+	return nil
+}
+
+// loadSigningKey reads an unencrypted PEM private key: PKCS#8 (ECDSA,
+// Ed25519 or RSA), SEC 1 EC or PKCS#1 RSA.
+func loadSigningKey(path string) (crypto.Signer, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the signing key %s", path)
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM encoded key", path)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: %s keys are not supported, export an unencrypted PKCS#8 key", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't parse the signing key %s: %v", path, err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a signing key", path)
+	}
+	return signer, nil
+}
+
+func signMessage(signer crypto.Signer, message []byte) ([]byte, error) {
+	if _, ok := signer.(ed25519.PrivateKey); ok {
+		return signer.Sign(rand.Reader, message, crypto.Hash(0))
+	}
+	digest := sha256.Sum256(message)
+	return signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+func verifyMessage(publicKey crypto.PublicKey, message []byte, signature []byte) bool {
+	digest := sha256.Sum256(message)
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, message, signature)
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest[:], signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	}
+	return false
+}
+
+// verifyAttestation checks the signature of a DSSE envelope written by
+// signPublication and returns the statement it carries.
+func verifyAttestation(envelopeJson []byte, publicKey crypto.PublicKey) (*provenanceStatement, error) {
+	var envelope dsseEnvelope
+	if err := json.Unmarshal(envelopeJson, &envelope); err != nil {
+		return nil, fmt.Errorf("can't parse the attestation: %v", err)
+	}
+	if envelope.PayloadType != inTotoPayloadType {
+		return nil, fmt.Errorf("unexpected attestation payload type %s", envelope.PayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("can't decode the attestation payload: %v", err)
+	}
+
+	verified := false
+	for _, signature := range envelope.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(signature.Sig)
+		if err == nil && verifyMessage(publicKey, dssePreAuthEncoding(envelope.PayloadType, payload), sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("the attestation is not signed by the given key")
+	}
+
+	var statement provenanceStatement
+	if err := json.Unmarshal(payload, &statement); err != nil {
+		return nil, fmt.Errorf("can't parse the attestation statement: %v", err)
+	}
+	if statement.PredicateType != publishPredicateType {
+		return nil, fmt.Errorf("unexpected attestation predicate %s", statement.PredicateType)
+	}
+	return &statement, nil
+}
+
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the public key %s", path)
+	}
+	block, _ := pem.Decode(content)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("%s is not a PEM encoded public key", path)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse the public key %s: %v", path, err)
+	}
+	return publicKey, nil
+}
+
+// dssePreAuthEncoding is the message actually signed in a DSSE envelope.
+func dssePreAuthEncoding(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+func publicKeyId(publicKey crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(der)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// canonicalDigest hashes the value of a definition as SwaggerHub stores it,
+// see publishedValue, so the digest of the local file matches the one of the
+// fetched definition.
+func canonicalDigest(openApi []byte, version string) (string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(openApi, &root); err != nil || len(root.Content) == 0 {
+		return "", fmt.Errorf("can't parse the definition to compute its digest")
+	}
+	canonical, err := json.Marshal(publishedValue(root.Content[0], version))
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(canonical)
+	return hex.EncodeToString(digest[:]), nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestCanonicalDigest(t *testing.T) {
+	local := []byte("openapi: 3.0.0\ninfo: {title: Pets, version: \"1.0\"}\npaths: {}\n")
+	published := []byte(`{"openapi": "3.0.0", "servers": [{"url": "https://virtserver.swaggerhub.com/owner/pets/1.1"}], "info": {"version": "1.1", "title": "Pets"}, "paths": {}}`)
+
+	signed, err := canonicalDigest(local, "1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetched, err := canonicalDigest(published, "1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed != fetched {
+		t.Errorf("the digest of the published definition %s doesn't match the signed %s", fetched, signed)
+	}
+
+	if original, _ := canonicalDigest(local, ""); original == signed {
+		t.Error("the version didn't change the digest")
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type checkRefsOptions struct {
+	File         string `flag:"file" config:"file"`
+	FileHeader   string `flag:"file-header" env:"SWAGGERGO_FILE_HEADER"`
+	RefHeader    string `flag:"ref-header" env:"SWAGGERGO_REF_HEADER"`
+	NoRemoteRefs bool   `flag:"no-remote-refs" env:"SWAGGERGO_NO_REMOTE_REFS" config:"noRemoteRefs"`
+	Output       string `flag:"output" default:"text"`
+	transportOptions
+}
+
+// referenceSite is where a $ref is: the file, its line and the pointer to
+// the object holding it.
+type referenceSite struct {
+	File    string
+	Line    int
+	Pointer string
+}
+
+// referenceProblem is a $ref that can't be followed. From is the reference
+// of the definition that leads to it, the site itself when it's there.
+type referenceProblem struct {
+	Site    referenceSite
+	From    referenceSite
+	Message string
+}
+
+// checkRefsResult is what check-refs prints with --output json.
+type checkRefsResult struct {
+	Status   string           `json:"status"`
+	Path     string           `json:"path"`
+	Checked  int              `json:"checked"`
+	Problems []jsonRefProblem `json:"problems"`
+}
+
+// jsonRefProblem is a broken reference in the JSON output.
+type jsonRefProblem struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+// checkRefsCommand follows every $ref of the definition, into the files
+// and URLs it points to, and prints the ones that can't be resolved or go
+// round in circles.
+func checkRefsCommand(args []string) {
+	options := checkRefsOptions{}
+	positional := parseArgs(&options, args)
+	if len(positional) > 0 {
+		options.File = positional[0]
+	}
+	if options.File == "" {
+		exitAndError("check-refs needs the path to the OpenAPI definition")
+	}
+	useOutput(options.Output, "text", "github", "json")
+	options.useTransport()
+
+	openApi, err := readDefinition(options.File, options.FileHeader)
+	if err != nil {
+		exitAndError(err)
+	}
+	document, err := parseOpenApiDocument(options.File, openApi)
+	if err != nil {
+		exitWithCode(exitInvalid, err)
+	}
+	checked, problems, err := checkReferences(document, options.RefHeader, true, !options.NoRemoteRefs)
+	if err != nil {
+		exitAndError(err)
+	}
+
+	if outputJson {
+		printCheckRefsResult(options.File, checked, problems)
+	}
+	for _, problem := range problems {
+		if options.Output == "github" {
+			if err := githubAnnotation(os.Stdout, "error", problem.Site.File, problem.Site.Line, "check-refs", problem.Message); err != nil {
+				exitAndError(err)
+			}
+			continue
+		}
+		fmt.Printf("%s:%d: %s\n", problem.Site.File, problem.Site.Line, problem.Message)
+	}
+	if len(problems) > 0 {
+		exitWithCode(exitInvalid, fmt.Sprintf("%d of the %d references of %s are broken", len(problems), checked, options.File))
+	}
+	log.Printf("the %d references of %s resolve", checked, options.File)
+}
+
+// printCheckRefsResult prints the broken references with --output json,
+// exiting as the text output does when there are some.
+func printCheckRefsResult(path string, checked int, problems []referenceProblem) {
+	result := checkRefsResult{Status: "resolved", Path: path, Checked: checked, Problems: []jsonRefProblem{}}
+	for _, problem := range problems {
+		result.Problems = append(result.Problems, jsonRefProblem{File: problem.Site.File, Line: problem.Site.Line, Pointer: problem.Site.Pointer, Message: problem.Message})
+	}
+	code := exitOk
+	if len(problems) > 0 {
+		result.Status, code = "broken", exitInvalid
+	}
+	printJsonResult(result, code, fmt.Sprintf("%d of the %d references of %s are broken", len(problems), checked, path))
+}
+
+// checkReferences follows the references of the document, and of what they
+// point to in other files when external is set, downloading the ones to
+// URLs when remote is. The ones into the registry are left to SwaggerHub.
+// It returns how many references were followed.
+func checkReferences(document *openApiDocument, refHeader string, external bool, remote bool) (int, []referenceProblem, error) {
+	checker := referenceChecker{
+		definitionBundler: definitionBundler{root: document.Path, header: refHeader, remote: remote, files: map[string]*yaml.Node{}},
+		path:              document.Path,
+		external:          external,
+		failed:            map[string]error{},
+		walked:            map[string]bool{},
+	}
+	if !isDefinitionUrl(document.Path) {
+		root, err := filepath.Abs(document.Path)
+		if err != nil {
+			return 0, nil, err
+		}
+		checker.root = root
+	}
+	checker.files[checker.root] = document.Root
+
+	for i := 0; i+1 < len(document.Root.Content); i += 2 {
+		checker.walk(document.Root.Content[i+1], checker.root, joinPointer(document.Root.Content[i].Value), nil)
+	}
+	return checker.checked, checker.problems, nil
+}
+
+// checkRefs is the oas-refs rule: references into the document and to the
+// files next to it point to something, and don't go round in circles. Remote
+// references are left to SwaggerHub, or to bundling.
+func checkRefs(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	// documents read from SwaggerHub or stdin have no files next to them
+	_, statErr := os.Stat(document.Path)
+	_, problems, err := checkReferences(document, "", statErr == nil, false)
+	if err != nil {
+		return []lintFinding{{Message: err.Error()}}
+	}
+
+	var findings []lintFinding
+	for _, problem := range problems {
+		finding := lintFinding{Message: problem.Message, Pointer: problem.From.Pointer, Line: problem.From.Line}
+		if problem.Site != problem.From {
+			finding.Message = fmt.Sprintf("%s:%d: %s", problem.Site.File, problem.Site.Line, problem.Message)
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// referenceChecker follows references as the bundler does, without
+// changing them. walked has the locations whose references were checked,
+// and failed the files that couldn't be loaded.
+type referenceChecker struct {
+	definitionBundler
+	path     string
+	external bool
+	failed   map[string]error
+	walked   map[string]bool
+	checked  int
+	problems []referenceProblem
+}
+
+// walk checks the references of a node of file found at pointer. from is
+// the reference of the definition that led there, nil in the definition.
+func (checker *referenceChecker) walk(node *yaml.Node, file string, pointer string, from *referenceSite) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		if ref := mappingValue(node, "$ref"); ref != nil && ref.Kind == yaml.ScalarNode {
+			site := referenceSite{File: checker.display(file), Line: ref.Line, Pointer: pointer}
+			if from == nil {
+				checker.check(ref.Value, file, site, site)
+			} else {
+				checker.check(ref.Value, file, site, *from)
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			checker.walk(node.Content[i+1], file, pointer+"/"+escapePointer(node.Content[i].Value), from)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			checker.walk(item, file, pointer+"/"+strconv.Itoa(i), from)
+		}
+	}
+}
+
+// check follows a reference of file, then walks what it points to in
+// another file.
+func (checker *referenceChecker) check(ref string, file string, site referenceSite, from referenceSite) {
+	problem := func(format string, args ...interface{}) {
+		checker.problems = append(checker.problems, referenceProblem{Site: site, From: from, Message: fmt.Sprintf(format, args...)})
+	}
+
+	target, fragment, location, err := referenceTarget(ref, file)
+	if err != nil {
+		checker.checked++
+		problem("%v", err)
+		return
+	}
+	if (location != nil && (!checker.remote || isRegistryReference(location))) || (target != checker.root && !checker.external) {
+		return
+	}
+	checker.checked++
+	targetRoot, err := checker.target(target, file)
+	if err != nil {
+		problem("the reference %s can't be followed: %v", ref, err)
+		return
+	}
+	resolved := pointerNode(targetRoot, fragment)
+	if resolved == nil {
+		if target == file {
+			problem("the reference %s points to nothing", ref)
+		} else {
+			problem("the reference %s points to nothing, %s has nothing at #%s", ref, checker.display(target), fragment)
+		}
+		return
+	}
+	if cycle := checker.cycle(ref, file, site.Pointer, target, fragment, resolved); cycle != nil {
+		problem("the reference %s goes round in circles: %s", ref, strings.Join(cycle, " -> "))
+		return
+	}
+
+	key := target + "#" + fragment
+	if target != checker.root && !checker.walked[key] {
+		checker.walked[key] = true
+		checker.walk(resolved, target, "#"+fragment, &from)
+	}
+}
+
+// cycle follows the references that only point to another reference from
+// the one at pointer of file, and returns them when they lead back to it.
+// Recursive schemas have something next to the reference to themselves, so
+// they aren't cycles.
+func (checker *referenceChecker) cycle(ref string, file string, pointer string, target string, fragment string, resolved *yaml.Node) []string {
+	start := file + pointer
+	seen := map[string]bool{}
+	refs := []string{ref}
+	for {
+		key := target + "#" + fragment
+		if key == start {
+			return refs
+		}
+		if seen[key] {
+			return nil
+		}
+		seen[key] = true
+
+		next := mappingValue(resolved, "$ref")
+		if next == nil || next.Kind != yaml.ScalarNode {
+			return nil
+		}
+		nextTarget, nextFragment, location, err := referenceTarget(next.Value, target)
+		if err != nil || (location != nil && (!checker.remote || isRegistryReference(location))) || (nextTarget != checker.root && !checker.external) {
+			return nil
+		}
+		nextRoot, err := checker.target(nextTarget, target)
+		if err != nil {
+			return nil
+		}
+		if resolved = pointerNode(nextRoot, nextFragment); resolved == nil {
+			return nil
+		}
+		target, fragment = nextTarget, nextFragment
+		refs = append(refs, next.Value)
+	}
+}
+
+// target loads the file a reference points to, once even when it fails.
+func (checker *referenceChecker) target(path string, from string) (*yaml.Node, error) {
+	if err, ok := checker.failed[path]; ok {
+		return nil, err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) && !isDefinitionUrl(path) {
+		err = fmt.Errorf("%s doesn't exist", checker.display(path))
+		checker.failed[path] = err
+		return nil, err
+	}
+	root, err := checker.load(path, from)
+	if err != nil {
+		checker.failed[path] = err
+	}
+	return root, err
+}
+
+// display is how a file is named in the problems: the definition as it
+// was given, and the others relative to the working directory when they're
+// under it.
+func (checker *referenceChecker) display(path string) string {
+	if path == checker.root {
+		return checker.path
+	}
+	if isDefinitionUrl(path) {
+		return path
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if relative, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(relative, "..") {
+			return relative
+		}
+	}
+	return path
+}
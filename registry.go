@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/mijailr/swaggergo/pkg/swaggerhub"
+)
+
+// swaggerHubDomainUrls are the domains endpoints next to the APIs ones.
+func swaggerHubDomainUrls() []string {
+	return swaggerhub.DomainUrls(swaggerHubUrls)
+}
+
+// listOwner returns the names of every API (or domain, with the domain URLs)
+// of an owner.
+func listOwner(urls []string, owner string, accessToken string) ([]string, error) {
+	return swaggerHub(urls, accessToken).ListApis(runContext, owner)
+}
+
+// listVersions returns the versions of owner/name.
+func listVersions(urls []string, name string, accessToken string) ([]swaggerhub.Version, error) {
+	return swaggerHub(urls, accessToken).ListVersions(runContext, name)
+}
+
+// requestSwaggerHub calls the registry API. Bodies are YAML definitions for
+// POST and JSON settings otherwise.
+func requestSwaggerHub(urls []string, accessToken string, method string, path string, body []byte) ([]byte, error) {
+	return swaggerHub(urls, accessToken).Call(runContext, method, path, body)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// definitionFindings are the findings of a checked definition, or the error
+// that kept it from being checked.
+type definitionFindings struct {
+	Path     string
+	Findings []lintFinding
+	Err      error
+}
+
+// findingsReporters write the results of validate and lint for other tools,
+// by the name given with --report. --output github prints the annotations
+// with the usual output.
+var findingsReporters = map[string]func(output io.Writer, results []definitionFindings) error{
+	"junit":  writeJunitReport,
+	"github": writeGithubAnnotations,
+}
+
+// githubAnnotationLevels map severities to the workflow commands GitHub
+// Actions shows as annotations.
+var githubAnnotationLevels = map[string]string{"error": "error", "warning": "warning", "info": "notice", "hint": "notice"}
+
+// checkReportFormat stops on an unknown --report before anything is checked.
+func checkReportFormat(report string) {
+	if _, ok := findingsReporters[report]; report != "" && !ok {
+		exitAndError(fmt.Sprintf("unknown report %s, use junit or github", report))
+	}
+}
+
+// writeFindingsReport writes the report to file, or to the standard output
+// without one.
+func writeFindingsReport(report string, file string, results []definitionFindings) {
+	if report == "" {
+		return
+	}
+	output := io.Writer(os.Stdout)
+	if file != "" {
+		created, err := os.Create(file)
+		if err != nil {
+			exitAndError(fmt.Sprintf("can't write the file %s", file))
+		}
+		defer created.Close()
+		output = created
+	}
+	if err := findingsReporters[report](output, results); err != nil {
+		exitAndError(fmt.Errorf("can't write the report: %w", err))
+	}
+	if file != "" {
+		log.Printf("%s report written to %s", report, file)
+	}
+}
+
+// checkResult is the JSON output of validate and lint.
+type checkResult struct {
+	Status      string            `json:"status"`
+	Failed      int               `json:"failed"`
+	Definitions []checkDefinition `json:"definitions"`
+}
+
+type checkDefinition struct {
+	Path     string        `json:"path"`
+	Status   string        `json:"status"`
+	Findings []jsonFinding `json:"findings"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// printCheckResult prints the results with --output json, a definition
+// failing on a read error or an error finding.
+func printCheckResult(results []definitionFindings) {
+	result := checkResult{Status: "passed", Definitions: []checkDefinition{}}
+	for _, checked := range results {
+		definition := checkDefinition{Path: checked.Path, Status: "passed", Findings: jsonFindings(checked.Findings)}
+		if checked.Err != nil {
+			definition.Status, definition.Error = "failed", checked.Err.Error()
+		}
+		for _, finding := range checked.Findings {
+			if finding.Severity == "error" {
+				definition.Status = "failed"
+			}
+		}
+		if definition.Status == "failed" {
+			result.Status = "failed"
+			result.Failed++
+		}
+		result.Definitions = append(result.Definitions, definition)
+	}
+	code := exitOk
+	if result.Failed > 0 {
+		code = exitInvalid
+	}
+	printJsonResult(result, code, fmt.Sprintf("%d of %d definitions failed", result.Failed, len(results)))
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJunitReport writes a test suite per definition and a test case per
+// finding. Errors are failures, other severities pass with the finding as
+// their output, and a definition without findings has a single passing case.
+func writeJunitReport(output io.Writer, results []definitionFindings) error {
+	report := junitTestSuites{Name: commandLineName}
+	for _, result := range results {
+		suite := junitTestSuite{Name: result.Path}
+		switch {
+		case result.Err != nil:
+			suite.Errors++
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      result.Path,
+				Classname: result.Path,
+				File:      result.Path,
+				Error:     &junitFailure{Message: result.Err.Error(), Type: "error", Text: result.Err.Error()},
+			})
+		case len(result.Findings) == 0:
+			suite.Cases = append(suite.Cases, junitTestCase{Name: result.Path, Classname: result.Path, File: result.Path})
+		}
+
+		for _, finding := range result.Findings {
+			text := fmt.Sprintf("%s:%d: %s %s: %s", result.Path, finding.Line, finding.Severity, finding.Rule, finding.Message)
+			testCase := junitTestCase{
+				Name:      fmt.Sprintf("%s %s", finding.Rule, finding.Pointer),
+				Classname: result.Path,
+				File:      result.Path,
+				Line:      finding.Line,
+			}
+			if finding.Severity == "error" {
+				suite.Failures++
+				testCase.Failure = &junitFailure{Message: finding.Message, Type: finding.Rule, Text: text}
+			} else {
+				testCase.SystemOut = text
+			}
+			suite.Cases = append(suite.Cases, testCase)
+		}
+
+		suite.Tests = len(suite.Cases)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Suites = append(report.Suites, suite)
+	}
+
+	if _, err := io.WriteString(output, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(output)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(output, "\n")
+	return err
+}
+
+// writeGithubAnnotations prints the findings as workflow commands, so GitHub
+// Actions shows them on the lines of the pull request.
+func writeGithubAnnotations(output io.Writer, results []definitionFindings) error {
+	for _, result := range results {
+		if result.Err != nil {
+			if err := githubAnnotation(output, "error", result.Path, 0, commandLineName, result.Err.Error()); err != nil {
+				return err
+			}
+		}
+		for _, finding := range result.Findings {
+			if err := githubAnnotation(output, githubAnnotationLevels[finding.Severity], result.Path, finding.Line, finding.Rule, finding.Message); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// githubAnnotation prints a ::error, ::warning or ::notice workflow command.
+// Without a line the annotation goes to the whole file.
+func githubAnnotation(output io.Writer, level string, file string, line int, title string, message string) error {
+	properties := "file=" + escapeGithubProperty(file)
+	if line > 0 {
+		properties += fmt.Sprintf(",line=%d", line)
+	}
+	if title != "" {
+		properties += ",title=" + escapeGithubProperty(title)
+	}
+	_, err := fmt.Fprintf(output, "::%s %s::%s\n", level, properties, escapeGithubData(message))
+	return err
+}
+
+func escapeGithubData(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(value)
+}
+
+func escapeGithubProperty(value string) string {
+	return strings.NewReplacer(":", "%3A", ",", "%2C").Replace(escapeGithubData(value))
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mijailr/swaggergo/pkg/swaggerhub"
+)
+
+type retentionOptions struct {
+	SwaggerHubApi string `flag:"api" env:"SWAGGERHUB_API"`
+	Dir           string `flag:"dir"`
+	Keep          string `flag:"keep"`
+	KeepPublished bool   `flag:"keep-published"`
+	Plan          bool   `flag:"plan"`
+	AutoApprove   bool   `flag:"auto-approve"`
+	Output        string `flag:"output" default:"text"`
+	connectionOptions
+}
+
+// retentionPolicy keeps the newest Keep versions of an API. The default
+// version is always kept, and so are the published ones with KeepPublished.
+type retentionPolicy struct {
+	Keep          int  `yaml:"keep"`
+	KeepPublished bool `yaml:"keepPublished"`
+}
+
+// retentionTarget is an API or domain the policy applies to. Entry is set
+// when it comes from a registry directory.
+type retentionTarget struct {
+	Urls   []string
+	Kind   string
+	Item   string
+	Policy retentionPolicy
+	Entry  *registryEntry
+}
+
+// retentionCommand deletes the versions beyond the retention window of an
+// API, or of every API and domain of a registry directory with a retention
+// policy. With --dir the deleted versions are also removed from the
+// directory, so apply doesn't create them again.
+func retentionCommand(args []string) {
+	options := retentionOptions{}
+	positional := parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+	if len(positional) != 1 || positional[0] != "apply" {
+		exitAndError("usage: swaggergo retention apply (--api owner/name --keep 10 | --dir registry) [--keep-published]")
+	}
+	options.useConnection(&options.SwaggerHubApi)
+	if options.SwaggerHubAccessToken == "" {
+		exitAndError("missing access-token")
+	}
+	if (options.SwaggerHubApi == "") == (options.Dir == "") {
+		exitAndError("retention needs either --api or --dir")
+	}
+
+	var flagPolicy *retentionPolicy
+	if options.Keep != "" {
+		keep, err := strconv.Atoi(options.Keep)
+		if err != nil || keep < 1 {
+			exitAndError(fmt.Sprintf("invalid keep %s, use a number of versions", options.Keep))
+		}
+		flagPolicy = &retentionPolicy{Keep: keep, KeepPublished: options.KeepPublished}
+	}
+
+	var targets []retentionTarget
+	var manifest *registryManifest
+	if options.SwaggerHubApi != "" {
+		if flagPolicy == nil {
+			exitAndError("missing keep")
+		}
+		targets = append(targets, retentionTarget{Urls: swaggerHubUrls, Kind: "apis", Item: options.SwaggerHubApi, Policy: *flagPolicy})
+	} else {
+		var err error
+		manifest, err = loadRegistryManifest(options.Dir)
+		if err != nil {
+			exitAndError(fmt.Errorf("can't read the registry in %s: %w", options.Dir, err))
+		}
+		for _, kind := range []struct {
+			urls    []string
+			name    string
+			entries []registryEntry
+		}{
+			{swaggerHubUrls, "apis", manifest.Apis},
+			{swaggerHubDomainUrls(), "domains", manifest.Domains},
+		} {
+			for i := range kind.entries {
+				entry := &kind.entries[i]
+				policy := flagPolicy
+				if policy == nil {
+					policy = entry.Retention
+				}
+				if policy == nil {
+					policy = manifest.Retention
+				}
+				if policy == nil || policy.Keep < 1 {
+					continue
+				}
+				targets = append(targets, retentionTarget{Urls: kind.urls, Kind: kind.name, Item: manifest.Owner + "/" + entry.Name, Policy: *policy, Entry: entry})
+			}
+		}
+	}
+
+	// a cached listing could miss the latest versions
+	swaggerHubCache = nil
+	var changes []applyChange
+	for _, target := range targets {
+		expired, err := expiredVersions(target, options.SwaggerHubAccessToken)
+		if err != nil {
+			exitAndError(fmt.Errorf("can't list the versions of %s: %w", target.Item, err))
+		}
+		for _, version := range expired {
+			target, version := target, version
+			changes = append(changes, applyChange{fmt.Sprintf("- delete %s/%s %s", target.Kind, target.Item, version), func() error {
+				if _, err := requestSwaggerHub(target.Urls, options.SwaggerHubAccessToken, "DELETE", fmt.Sprintf("%s/%s", target.Item, version), nil); err != nil {
+					return err
+				}
+				if target.Entry == nil {
+					return nil
+				}
+				forgetVersion(options.Dir, target.Entry, version)
+				return writeRegistryManifest(options.Dir, manifest)
+			}})
+		}
+	}
+
+	target := options.SwaggerHubApi
+	if manifest != nil {
+		target = manifest.Owner
+	}
+	if len(changes) == 0 {
+		log.Print("no versions beyond the retention window")
+		printChangesResult(target, changes, false)
+		return
+	}
+	applied := runChanges(target, changes, options.Plan, options.AutoApprove)
+	if applied {
+		log.Printf("%d versions deleted", len(changes))
+	}
+	printChangesResult(target, changes, applied)
+}
+
+// expiredVersions returns the versions of the target beyond its retention
+// window.
+func expiredVersions(target retentionTarget, accessToken string) ([]string, error) {
+	versions, err := listVersions(target.Urls, target.Item, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	return versionsBeyond(target.Item, versions, target.Policy), nil
+}
+
+// versionsBeyond sorts the versions newest first by creation time and
+// returns the ones beyond the window of the policy. A version without a
+// valid creation time is kept, its age is unknown.
+func versionsBeyond(item string, versions []swaggerhub.Version, policy retentionPolicy) []string {
+	type datedVersion struct {
+		swaggerhub.Version
+		created time.Time
+	}
+	var dated []datedVersion
+	for _, version := range versions {
+		created, err := time.Parse(time.RFC3339, version.Created)
+		if err != nil {
+			log.Printf("keeping %s %s, its creation time %q is unknown", item, version.Version, version.Created)
+			continue
+		}
+		dated = append(dated, datedVersion{version, created})
+	}
+	sort.SliceStable(dated, func(i, j int) bool {
+		return dated[i].created.After(dated[j].created)
+	})
+
+	var expired []string
+	for i, version := range dated {
+		if i < policy.Keep || version.Default || version.Published && policy.KeepPublished {
+			continue
+		}
+		expired = append(expired, version.Version.Version)
+	}
+	return expired
+}
+
+// forgetVersion removes a deleted version and its file from a registry
+// directory.
+func forgetVersion(directory string, entry *registryEntry, version string) {
+	for i, candidate := range entry.Versions {
+		if candidate.Version == version {
+			os.Remove(filepath.Join(directory, filepath.FromSlash(candidate.File)))
+			entry.Versions = append(entry.Versions[:i], entry.Versions[i+1:]...)
+			return
+		}
+	}
+}
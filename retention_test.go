@@ -0,0 +1,36 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mijailr/swaggergo/pkg/swaggerhub"
+)
+
+func TestVersionsBeyond(t *testing.T) {
+	versions := []swaggerhub.Version{
+		{Version: "1.0", Created: "2026-01-01T00:00:00Z"},
+		{Version: "1.2", Created: "2026-03-01T00:00:00Z"},
+		{Version: "1.1", Created: "2026-02-01T00:00:00Z", Published: true},
+		{Version: "0.9", Created: "2025-12-01T00:00:00Z", Default: true},
+		{Version: "0.1", Created: ""},
+		{Version: "0.2", Created: "last year"},
+	}
+	tests := []struct {
+		name   string
+		policy retentionPolicy
+		want   []string
+	}{
+		{"oldest beyond the window", retentionPolicy{Keep: 1}, []string{"1.1", "1.0"}},
+		{"published kept", retentionPolicy{Keep: 1, KeepPublished: true}, []string{"1.0"}},
+		{"all in the window", retentionPolicy{Keep: 4}, nil},
+		{"unknown age never deleted", retentionPolicy{Keep: 0}, []string{"1.2", "1.1", "1.0"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := versionsBeyond("owner/orders", versions, test.policy); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/mijailr/swaggergo/pkg/swaggerhub"
+)
+
+// noRevision is the revision of a version that doesn't exist yet, so
+// `--if-match none` only publishes a version nobody else created.
+const noRevision = "none"
+
+// definitionRevision identifies the content of a version. It's the canonical
+// digest, so the YAML and JSON documents of a version have the same one and
+// the reformatting of SwaggerHub doesn't change it.
+func definitionRevision(openApi []byte) (string, error) {
+	digest, err := canonicalDigest(openApi, "")
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + digest, nil
+}
+
+// checkRevision stops the publication when the version on SwaggerHub isn't
+// the revision given with --if-match (or the file it names with @), because
+// someone published it after it was fetched. It's checked right before the
+// upload, SwaggerHub has no conditional publication to close the gap.
+func checkRevision(registry *swaggerhub.Client, ifMatch string, openApi []byte, options *commandLineOptions) error {
+	expected := ifMatch
+	if strings.HasPrefix(ifMatch, "@") {
+		content, err := ioutil.ReadFile(ifMatch[1:])
+		if err != nil {
+			return fmt.Errorf("can't read the revision from %s", ifMatch[1:])
+		}
+		expected = strings.TrimSpace(string(content))
+	}
+
+	version := publicationVersion(openApi, options)
+	// a cached copy would hide a publication made in the meantime
+	uncached := *registry
+	uncached.Cache = nil
+	current := noRevision
+	published, err := uncached.Fetch(runContext, options.SwaggerHubApi, version)
+	var statusError *swaggerhub.StatusError
+	switch {
+	case errors.As(err, &statusError) && statusError.StatusCode == http.StatusNotFound:
+	case err != nil:
+		return fmt.Errorf("can't check the revision of %s %s: %w", options.SwaggerHubApi, version, err)
+	default:
+		if current, err = definitionRevision(published); err != nil {
+			return err
+		}
+	}
+
+	if current != expected {
+		return fmt.Errorf("remote changed since fetch: %s %s is at revision %s, not %s; fetch it again and reapply the changes", options.SwaggerHubApi, version, current, expected)
+	}
+	log.Printf("%s %s is still at revision %s", options.SwaggerHubApi, version, expected)
+	return nil
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	dialectSwagger20 = "swagger-2.0"
+	dialectOas30     = "oas-3.0"
+	dialect202012    = "https://json-schema.org/draft/2020-12/schema"
+)
+
+var oas31Dialects = map[string]bool{
+	"https://spec.openapis.org/oas/3.1/dialect/base": true,
+	dialect202012: true,
+}
+
+var swagger20Keywords = []string{
+	"$ref", "format", "title", "description", "default", "multipleOf", "maximum", "exclusiveMaximum",
+	"minimum", "exclusiveMinimum", "maxLength", "minLength", "pattern", "maxItems", "minItems",
+	"uniqueItems", "maxProperties", "minProperties", "required", "enum", "type", "items", "allOf",
+	"properties", "additionalProperties", "discriminator", "readOnly", "xml", "externalDocs", "example",
+}
+
+var oas30Keywords = append([]string{
+	"oneOf", "anyOf", "not", "nullable", "writeOnly", "deprecated",
+}, swagger20Keywords...)
+
+var jsonSchemaTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true, "number": true, "string": true, "integer": true,
+}
+
+// Keywords holding a single subschema, a list of them or a map of them. The
+// 3.0 subset only knows about some of these, which are checked separately.
+var (
+	subschemaKeywords     = []string{"items", "additionalProperties", "not", "contains", "if", "then", "else", "propertyNames", "unevaluatedItems", "unevaluatedProperties", "contentSchema"}
+	subschemaListKeywords = []string{"allOf", "anyOf", "oneOf", "prefixItems"}
+	subschemaMapKeywords  = []string{"properties", "patternProperties", "dependentSchemas", "$defs"}
+)
+
+var schemaRules = []lintRule{
+	{Name: "schema-dialect", Severity: "error", Check: checkSchemaDialect},
+}
+
+type schemaNode struct {
+	Pointer string
+	Node    *yaml.Node
+}
+
+// schemaDialect returns the JSON Schema dialect used by the document: the
+// OpenAPI subsets for 2.0 and 3.0, and 2020-12 (or jsonSchemaDialect) for 3.1.
+func (document *openApiDocument) schemaDialect() string {
+	if document.isSwagger2() {
+		return dialectSwagger20
+	}
+	if !strings.HasPrefix(scalarValue(document.lookup("openapi")), "3.1") {
+		return dialectOas30
+	}
+	if dialect := scalarValue(document.lookup("jsonSchemaDialect")); dialect != "" {
+		return dialect
+	}
+	return dialect202012
+}
+
+func (document *openApiDocument) is31() bool {
+	dialect := document.schemaDialect()
+	return dialect != dialectSwagger20 && dialect != dialectOas30
+}
+
+// schemas walks every schema reachable from the document, nested ones
+// included, without following references.
+func (document *openApiDocument) schemas() []schemaNode {
+	var schemas []schemaNode
+	var walk func(pointer string, node *yaml.Node)
+	walk = func(pointer string, node *yaml.Node) {
+		if node == nil {
+			return
+		}
+		schemas = append(schemas, schemaNode{Pointer: pointer, Node: node})
+		for _, keyword := range subschemaKeywords {
+			if sub := mappingValue(node, keyword); sub != nil && sub.Kind != yaml.SequenceNode {
+				walk(pointer+"/"+keyword, sub)
+			}
+		}
+		for _, keyword := range subschemaListKeywords {
+			if list := mappingValue(node, keyword); list != nil && list.Kind == yaml.SequenceNode {
+				for i, sub := range list.Content {
+					walk(fmt.Sprintf("%s/%s/%d", pointer, keyword, i), sub)
+				}
+			}
+		}
+		for _, keyword := range subschemaMapKeywords {
+			eachMapping(mappingValue(node, keyword), func(name *yaml.Node, sub *yaml.Node) {
+				walk(pointer+"/"+keyword+"/"+escapePointer(name.Value), sub)
+			})
+		}
+	}
+
+	for _, root := range document.rootSchemas() {
+		walk(root.Pointer, root.Node)
+	}
+	return schemas
+}
+
+func (document *openApiDocument) rootSchemas() []schemaNode {
+	var roots []schemaNode
+	add := func(node *yaml.Node, keys ...string) {
+		if node != nil {
+			roots = append(roots, schemaNode{Pointer: joinPointer(keys...), Node: node})
+		}
+	}
+	addContent := func(content *yaml.Node, keys ...string) {
+		eachMapping(content, func(mediaType *yaml.Node, value *yaml.Node) {
+			add(mappingValue(value, "schema"), append(keys, mediaType.Value, "schema")...)
+		})
+	}
+	addParameters := func(parameters *yaml.Node, keys ...string) {
+		if parameters == nil {
+			return
+		}
+		for i, parameter := range parameters.Content {
+			index := fmt.Sprint(i)
+			add(mappingValue(parameter, "schema"), append(keys, index, "schema")...)
+			addContent(mappingValue(parameter, "content"), append(keys, index, "content")...)
+		}
+	}
+	addResponses := func(responses *yaml.Node, keys ...string) {
+		eachMapping(responses, func(code *yaml.Node, response *yaml.Node) {
+			add(mappingValue(response, "schema"), append(keys, code.Value, "schema")...)
+			addContent(mappingValue(response, "content"), append(keys, code.Value, "content")...)
+			eachMapping(mappingValue(response, "headers"), func(name *yaml.Node, header *yaml.Node) {
+				add(mappingValue(header, "schema"), append(keys, code.Value, "headers", name.Value, "schema")...)
+			})
+		})
+	}
+
+	eachMapping(document.lookup("definitions"), func(name *yaml.Node, schema *yaml.Node) {
+		add(schema, "definitions", name.Value)
+	})
+	eachMapping(document.lookup("components", "schemas"), func(name *yaml.Node, schema *yaml.Node) {
+		add(schema, "components", "schemas", name.Value)
+	})
+	eachMapping(document.lookup("components", "parameters"), func(name *yaml.Node, parameter *yaml.Node) {
+		add(mappingValue(parameter, "schema"), "components", "parameters", name.Value, "schema")
+	})
+	eachMapping(document.lookup("components", "requestBodies"), func(name *yaml.Node, body *yaml.Node) {
+		addContent(mappingValue(body, "content"), "components", "requestBodies", name.Value, "content")
+	})
+	addResponses(document.lookup("components", "responses"), "components", "responses")
+
+	for _, section := range []string{"paths", "webhooks"} {
+		eachMapping(document.lookup(section), func(path *yaml.Node, item *yaml.Node) {
+			addParameters(mappingValue(item, "parameters"), section, path.Value, "parameters")
+		})
+	}
+	for _, operation := range append(document.operations(), document.webhooks()...) {
+		keys := operation.keys()
+		addParameters(mappingValue(operation.Node, "parameters"), append(keys, "parameters")...)
+		addContent(mappingValue(mappingValue(operation.Node, "requestBody"), "content"), append(keys, "requestBody", "content")...)
+		addResponses(mappingValue(operation.Node, "responses"), append(keys, "responses")...)
+	}
+	return roots
+}
+
+func checkSchemaDialect(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	dialect := document.schemaDialect()
+	if document.is31() && !oas31Dialects[dialect] {
+		// Schemas written for a custom dialect can't be checked here.
+		return nil
+	}
+
+	var findings []lintFinding
+	report := func(schema schemaNode, line int, format string, args ...interface{}) {
+		findings = append(findings, lintFinding{
+			Message: fmt.Sprintf("schema at %s: %s", schema.Pointer, fmt.Sprintf(format, args...)),
+			Pointer: schema.Pointer,
+			Line:    line,
+		})
+	}
+
+	for _, schema := range document.schemas() {
+		if schema.Node.Tag == "!!bool" && (document.is31() || strings.HasSuffix(schema.Pointer, "/additionalProperties")) {
+			continue
+		}
+		if schema.Node.Kind != yaml.MappingNode {
+			report(schema, schema.Node.Line, "must be an object")
+			continue
+		}
+		if document.is31() {
+			check202012Schema(schema, report)
+		} else {
+			checkSubsetSchema(dialect, schema, report)
+		}
+	}
+	return findings
+}
+
+func checkSubsetSchema(dialect string, schema schemaNode, report func(schemaNode, int, string, ...interface{})) {
+	allowed := map[string]bool{}
+	keywords := oas30Keywords
+	if dialect == dialectSwagger20 {
+		keywords = swagger20Keywords
+	}
+	for _, keyword := range keywords {
+		allowed[keyword] = true
+	}
+
+	eachMapping(schema.Node, func(key *yaml.Node, value *yaml.Node) {
+		switch {
+		case strings.HasPrefix(key.Value, "x-"):
+		case !allowed[key.Value]:
+			report(schema, key.Line, "%s is not supported by %s, it needs OpenAPI 3.1", key.Value, dialect)
+		case key.Value == "type" && (value.Kind != yaml.ScalarNode || value.Value == "null" || !jsonSchemaTypes[value.Value]):
+			report(schema, key.Line, "type must be one of boolean, object, array, number, string or integer")
+		case (key.Value == "exclusiveMinimum" || key.Value == "exclusiveMaximum") && value.Tag != "!!bool":
+			report(schema, key.Line, "%s must be a boolean in %s", key.Value, dialect)
+		case key.Value == "items" && value.Kind == yaml.SequenceNode:
+			report(schema, key.Line, "items must be a schema object")
+		}
+	})
+}
+
+func check202012Schema(schema schemaNode, report func(schemaNode, int, string, ...interface{})) {
+	eachMapping(schema.Node, func(key *yaml.Node, value *yaml.Node) {
+		switch key.Value {
+		case "type":
+			types := []*yaml.Node{value}
+			if value.Kind == yaml.SequenceNode {
+				types = value.Content
+			}
+			for _, item := range types {
+				if !jsonSchemaTypes[scalarValue(item)] {
+					report(schema, key.Line, "%q is not a JSON Schema type", item.Value)
+				}
+			}
+		case "exclusiveMinimum", "exclusiveMaximum":
+			if value.Tag != "!!int" && value.Tag != "!!float" {
+				report(schema, key.Line, "%s must be a number in JSON Schema 2020-12", key.Value)
+			}
+		case "items":
+			if value.Kind == yaml.SequenceNode {
+				report(schema, key.Line, "items must be a single schema, use prefixItems for tuples")
+			}
+		case "prefixItems", "allOf", "anyOf", "oneOf":
+			if value.Kind != yaml.SequenceNode || len(value.Content) == 0 {
+				report(schema, key.Line, "%s must be a non-empty array of schemas", key.Value)
+			}
+		case "$ref", "$dynamicRef", "$anchor", "$dynamicAnchor", "$id", "$schema", "$comment":
+			if value.Kind != yaml.ScalarNode {
+				report(schema, key.Line, "%s must be a string", key.Value)
+			}
+		case "unevaluatedProperties", "unevaluatedItems", "additionalProperties", "not", "contains", "propertyNames", "if", "then", "else":
+			if value.Kind != yaml.MappingNode && value.Tag != "!!bool" {
+				report(schema, key.Line, "%s must be a schema or a boolean", key.Value)
+			}
+		}
+	})
+}
+
+func checkSchemaNullable(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	if !document.is31() {
+		return nil
+	}
+
+	var findings []lintFinding
+	for _, schema := range document.schemas() {
+		if nullable := mappingValue(schema.Node, "nullable"); nullable != nil {
+			findings = append(findings, lintFinding{
+				Message: fmt.Sprintf("schema at %s: nullable was removed in OpenAPI 3.1, add \"null\" to type instead", schema.Pointer),
+				Pointer: schema.Pointer,
+				Line:    nullable.Line,
+			})
+		}
+	}
+	return findings
+}
+
+// validateSchemaValue checks a decoded JSON value against a schema and
+// returns a description of every mismatch. It covers the keywords that
+// describe the shape of a payload, following local references.
+func validateSchemaValue(document *openApiDocument, schema *yaml.Node, value interface{}, path string) []string {
+	schema = document.resolve(schema)
+	if schema == nil || schema.Tag == "!!bool" {
+		if schema != nil && schema.Value == "false" {
+			return []string{fmt.Sprintf("%s is not allowed", path)}
+		}
+		return nil
+	}
+
+	var problems []string
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("%s %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	if value == nil {
+		if scalarValue(mappingValue(schema, "nullable")) == "true" || schemaAllowsType(schema, "null") {
+			return nil
+		}
+	}
+	if types := schemaTypes(schema); len(types) > 0 && !valueMatchesTypes(value, types) {
+		problem("should be %s", strings.Join(types, " or "))
+		return problems
+	}
+
+	if enum := mappingValue(schema, "enum"); enum != nil {
+		found := false
+		for _, item := range enum.Content {
+			if compareValues("==", nodeValue(item), value) {
+				found = true
+			}
+		}
+		if !found {
+			problem("is not one of the enum values")
+		}
+	}
+
+	switch value := value.(type) {
+	case string:
+		if min := numberKeyword(schema, "minLength"); min != nil && float64(len([]rune(value))) < *min {
+			problem("should be at least %v characters long", *min)
+		}
+		if max := numberKeyword(schema, "maxLength"); max != nil && float64(len([]rune(value))) > *max {
+			problem("should be at most %v characters long", *max)
+		}
+		if pattern := scalarValue(mappingValue(schema, "pattern")); pattern != "" {
+			if compiled, err := regexp.Compile(pattern); err == nil && !compiled.MatchString(value) {
+				problem("should match %s", pattern)
+			}
+		}
+	case float64:
+		if min := numberKeyword(schema, "minimum"); min != nil && value < *min {
+			problem("should be at least %v", *min)
+		}
+		if max := numberKeyword(schema, "maximum"); max != nil && value > *max {
+			problem("should be at most %v", *max)
+		}
+	case []interface{}:
+		if min := numberKeyword(schema, "minItems"); min != nil && float64(len(value)) < *min {
+			problem("should have at least %v items", *min)
+		}
+		if max := numberKeyword(schema, "maxItems"); max != nil && float64(len(value)) > *max {
+			problem("should have at most %v items", *max)
+		}
+		prefix := mappingValue(schema, "prefixItems")
+		for i, item := range value {
+			itemPath := fmt.Sprintf("%s/%d", path, i)
+			if prefix != nil && i < len(prefix.Content) {
+				problems = append(problems, validateSchemaValue(document, prefix.Content[i], item, itemPath)...)
+			} else if items := mappingValue(schema, "items"); items != nil {
+				problems = append(problems, validateSchemaValue(document, items, item, itemPath)...)
+			}
+		}
+	case map[string]interface{}:
+		if required := mappingValue(schema, "required"); required != nil {
+			for _, name := range required.Content {
+				if _, ok := value[name.Value]; !ok {
+					problem("is missing the required property %s", name.Value)
+				}
+			}
+		}
+		properties := mappingValue(schema, "properties")
+		additional := mappingValue(schema, "additionalProperties")
+		for name, property := range value {
+			propertyPath := path + "/" + escapePointer(name)
+			if definition := mappingValue(properties, name); definition != nil {
+				problems = append(problems, validateSchemaValue(document, definition, property, propertyPath)...)
+			} else if additional != nil {
+				problems = append(problems, validateSchemaValue(document, additional, property, propertyPath)...)
+			}
+		}
+	}
+
+	if allOf := mappingValue(schema, "allOf"); allOf != nil {
+		for _, sub := range allOf.Content {
+			problems = append(problems, validateSchemaValue(document, sub, value, path)...)
+		}
+	}
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		list := mappingValue(schema, keyword)
+		if list == nil {
+			continue
+		}
+		matching := 0
+		for _, sub := range list.Content {
+			if len(validateSchemaValue(document, sub, value, path)) == 0 {
+				matching++
+			}
+		}
+		if keyword == "oneOf" && matching != 1 {
+			problem("should match exactly one of the oneOf schemas")
+		} else if matching == 0 {
+			problem("should match at least one of the anyOf schemas")
+		}
+	}
+	return problems
+}
+
+func schemaTypes(schema *yaml.Node) []string {
+	node := mappingValue(schema, "type")
+	if node == nil {
+		return nil
+	}
+	if node.Kind == yaml.SequenceNode {
+		types := make([]string, len(node.Content))
+		for i, item := range node.Content {
+			types[i] = item.Value
+		}
+		return types
+	}
+	return []string{node.Value}
+}
+
+func schemaAllowsType(schema *yaml.Node, name string) bool {
+	for _, schemaType := range schemaTypes(schema) {
+		if schemaType == name {
+			return true
+		}
+	}
+	return false
+}
+
+func valueMatchesTypes(value interface{}, types []string) bool {
+	for _, schemaType := range types {
+		switch value := value.(type) {
+		case nil:
+			if schemaType == "null" {
+				return true
+			}
+		case bool:
+			if schemaType == "boolean" {
+				return true
+			}
+		case string:
+			if schemaType == "string" {
+				return true
+			}
+		case float64:
+			if schemaType == "number" || (schemaType == "integer" && value == float64(int64(value))) {
+				return true
+			}
+		case []interface{}:
+			if schemaType == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if schemaType == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func numberKeyword(schema *yaml.Node, keyword string) *float64 {
+	number, ok := nodeValue(mappingValue(schema, keyword)).(float64)
+	if !ok {
+		return nil
+	}
+	return &number
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var serverRules = []lintRule{
+	{Name: "server-urls", Severity: "error", Check: checkServerUrls},
+}
+
+var serverChecks = map[string]int{"dns": 1, "tls": 2, "http": 3}
+
+// documentServers lists the absolute server URLs, replacing server variables
+// by their defaults. Swagger 2.0 documents build them from host and basePath.
+func documentServers(document *openApiDocument) []documentLink {
+	var servers []documentLink
+	if document.isSwagger2() {
+		host := document.lookup("host")
+		if host == nil {
+			return nil
+		}
+		schemes := []string{"https"}
+		if list := document.lookup("schemes"); list != nil && len(list.Content) > 0 {
+			schemes = nil
+			for _, scheme := range list.Content {
+				schemes = append(schemes, scheme.Value)
+			}
+		}
+		for _, scheme := range schemes {
+			url := fmt.Sprintf("%s://%s%s", scheme, host.Value, scalarValue(document.lookup("basePath")))
+			servers = append(servers, documentLink{Url: url, Pointer: "#/host", Line: host.Line})
+		}
+		return servers
+	}
+
+	list := document.lookup("servers")
+	if list == nil {
+		return nil
+	}
+	for i, server := range list.Content {
+		url := scalarValue(mappingValue(server, "url"))
+		eachMapping(mappingValue(server, "variables"), func(name *yaml.Node, variable *yaml.Node) {
+			url = strings.Replace(url, "{"+name.Value+"}", scalarValue(mappingValue(variable, "default")), -1)
+		})
+		if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+			servers = append(servers, documentLink{Url: url, Pointer: fmt.Sprintf("#/servers/%d/url", i), Line: server.Line})
+		}
+	}
+	return servers
+}
+
+// serverSettings are the level and timeout of the server checks, checked by
+// publishDefinition before the rules run.
+func serverSettings(options *commandLineOptions) (int, time.Duration, error) {
+	level, ok := serverChecks[options.ServerCheck]
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid server-check %s, use dns, tls or http", options.ServerCheck)
+	}
+	timeout, err := time.ParseDuration(options.ServerTimeout)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid server-timeout %s", options.ServerTimeout)
+	}
+	return level, timeout, nil
+}
+
+func checkServerUrls(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	level, timeout, err := serverSettings(options)
+	if err != nil {
+		exitAndError(err)
+	}
+
+	var findings []lintFinding
+	for _, server := range documentServers(document) {
+		if problem := probeServer(server.Url, level, timeout); problem != "" {
+			findings = append(findings, lintFinding{
+				Message: fmt.Sprintf("server %s %s", server.Url, problem),
+				Pointer: server.Pointer,
+				Line:    server.Line,
+			})
+		}
+	}
+	return findings
+}
+
+func probeServer(url string, level int, timeout time.Duration) string {
+	parsed, err := neturl.Parse(url)
+	if err != nil {
+		return fmt.Sprintf("is not a valid URL: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if _, err := net.DefaultResolver.LookupHost(ctx, parsed.Hostname()); err != nil {
+		return fmt.Sprintf("does not resolve: %v", err)
+	}
+	if level < serverChecks["tls"] {
+		return ""
+	}
+
+	port := parsed.Port()
+	if port == "" {
+		port = "443"
+		if parsed.Scheme == "http" {
+			port = "80"
+		}
+	}
+	address := net.JoinHostPort(parsed.Hostname(), port)
+	dialer := &net.Dialer{Timeout: timeout}
+	if parsed.Scheme == "https" {
+		connection, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: parsed.Hostname()})
+		if err != nil {
+			return fmt.Sprintf("has no valid TLS: %v", err)
+		}
+		connection.Close()
+	} else {
+		connection, err := dialer.Dial("tcp", address)
+		if err != nil {
+			return fmt.Sprintf("is not reachable: %v", err)
+		}
+		connection.Close()
+	}
+	if level < serverChecks["http"] {
+		return ""
+	}
+
+	httpClient := client()
+	httpClient.Timeout = timeout
+	response, err := httpClient.Get(url)
+	if err != nil {
+		return fmt.Sprintf("is not reachable: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode >= 500 {
+		return fmt.Sprintf("answers with %s", response.Status)
+	}
+	return ""
+}
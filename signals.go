@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// runContext is cancelled on SIGINT or SIGTERM. Every request made through
+// client() is bound to it, so an interruption cancels what is in flight
+// instead of killing the process in the middle of an upload.
+var runContext, cancelRun = context.WithCancel(context.Background())
+
+var interruptedBy os.Signal
+
+var maxTimeExceeded bool
+
+// timeLimit is the limit maxTimeExceeded is about, max time or deadline.
+var timeLimit = "max time"
+
+func handleSignals() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		interruptedBy = <-signals
+		log.Printf("%s received, cancelling (again to quit right away)", interruptedBy)
+		cancelRun()
+		<-signals
+		os.Exit(interruptedExitCode())
+	}()
+}
+
+// limitRunTime cancels the run, like an interruption, once maxTime is over
+// or at the deadline (an RFC 3339 time, the same for every run of a
+// pipeline), whichever comes first, whatever is going on at that moment.
+func limitRunTime(maxTime string, deadline string) {
+	var limit time.Duration
+	reason := ""
+	if maxTime != "" {
+		var err error
+		limit, err = time.ParseDuration(maxTime)
+		if err != nil || limit <= 0 {
+			exitAndError(fmt.Sprintf("invalid max-time %s", maxTime))
+		}
+		reason = fmt.Sprintf("the max time of %s is over", limit)
+	}
+	if deadline != "" {
+		at, err := time.Parse(time.RFC3339, deadline)
+		if err != nil {
+			exitAndError(fmt.Sprintf("invalid deadline %s, use a time as 2006-01-02T15:04:05Z", deadline))
+		}
+		if until := time.Until(at); reason == "" || until < limit {
+			limit, reason = until, fmt.Sprintf("the deadline %s is over", deadline)
+			timeLimit = "deadline"
+		}
+	}
+	if reason == "" {
+		return
+	}
+	time.AfterFunc(limit, func() {
+		maxTimeExceeded = true
+		log.Printf("%s, cancelling", reason)
+		cancelRun()
+	})
+}
+
+func interrupted() bool {
+	return runContext.Err() != nil
+}
+
+// interruptedExitCode follows the shell convention of 128 plus the signal,
+// and the one of timeout(1) when the max time is over.
+func interruptedExitCode() int {
+	if maxTimeExceeded {
+		return 124
+	}
+	if interruptedBy == syscall.SIGTERM {
+		return 128 + 15
+	}
+	return 128 + 2
+}
+
+type cancellableTransport struct {
+	transport http.RoundTripper
+}
+
+func (cancellable cancellableTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	return cancellable.transport.RoundTrip(request.WithContext(runContext))
+}
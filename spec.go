@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	neturl "net/url"
+	"strings"
+)
+
+// specHandler is a kind of definition swaggergo knows how to check and
+// publish. Supporting a new kind means adding a handler to specHandlers;
+// publishing, linting and the commands built on them go through
+// detectSpecHandler.
+type specHandler interface {
+	// name is shown in messages, as "OpenAPI 3.1".
+	name() string
+	// specType is the --spec-type of this kind, as openapi.
+	specType() string
+	// detect reports whether the parsed document is of this kind.
+	detect(document *openApiDocument) bool
+	// validation are the checks of the structure the specification requires,
+	// run before every publication whatever the options.
+	validation() []lintRule
+	// rules are the other built-in checks of this kind enabled by the
+	// options. The ruleset and the rules of the config are added to them.
+	rules(options *commandLineOptions) []lintRule
+	// publishQuery holds the parameters SwaggerHub needs to create a version.
+	publishQuery(document *openApiDocument, options *commandLineOptions) neturl.Values
+}
+
+// specHandlers are tried in order, the first one detecting the document
+// handles it.
+var specHandlers = []specHandler{
+	openApiHandler{version: "2.0"},
+	openApiHandler{version: "3.0"},
+	openApiHandler{version: "3.1"},
+	asyncApiHandler{version: "2"},
+	asyncApiHandler{version: "3"},
+}
+
+// specTypeNames name the kinds of definition in messages, by --spec-type.
+var specTypeNames = map[string]string{"openapi": "OpenAPI", "asyncapi": "AsyncAPI"}
+
+// detectSpecHandler finds the handler of the document, among the ones of
+// specType when it's given.
+func detectSpecHandler(document *openApiDocument, specType string) (specHandler, error) {
+	for _, handler := range specHandlers {
+		if (specType == "" || handler.specType() == specType) && handler.detect(document) {
+			return handler, nil
+		}
+	}
+	switch specType {
+	case "openapi":
+		return nil, fmt.Errorf("%s is not an OpenAPI definition, it has no swagger or openapi version", document.Path)
+	case "asyncapi":
+		return nil, fmt.Errorf("%s is not an AsyncAPI definition, it has no asyncapi version", document.Path)
+	}
+	return nil, fmt.Errorf("%s is not a supported definition, it has no swagger, openapi or asyncapi version", document.Path)
+}
+
+// lintSpec runs the built-in checks of the kind of the document followed by
+// the given rules.
+func lintSpec(document *openApiDocument, rules []lintRule, options *commandLineOptions) ([]lintFinding, error) {
+	handler, err := detectSpecHandler(document, options.SpecType)
+	if err != nil {
+		return nil, err
+	}
+	return lintDocument(document, append(append(handler.validation(), handler.rules(options)...), rules...), options), nil
+}
+
+// openApiHandler handles Swagger 2.0 and OpenAPI 3.x by major and minor
+// version. The schema checks pick the JSON Schema dialect of the version.
+type openApiHandler struct {
+	version string
+}
+
+func (handler openApiHandler) name() string {
+	if handler.version == "2.0" {
+		return "Swagger 2.0"
+	}
+	return "OpenAPI " + handler.version
+}
+
+func (handler openApiHandler) specType() string {
+	return "openapi"
+}
+
+func (handler openApiHandler) detect(document *openApiDocument) bool {
+	if handler.version == "2.0" {
+		return scalarValue(document.lookup("swagger")) == "2.0"
+	}
+	version := scalarValue(document.lookup("openapi"))
+	return version == handler.version || strings.HasPrefix(version, handler.version+".")
+}
+
+func (handler openApiHandler) validation() []lintRule {
+	return validationRules
+}
+
+func (handler openApiHandler) rules(options *commandLineOptions) []lintRule {
+	var rules []lintRule
+	if options.ErrorSchema != "" {
+		rules = append(rules, errorResponseRules...)
+	}
+	if options.CheckSchemas {
+		rules = append(rules, schemaRules...)
+	}
+	if options.CheckLinks {
+		rules = append(rules, linkRules...)
+	}
+	if options.CheckServers {
+		rules = append(rules, serverRules...)
+	}
+	return rules
+}
+
+// publishQuery sets the OAS level of the version from the swagger or openapi
+// field of the document, as 2.0 or 3.0.3. --oas (or the config and profile)
+// still overrides it, with a warning when it's of another major and minor
+// version than the document.
+func (handler openApiHandler) publishQuery(document *openApiDocument, options *commandLineOptions) neturl.Values {
+	oas := documentOas(document)
+	if options.Oas != "" {
+		if oasLevel(options.Oas) != oasLevel(oas) {
+			log.Printf("%s is %s but it's published as OAS %s, as --oas or the config say", document.Path, handler.name(), options.Oas)
+		}
+		oas = options.Oas
+	}
+	query := neturl.Values{}
+	query.Set("oas", oas)
+	return query
+}
+
+// documentOas is the version in the swagger or openapi field of a document.
+func documentOas(document *openApiDocument) string {
+	if document.isSwagger2() {
+		return scalarValue(document.lookup("swagger"))
+	}
+	return scalarValue(document.lookup("openapi"))
+}
+
+// oasLevel is the major and minor version of an OAS version, 3.0 for 3.0.3.
+func oasLevel(version string) string {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return version
+	}
+	return parts[0] + "." + parts[1]
+}
@@ -0,0 +1,670 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// spectralOasRules are the commonly used rules of Spectral's "spectral:oas"
+// ruleset, under the same names and default severities.
+var spectralOasRules = []lintRule{
+	{Name: "operation-operationId", Severity: "warning", Check: checkOperationOperationId},
+	{Name: "operation-operationId-unique", Severity: "error", Check: checkOperationOperationIdUnique},
+	{Name: "operation-description", Severity: "warning", Check: checkOperationDescription},
+	{Name: "operation-tags", Severity: "warning", Check: checkOperationTags},
+	{Name: "operation-tag-defined", Severity: "warning", Check: checkOperationTagDefined},
+	{Name: "operation-success-response", Severity: "warning", Check: checkOperationSuccessResponse},
+	{Name: "path-params", Severity: "error", Check: checkPathParams},
+	{Name: "path-keys-no-trailing-slash", Severity: "warning", Check: checkPathKeysNoTrailingSlash},
+	{Name: "info-contact", Severity: "warning", Check: checkInfoContact},
+	{Name: "info-description", Severity: "warning", Check: checkInfoDescription},
+	{Name: "oas3-api-servers", Severity: "warning", Check: checkOas3ApiServers},
+}
+
+var spectralSeverities = map[string]string{
+	"error": "error", "0": "error",
+	"warn": "warning", "1": "warning",
+	"info": "info", "2": "info",
+	"hint": "hint", "3": "hint",
+}
+
+var pathTemplate = regexp.MustCompile(`{([^}]+)}`)
+
+type spectralThen struct {
+	Field           string                 `yaml:"field"`
+	Function        string                 `yaml:"function"`
+	FunctionOptions map[string]interface{} `yaml:"functionOptions"`
+}
+
+type spectralRule struct {
+	Description string
+	Message     string
+	Severity    string
+	Formats     []string
+	Given       []string
+	Then        []spectralThen
+}
+
+// loadSpectralRuleset reads a .spectral.yaml file: "extends" enables the
+// spectral:oas rules swaggergo implements, and "rules" can change their
+// severity, turn them off or add custom rules using the core functions. The
+// severity of swaggergo's own checks, as oas-responses, can be changed too,
+// returned as overrides by rule name for lintDocument.
+func loadSpectralRuleset(path string) ([]lintRule, map[string]string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't read the ruleset %s", path)
+	}
+
+	var ruleset struct {
+		Extends yaml.Node            `yaml:"extends"`
+		Rules   map[string]yaml.Node `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(content, &ruleset); err != nil {
+		return nil, nil, fmt.Errorf("can't parse the ruleset %s: %v", path, err)
+	}
+
+	enabled := map[string]lintRule{}
+	overrides := map[string]string{}
+	if spectralExtendsOas(&ruleset.Extends) {
+		for _, rule := range spectralOasRules {
+			enabled[rule.Name] = rule
+		}
+	}
+
+	names := make([]string, 0, len(ruleset.Rules))
+	for name := range ruleset.Rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		definition := ruleset.Rules[name]
+		if definition.Kind == yaml.ScalarNode {
+			if err := overrideSpectralRule(enabled, overrides, name, definition.Value); err != nil {
+				return nil, nil, fmt.Errorf("%s: %v", path, err)
+			}
+			continue
+		}
+
+		rule, err := parseSpectralRule(&definition)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: rule %s: %v", path, name, err)
+		}
+		if rule.Severity == "off" {
+			delete(enabled, name)
+			continue
+		}
+		if unsupported := unsupportedSpectralFunction(rule); unsupported != "" {
+			log.Printf("%s: skipping rule %s, function %s is not supported", path, name, unsupported)
+			continue
+		}
+		enabled[name] = lintRule{Name: name, Severity: rule.Severity, Check: spectralRuleCheck(rule)}
+	}
+
+	var rules []lintRule
+	for _, rule := range spectralOasRules {
+		if enabled[rule.Name].Check != nil {
+			rules = append(rules, enabled[rule.Name])
+			delete(enabled, rule.Name)
+		}
+	}
+	for _, name := range names {
+		if rule, ok := enabled[name]; ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, overrides, nil
+}
+
+func spectralExtendsOas(extends *yaml.Node) bool {
+	var items []*yaml.Node
+	switch extends.Kind {
+	case yaml.ScalarNode:
+		items = []*yaml.Node{extends}
+	case yaml.SequenceNode:
+		items = extends.Content
+	}
+
+	for _, item := range items {
+		if item.Kind == yaml.SequenceNode && len(item.Content) == 2 {
+			if item.Content[0].Value == "spectral:oas" && item.Content[1].Value != "off" {
+				return true
+			}
+		} else if item.Value == "spectral:oas" {
+			return true
+		}
+	}
+	return false
+}
+
+func overrideSpectralRule(enabled map[string]lintRule, overrides map[string]string, name string, value string) error {
+	rule, ok := enabled[name]
+	switch {
+	case value == "off" || value == "false":
+		delete(enabled, name)
+		if swaggerGoRule(name) {
+			overrides[name] = "off"
+		}
+	case !ok && value == "true":
+		for _, builtin := range spectralOasRules {
+			if builtin.Name == name {
+				enabled[name] = builtin
+			}
+		}
+	case !ok && swaggerGoRule(name) && spectralSeverities[value] != "":
+		overrides[name] = spectralSeverities[value]
+	case !ok && swaggerGoRule(name):
+		return fmt.Errorf("rule %s has an invalid severity %q", name, value)
+	case !ok:
+		return fmt.Errorf("rule %s is not a spectral:oas or swaggergo rule", name)
+	case value == "true":
+	case spectralSeverities[value] != "":
+		rule.Severity = spectralSeverities[value]
+		enabled[name] = rule
+	default:
+		return fmt.Errorf("rule %s has an invalid severity %q", name, value)
+	}
+	return nil
+}
+
+// swaggerGoRule tells if name is one of the checks swaggergo runs besides
+// the ruleset.
+func swaggerGoRule(name string) bool {
+	for _, rules := range [][]lintRule{validationRules, errorResponseRules, schemaRules, linkRules, serverRules} {
+		for _, rule := range rules {
+			if rule.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func parseSpectralRule(definition *yaml.Node) (spectralRule, error) {
+	var raw struct {
+		Description string    `yaml:"description"`
+		Message     string    `yaml:"message"`
+		Severity    string    `yaml:"severity"`
+		Formats     []string  `yaml:"formats"`
+		Given       yaml.Node `yaml:"given"`
+		Then        yaml.Node `yaml:"then"`
+	}
+	if err := definition.Decode(&raw); err != nil {
+		return spectralRule{}, err
+	}
+
+	rule := spectralRule{Description: raw.Description, Message: raw.Message, Formats: raw.Formats, Severity: "warning"}
+	if raw.Severity == "off" {
+		rule.Severity = "off"
+	} else if raw.Severity != "" {
+		if rule.Severity = spectralSeverities[raw.Severity]; rule.Severity == "" {
+			return rule, fmt.Errorf("invalid severity %q", raw.Severity)
+		}
+	}
+
+	if err := decodeOneOrMany(&raw.Given, &rule.Given); err != nil || len(rule.Given) == 0 {
+		return rule, fmt.Errorf("given must be a JSONPath or a list of them")
+	}
+	if err := decodeOneOrMany(&raw.Then, &rule.Then); err != nil || len(rule.Then) == 0 {
+		return rule, fmt.Errorf("then must be an object or a list of them")
+	}
+	for _, given := range rule.Given {
+		if _, err := parseJsonPath(given); err != nil {
+			return rule, err
+		}
+	}
+	return rule, nil
+}
+
+func decodeOneOrMany(node *yaml.Node, out interface{}) error {
+	if node.Kind == yaml.SequenceNode {
+		return node.Decode(out)
+	}
+	list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{node}}
+	return list.Decode(out)
+}
+
+func unsupportedSpectralFunction(rule spectralRule) string {
+	for _, then := range rule.Then {
+		if spectralFunctions[then.Function] == nil {
+			return then.Function
+		}
+	}
+	return ""
+}
+
+func spectralRuleCheck(rule spectralRule) func(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	return func(document *openApiDocument, options *commandLineOptions) []lintFinding {
+		if !spectralFormatMatches(document, rule.Formats) {
+			return nil
+		}
+
+		var findings []lintFinding
+		for _, given := range rule.Given {
+			matches, err := queryJsonPath(document.Root, given)
+			if err != nil {
+				continue
+			}
+			for _, match := range matches {
+				for _, then := range rule.Then {
+					findings = append(findings, applySpectralThen(rule, then, match)...)
+				}
+			}
+		}
+		return findings
+	}
+}
+
+func spectralFormatMatches(document *openApiDocument, formats []string) bool {
+	if len(formats) == 0 {
+		return true
+	}
+	version := scalarValue(document.lookup("openapi"))
+	for _, format := range formats {
+		switch format {
+		case "oas2":
+			if document.isSwagger2() {
+				return true
+			}
+		case "oas3":
+			if version != "" {
+				return true
+			}
+		case "oas3_0", "oas3.0":
+			if strings.HasPrefix(version, "3.0") {
+				return true
+			}
+		case "oas3_1", "oas3.1":
+			if strings.HasPrefix(version, "3.1") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func applySpectralThen(rule spectralRule, then spectralThen, match jsonPathMatch) []lintFinding {
+	targets := []jsonPathMatch{match}
+	switch {
+	case then.Field == "@key":
+		// as in Spectral, the function gets every property name of the
+		// given object, e.g. each path of $.paths
+		targets = nil
+		eachMapping(match.Node, func(key *yaml.Node, _ *yaml.Node) {
+			path := append(append([]string{}, match.Path...), key.Value)
+			targets = append(targets, jsonPathMatch{Node: key, Key: key.Value, Path: path, Line: key.Line})
+		})
+	case strings.HasPrefix(then.Field, "$"):
+		targets, _ = queryJsonPath(match.Node, then.Field)
+		for i := range targets {
+			targets[i].Path = append(append([]string{}, match.Path...), targets[i].Path...)
+		}
+	case then.Field != "":
+		target := jsonPathMatch{Node: match.Node, Path: match.Path, Line: match.Line}
+		for _, key := range strings.Split(then.Field, ".") {
+			target.Key = key
+			target.Path = append(append([]string{}, target.Path...), key)
+			target.Node = mappingValue(target.Node, key)
+		}
+		if target.Node != nil {
+			target.Line = target.Node.Line
+		}
+		targets = []jsonPathMatch{target}
+	}
+
+	var findings []lintFinding
+	for _, target := range targets {
+		problem := spectralFunctions[then.Function](target.Node, then.FunctionOptions)
+		if problem == "" {
+			continue
+		}
+		message := problem
+		if rule.Message != "" {
+			message = strings.NewReplacer(
+				"{{error}}", problem,
+				"{{description}}", rule.Description,
+				"{{property}}", target.Key,
+				"{{path}}", target.pointer(),
+				"{{value}}", scalarValue(target.Node),
+			).Replace(rule.Message)
+		}
+		if target.Key != "" && rule.Message == "" {
+			message = fmt.Sprintf("%q %s", target.Key, problem)
+		}
+		findings = append(findings, lintFinding{Message: message, Pointer: target.pointer(), Line: target.Line})
+	}
+	return findings
+}
+
+// spectralFunctions implements Spectral's core functions. They return a
+// description of the problem, or an empty string when the value is valid.
+var spectralFunctions = map[string]func(value *yaml.Node, options map[string]interface{}) string{
+	"truthy": func(value *yaml.Node, options map[string]interface{}) string {
+		if !isTruthy(value) {
+			return "property must be truthy"
+		}
+		return ""
+	},
+	"falsy": func(value *yaml.Node, options map[string]interface{}) string {
+		if isTruthy(value) {
+			return "property must be falsy"
+		}
+		return ""
+	},
+	"defined": func(value *yaml.Node, options map[string]interface{}) string {
+		if value == nil {
+			return "property must be defined"
+		}
+		return ""
+	},
+	"undefined": func(value *yaml.Node, options map[string]interface{}) string {
+		if value != nil {
+			return "property must be undefined"
+		}
+		return ""
+	},
+	"pattern": func(value *yaml.Node, options map[string]interface{}) string {
+		if value == nil || value.Kind != yaml.ScalarNode {
+			return ""
+		}
+		if pattern, ok := options["match"].(string); ok && !spectralRegexp(pattern).MatchString(value.Value) {
+			return fmt.Sprintf("must match the pattern %q", pattern)
+		}
+		if pattern, ok := options["notMatch"].(string); ok && spectralRegexp(pattern).MatchString(value.Value) {
+			return fmt.Sprintf("must not match the pattern %q", pattern)
+		}
+		return ""
+	},
+	"enumeration": func(value *yaml.Node, options map[string]interface{}) string {
+		if value == nil || value.Kind != yaml.ScalarNode {
+			return ""
+		}
+		values, _ := options["values"].([]interface{})
+		allowed := make([]string, len(values))
+		for i, item := range values {
+			if allowed[i] = fmt.Sprint(item); allowed[i] == value.Value {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be equal to one of the allowed values: %s", strings.Join(allowed, ", "))
+	},
+	"length": func(value *yaml.Node, options map[string]interface{}) string {
+		if value == nil {
+			return ""
+		}
+		length := len(value.Content)
+		switch {
+		case value.Kind == yaml.MappingNode:
+			length = len(value.Content) / 2
+		case value.Tag == "!!int" || value.Tag == "!!float":
+			number, _ := strconv.ParseFloat(value.Value, 64)
+			length = int(number)
+		case value.Kind == yaml.ScalarNode:
+			length = len([]rune(value.Value))
+		}
+		if min, ok := options["min"].(int); ok && length < min {
+			return fmt.Sprintf("must not be shorter than %d", min)
+		}
+		if max, ok := options["max"].(int); ok && length > max {
+			return fmt.Sprintf("must not be longer than %d", max)
+		}
+		return ""
+	},
+	"casing": func(value *yaml.Node, options map[string]interface{}) string {
+		if value == nil || value.Kind != yaml.ScalarNode {
+			return ""
+		}
+		casing, _ := options["type"].(string)
+		pattern, ok := spectralCasings[casing]
+		if ok && !pattern.MatchString(value.Value) {
+			return fmt.Sprintf("must be %s case", casing)
+		}
+		return ""
+	},
+}
+
+var spectralCasings = map[string]*regexp.Regexp{
+	"flat":   regexp.MustCompile(`^[a-z][a-z0-9]*$`),
+	"camel":  regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`),
+	"pascal": regexp.MustCompile(`^[A-Z][a-zA-Z0-9]*$`),
+	"kebab":  regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`),
+	"cobol":  regexp.MustCompile(`^[A-Z][A-Z0-9]*(-[A-Z0-9]+)*$`),
+	"snake":  regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`),
+	"macro":  regexp.MustCompile(`^[A-Z][A-Z0-9]*(_[A-Z0-9]+)*$`),
+}
+
+// spectralRegexp accepts both plain patterns and the /pattern/flags form.
+func spectralRegexp(pattern string) *regexp.Regexp {
+	if strings.HasPrefix(pattern, "/") && strings.LastIndex(pattern, "/") > 0 {
+		end := strings.LastIndex(pattern, "/")
+		flags := pattern[end+1:]
+		pattern = pattern[1:end]
+		if strings.Contains(flags, "i") {
+			pattern = "(?i)" + pattern
+		}
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return regexp.MustCompile(regexp.QuoteMeta(pattern))
+	}
+	return compiled
+}
+
+func isTruthy(node *yaml.Node) bool {
+	if node == nil {
+		return false
+	}
+	if node.Kind != yaml.ScalarNode {
+		return true
+	}
+	switch node.Tag {
+	case "!!null":
+		return false
+	case "!!bool":
+		return node.Value == "true"
+	case "!!int", "!!float":
+		number, _ := strconv.ParseFloat(node.Value, 64)
+		return number != 0
+	}
+	return node.Value != ""
+}
+
+func operationFinding(operation openApiOperation, message string, keys ...string) lintFinding {
+	line := operation.Node.Line
+	if len(keys) > 0 {
+		if node := mappingValue(operation.Node, keys[0]); node != nil {
+			line = node.Line
+		}
+	}
+	return lintFinding{
+		Message: fmt.Sprintf("%s %s", operation, message),
+		Pointer: operation.pointer(keys...),
+		Line:    line,
+	}
+}
+
+func checkOperationOperationId(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	var findings []lintFinding
+	for _, operation := range document.operations() {
+		if scalarValue(mappingValue(operation.Node, "operationId")) == "" {
+			findings = append(findings, operationFinding(operation, "must have an operationId"))
+		}
+	}
+	return findings
+}
+
+func checkOperationOperationIdUnique(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	var findings []lintFinding
+	seen := map[string]openApiOperation{}
+	for _, operation := range document.operations() {
+		operationId := scalarValue(mappingValue(operation.Node, "operationId"))
+		if operationId == "" {
+			continue
+		}
+		if first, ok := seen[operationId]; ok {
+			findings = append(findings, operationFinding(operation, fmt.Sprintf("reuses the operationId %s of %s", operationId, first), "operationId"))
+			continue
+		}
+		seen[operationId] = operation
+	}
+	return findings
+}
+
+func checkOperationDescription(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	var findings []lintFinding
+	for _, operation := range document.operations() {
+		if scalarValue(mappingValue(operation.Node, "description")) == "" {
+			findings = append(findings, operationFinding(operation, "must have a description"))
+		}
+	}
+	return findings
+}
+
+func checkOperationTags(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	var findings []lintFinding
+	for _, operation := range document.operations() {
+		if tags := mappingValue(operation.Node, "tags"); tags == nil || len(tags.Content) == 0 {
+			findings = append(findings, operationFinding(operation, "must have at least one tag"))
+		}
+	}
+	return findings
+}
+
+func checkOperationTagDefined(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	declared := map[string]bool{}
+	if tags := document.lookup("tags"); tags != nil {
+		for _, tag := range tags.Content {
+			declared[scalarValue(mappingValue(tag, "name"))] = true
+		}
+	}
+
+	var findings []lintFinding
+	for _, operation := range document.operations() {
+		tags := mappingValue(operation.Node, "tags")
+		if tags == nil {
+			continue
+		}
+		for _, tag := range tags.Content {
+			if !declared[tag.Value] {
+				finding := operationFinding(operation, fmt.Sprintf("uses the tag %s which is not declared in the global tags", tag.Value), "tags")
+				finding.Line = tag.Line
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings
+}
+
+func checkOperationSuccessResponse(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	var findings []lintFinding
+	for _, operation := range document.operations() {
+		success := false
+		eachMapping(mappingValue(operation.Node, "responses"), func(code *yaml.Node, _ *yaml.Node) {
+			if strings.HasPrefix(code.Value, "2") || strings.HasPrefix(code.Value, "3") {
+				success = true
+			}
+		})
+		if !success {
+			findings = append(findings, operationFinding(operation, "must have at least one 2xx or 3xx response", "responses"))
+		}
+	}
+	return findings
+}
+
+func checkPathParams(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	var findings []lintFinding
+	eachMapping(document.lookup("paths"), func(path *yaml.Node, item *yaml.Node) {
+		pathLevel := pathParameterNames(document, mappingValue(item, "parameters"))
+		for _, method := range httpMethods {
+			operation := mappingValue(item, method)
+			if operation == nil {
+				continue
+			}
+			defined := pathParameterNames(document, mappingValue(operation, "parameters"))
+			for name := range pathLevel {
+				defined[name] = true
+			}
+			for _, placeholder := range pathTemplate.FindAllStringSubmatch(path.Value, -1) {
+				if !defined[placeholder[1]] {
+					findings = append(findings, lintFinding{
+						Message: fmt.Sprintf("%s %s doesn't define the path parameter %s", strings.ToUpper(method), path.Value, placeholder[1]),
+						Pointer: joinPointer("paths", path.Value, method),
+						Line:    path.Line,
+					})
+				}
+			}
+		}
+	})
+	return findings
+}
+
+func pathParameterNames(document *openApiDocument, parameters *yaml.Node) map[string]bool {
+	names := map[string]bool{}
+	if parameters == nil {
+		return names
+	}
+	for _, parameter := range parameters.Content {
+		parameter = document.resolve(parameter)
+		if scalarValue(mappingValue(parameter, "in")) == "path" {
+			names[scalarValue(mappingValue(parameter, "name"))] = true
+		}
+	}
+	return names
+}
+
+func checkPathKeysNoTrailingSlash(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	var findings []lintFinding
+	eachMapping(document.lookup("paths"), func(path *yaml.Node, _ *yaml.Node) {
+		if len(path.Value) > 1 && strings.HasSuffix(path.Value, "/") {
+			findings = append(findings, lintFinding{
+				Message: fmt.Sprintf("path %s must not end with a slash", path.Value),
+				Pointer: joinPointer("paths", path.Value),
+				Line:    path.Line,
+			})
+		}
+	})
+	return findings
+}
+
+func rootFinding(document *openApiDocument, message string, keys ...string) lintFinding {
+	line := document.Root.Line
+	for i := len(keys); i > 0; i-- {
+		if node := document.lookup(keys[:i]...); node != nil {
+			line = node.Line
+			break
+		}
+	}
+	return lintFinding{Message: message, Pointer: joinPointer(keys...), Line: line}
+}
+
+func checkInfoContact(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	if document.lookup("info", "contact") == nil {
+		return []lintFinding{rootFinding(document, "info must have a contact", "info", "contact")}
+	}
+	return nil
+}
+
+func checkInfoDescription(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	if scalarValue(document.lookup("info", "description")) == "" {
+		return []lintFinding{rootFinding(document, "info must have a description", "info", "description")}
+	}
+	return nil
+}
+
+func checkOas3ApiServers(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	if document.isSwagger2() || document.isAsyncApi() {
+		return nil
+	}
+	if servers := document.lookup("servers"); servers == nil || len(servers.Content) == 0 {
+		return []lintFinding{rootFinding(document, "servers must be present and non-empty", "servers")}
+	}
+	return nil
+}
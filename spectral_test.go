@@ -0,0 +1,138 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadSpectralRuleset(t *testing.T) {
+	var oasRules []string
+	for _, rule := range spectralOasRules {
+		oasRules = append(oasRules, rule.Name+" "+rule.Severity)
+	}
+	withoutTags := []string{}
+	for _, rule := range oasRules {
+		if rule != "operation-tags warning" {
+			withoutTags = append(withoutTags, rule)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		ruleset string
+		want    []string
+	}{
+		{"extends", "extends: spectral:oas\n", oasRules},
+		{"extends as a list", "extends: [[spectral:oas, recommended]]\n", oasRules},
+		{"extends turned off", "extends: [[spectral:oas, off]]\n", nil},
+		{"rule off", "extends: spectral:oas\nrules:\n  operation-tags: off\n", withoutTags},
+		{"severity", "extends: spectral:oas\nrules:\n  info-contact: error\n", replaceRule(oasRules, "info-contact warning", "info-contact error")},
+		{"enabled without extends", "rules:\n  info-contact: true\n", []string{"info-contact warning"}},
+		{"custom rule", "rules:\n  title-required:\n    given: $.info\n    then: {field: title, function: truthy}\n", []string{"title-required warning"}},
+		{"custom rule severity", "rules:\n  title-required:\n    severity: hint\n    given: $.info\n    then: {field: title, function: truthy}\n", []string{"title-required hint"}},
+		{"unsupported function", "rules:\n  custom:\n    given: $.info\n    then: {function: oasExample}\n", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules, _, err := loadSpectralRuleset(writeRuleset(t, test.ruleset))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, rule := range rules {
+				got = append(got, rule.Name+" "+rule.Severity)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadSpectralRulesetErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		ruleset string
+	}{
+		{"unknown rule", "rules:\n  not-a-rule: warn\n"},
+		{"invalid severity", "extends: spectral:oas\nrules:\n  info-contact: loud\n"},
+		{"invalid custom severity", "rules:\n  custom:\n    severity: loud\n    given: $.info\n    then: {function: truthy}\n"},
+		{"missing given", "rules:\n  custom:\n    then: {function: truthy}\n"},
+		{"invalid given", "rules:\n  custom:\n    given: info\n    then: {function: truthy}\n"},
+		{"not yaml", "rules: [\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := loadSpectralRuleset(writeRuleset(t, test.ruleset)); err == nil {
+				t.Error("the ruleset loaded without an error")
+			}
+		})
+	}
+	if _, _, err := loadSpectralRuleset(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("a missing ruleset loaded without an error")
+	}
+}
+
+func TestSpectralCustomRuleFindings(t *testing.T) {
+	rules, _, err := loadSpectralRuleset(writeRuleset(t, "rules:\n  title-required:\n    message: the API needs a title\n    given: $.info\n    then: {field: title, function: truthy}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for content, want := range map[string]int{
+		"openapi: 3.0.0\ninfo: {title: Pets, version: \"1.0\"}\npaths: {}\n": 0,
+		"openapi: 3.0.0\ninfo: {version: \"1.0\"}\npaths: {}\n":              1,
+	} {
+		document, err := parseOpenApiDocument("openapi.yml", []byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if findings := rules[0].Check(document, &commandLineOptions{}); len(findings) != want {
+			t.Errorf("got %d findings, want %d, for %s", len(findings), want, content)
+		}
+	}
+}
+
+func TestLoadSpectralRulesetOverrides(t *testing.T) {
+	_, overrides, err := loadSpectralRuleset(writeRuleset(t, "rules:\n  error-response-schema: warn\n  server-urls: off\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"error-response-schema": "warning", "server-urls": "off"}; !reflect.DeepEqual(overrides, want) {
+		t.Errorf("got %v, want %v", overrides, want)
+	}
+
+	// another ruleset, as the one of another definition of a batch, starts
+	// without them
+	if _, overrides, _ = loadSpectralRuleset(writeRuleset(t, "extends: spectral:oas\n")); len(overrides) != 0 {
+		t.Errorf("got the overrides %v", overrides)
+	}
+
+	document, err := parseOpenApiDocument("openapi.yml", []byte("openapi: 3.0.0\ninfo: {title: Pets, version: \"1.0\"}\npaths:\n  /pets:\n    get:\n      responses: {\"400\": {description: bad}}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings := lintDocument(document, errorResponseRules[:1], &commandLineOptions{ErrorSchema: "Error", ruleOverrides: map[string]string{"error-response-schema": "warning"}})
+	if len(findings) != 1 || findings[0].Severity != "warning" {
+		t.Errorf("got %+v, want a warning", findings)
+	}
+}
+
+func writeRuleset(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), ".spectral.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func replaceRule(rules []string, old string, new string) []string {
+	replaced := append([]string{}, rules...)
+	for i, rule := range replaced {
+		if rule == old {
+			replaced[i] = new
+		}
+	}
+	return replaced
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+func enableAnsi(file *os.File) bool {
+	return true
+}
+
+// setEcho turns the echo of what is typed on the terminal on or off, with
+// stty as there's no terminal package.
+func setEcho(file *os.File, enabled bool) error {
+	mode := "echo"
+	if !enabled {
+		mode = "-echo"
+	}
+	command := exec.Command("stty", mode)
+	command.Stdin = file
+	return command.Run()
+}
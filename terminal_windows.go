@@ -0,0 +1,49 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+const enableVirtualTerminalProcessing = 0x0004
+
+var setConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// enableAnsi turns on the handling of ANSI escape sequences, off by default
+// in Windows consoles. Consoles that can't do it (before Windows 10) get the
+// plain output.
+func enableAnsi(file *os.File) bool {
+	var mode uint32
+	handle := syscall.Handle(file.Fd())
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return false
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	result, _, _ := setConsoleMode.Call(uintptr(handle), uintptr(mode|enableVirtualTerminalProcessing))
+	return result != 0
+}
+
+const enableEchoInput = 0x0004
+
+// setEcho turns the echo of what is typed in the console on or off.
+func setEcho(file *os.File, enabled bool) error {
+	var mode uint32
+	handle := syscall.Handle(file.Fd())
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return err
+	}
+	if enabled {
+		mode |= enableEchoInput
+	} else {
+		mode &^= enableEchoInput
+	}
+	if result, _, err := setConsoleMode.Call(uintptr(handle), uintptr(mode)); result == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ratePattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([KMG]i?B|B)?(?:/s)?$`)
+
+var rateUnits = map[string]float64{
+	"": 1, "B": 1,
+	"KB": 1e3, "MB": 1e6, "GB": 1e9,
+	"KiB": 1 << 10, "MiB": 1 << 20, "GiB": 1 << 30,
+}
+
+// parseRate reads rates like 1MiB/s, 500KB/s or 100000 (bytes per second).
+func parseRate(rate string) (int, error) {
+	match := ratePattern.FindStringSubmatch(strings.TrimSpace(rate))
+	if match == nil {
+		return 0, fmt.Errorf("invalid rate %s, use something like 1MiB/s", rate)
+	}
+	value, _ := strconv.ParseFloat(match[1], 64)
+	bytes := int(value * rateUnits[match[2]])
+	if bytes <= 0 {
+		return 0, fmt.Errorf("invalid rate %s, use something like 1MiB/s", rate)
+	}
+	return bytes, nil
+}
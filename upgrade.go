@@ -0,0 +1,802 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parameterSchemaKeys are the keys of a Swagger 2.0 parameter, header or
+// items object that move into its schema in OpenAPI 3.
+var parameterSchemaKeys = []string{"type", "format", "items", "default", "maximum", "exclusiveMaximum", "minimum", "exclusiveMinimum", "maxLength", "minLength", "pattern", "maxItems", "minItems", "uniqueItems", "enum", "multipleOf"}
+
+// oauth2Flows are the OpenAPI 3 names of the Swagger 2.0 OAuth2 flows.
+var oauth2Flows = map[string]string{
+	"implicit":    "implicit",
+	"password":    "password",
+	"application": "clientCredentials",
+	"accessCode":  "authorizationCode",
+}
+
+// upgradeDefinition writes a Swagger 2.0 or OpenAPI 3.0 definition as
+// OpenAPI at the target level, 3.0 or 3.1, in the type it's written in.
+// Swagger 2.0 is upgraded locally, and the definitions the local upgrade
+// can't handle are sent to converterUrl unless converter is local; service
+// sends them all. The service answers in JSON, so the type of the result is
+// returned with it.
+func upgradeDefinition(definitionPath string, content []byte, definitionType string, targetOas string, converter string, converterUrl string) ([]byte, string, error) {
+	target := oasLevel(targetOas)
+	if target == "3" {
+		target = "3.0"
+	}
+	if target != "3.0" && target != "3.1" {
+		return nil, "", fmt.Errorf("unknown target OAS %s, use 3.0 or 3.1", targetOas)
+	}
+	document, err := parseOpenApiDocument(definitionPath, content)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if document.isSwagger2() {
+		if content, definitionType, err = upgradeSwagger2Definition(definitionPath, content, definitionType, document, converter, converterUrl); err != nil {
+			return nil, "", err
+		}
+		if target == "3.0" {
+			return content, definitionType, nil
+		}
+		if document, err = parseOpenApiDocument(definitionPath, content); err != nil {
+			return nil, "", err
+		}
+	}
+
+	switch oas := documentOas(document); {
+	case oasLevel(oas) == target:
+		log.Printf("%s is already OpenAPI %s", definitionPath, target)
+		return content, definitionType, nil
+	case oasLevel(oas) == "3.0" && target == "3.1":
+		content, err := encodeDefinition(upgradeOas31(document), definitionType)
+		return content, definitionType, err
+	default:
+		return nil, "", fmt.Errorf("%s is OpenAPI %s, it can't be converted to %s", definitionPath, oas, target)
+	}
+}
+
+// upgradeSwagger2Definition writes a Swagger 2.0 definition as OpenAPI 3.0,
+// locally or with the converter service.
+func upgradeSwagger2Definition(definitionPath string, content []byte, definitionType string, document *openApiDocument, converter string, converterUrl string) ([]byte, string, error) {
+	if converter != "service" {
+		upgraded, err := upgradeSwagger2(document.Root)
+		if err == nil {
+			content, err := encodeDefinition(upgraded, definitionType)
+			return content, definitionType, err
+		}
+		if converter == "local" {
+			return nil, "", fmt.Errorf("can't upgrade %s: %v", definitionPath, err)
+		}
+		log.Printf("can't upgrade %s here, sending it to %s: %v", definitionPath, converterUrl, err)
+	}
+	upgraded, err := convertWithService(converterUrl, content, definitionType)
+	if err != nil {
+		return nil, "", err
+	}
+	return upgraded, "json", nil
+}
+
+// convertWithService sends the definition to a converter.swagger.io
+// service and returns its answer, indented.
+func convertWithService(converterUrl string, content []byte, definitionType string) ([]byte, error) {
+	mediaType := "application/yaml"
+	if definitionType == "json" {
+		mediaType = "application/json"
+	}
+	request, err := http.NewRequest("POST", converterUrl, bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %s: %v", converterUrl, err)
+	}
+	request.Header.Set("Content-Type", mediaType)
+	request.Header.Set("Accept", "application/json")
+
+	client := client()
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("can't reach %s: %w", converterUrl, err)
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the answer of %s: %w", converterUrl, err)
+	}
+	if response.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s can't convert the definition: %s", converterUrl, response.Status)
+	}
+	var root yaml.Node
+	if !json.Valid(body) || yaml.Unmarshal(body, &root) != nil || len(root.Content) == 0 {
+		return nil, fmt.Errorf("%s didn't answer with a definition", converterUrl)
+	}
+	return encodeDefinition(root.Content[0], "json")
+}
+
+// swaggerUpgrade has what the parts of a Swagger 2.0 definition need from
+// the rest of it: the global media types and parameters, and the names of
+// the global body parameters, which become request bodies.
+type swaggerUpgrade struct {
+	root       *yaml.Node
+	consumes   []string
+	produces   []string
+	parameters *yaml.Node
+	bodies     map[string]bool
+}
+
+// upgradeSwagger2 writes a Swagger 2.0 definition as OpenAPI 3.0.3, its
+// keys in the same order. Its own nodes are left untouched.
+func upgradeSwagger2(root *yaml.Node) (*yaml.Node, error) {
+	upgrade := swaggerUpgrade{
+		root:       root,
+		consumes:   scalarValues(mappingValue(root, "consumes")),
+		produces:   scalarValues(mappingValue(root, "produces")),
+		parameters: mappingValue(root, "parameters"),
+		bodies:     map[string]bool{},
+	}
+	eachMapping(upgrade.parameters, func(key *yaml.Node, value *yaml.Node) {
+		if scalarValue(mappingValue(value, "in")) == "body" {
+			upgrade.bodies[key.Value] = true
+		}
+	})
+
+	upgraded := mappingNode()
+	servers, components := false, false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		switch key.Value {
+		case "swagger":
+			appendMapping(upgraded, "openapi", scalarNode("3.0.3"))
+		case "host", "basePath", "schemes":
+			if !servers {
+				servers = true
+				appendMapping(upgraded, "servers", upgrade.servers())
+			}
+		case "consumes", "produces":
+		case "paths":
+			paths, err := upgrade.paths(value)
+			if err != nil {
+				return nil, err
+			}
+			appendMapping(upgraded, "paths", paths)
+		case "definitions", "parameters", "responses", "securityDefinitions":
+			if !components {
+				components = true
+				node, err := upgrade.components()
+				if err != nil {
+					return nil, err
+				}
+				appendMapping(upgraded, "components", node)
+			}
+		default:
+			appendMapping(upgraded, key.Value, copyNode(value))
+		}
+	}
+	upgradeRefs(upgraded, upgrade.bodies)
+	return upgraded, nil
+}
+
+// servers are the URLs of the host and base path, one by scheme. Without
+// schemes the URL takes the one of the definition.
+func (upgrade *swaggerUpgrade) servers() *yaml.Node {
+	host := scalarValue(mappingValue(upgrade.root, "host"))
+	basePath := scalarValue(mappingValue(upgrade.root, "basePath"))
+	servers := sequenceNode()
+	if host == "" {
+		if basePath == "" {
+			basePath = "/"
+		}
+		servers.Content = append(servers.Content, mappingNode("url", scalarNode(basePath)))
+		return servers
+	}
+	schemes := scalarValues(mappingValue(upgrade.root, "schemes"))
+	if len(schemes) == 0 {
+		schemes = []string{""}
+	}
+	for _, scheme := range schemes {
+		url := "//" + host + basePath
+		if scheme != "" {
+			url = scheme + ":" + url
+		}
+		servers.Content = append(servers.Content, mappingNode("url", scalarNode(url)))
+	}
+	return servers
+}
+
+// components gathers the definitions, global parameters, responses and
+// security definitions. Global form parameters have no place there, they're
+// written in the operations using them.
+func (upgrade *swaggerUpgrade) components() (*yaml.Node, error) {
+	schemas, parameters, requestBodies, responses, securitySchemes := mappingNode(), mappingNode(), mappingNode(), mappingNode(), mappingNode()
+	eachMapping(mappingValue(upgrade.root, "definitions"), func(key *yaml.Node, value *yaml.Node) {
+		appendMapping(schemas, key.Value, upgradeSchema(copyNode(value)))
+	})
+	var err error
+	eachMapping(upgrade.parameters, func(key *yaml.Node, value *yaml.Node) {
+		switch scalarValue(mappingValue(value, "in")) {
+		case "body":
+			appendMapping(requestBodies, key.Value, upgrade.requestBody(value, upgrade.consumes))
+		case "formData":
+		default:
+			parameter, parameterErr := upgradeParameter(value)
+			if parameterErr != nil && err == nil {
+				err = fmt.Errorf("parameter %s: %v", key.Value, parameterErr)
+			}
+			appendMapping(parameters, key.Value, parameter)
+		}
+	})
+	eachMapping(mappingValue(upgrade.root, "responses"), func(key *yaml.Node, value *yaml.Node) {
+		appendMapping(responses, key.Value, upgrade.response(value, upgrade.produces))
+	})
+	eachMapping(mappingValue(upgrade.root, "securityDefinitions"), func(key *yaml.Node, value *yaml.Node) {
+		scheme, schemeErr := upgradeSecurityScheme(value)
+		if schemeErr != nil && err == nil {
+			err = fmt.Errorf("security definition %s: %v", key.Value, schemeErr)
+		}
+		appendMapping(securitySchemes, key.Value, scheme)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	components := mappingNode()
+	for _, section := range []struct {
+		Name string
+		Node *yaml.Node
+	}{{"schemas", schemas}, {"parameters", parameters}, {"requestBodies", requestBodies}, {"responses", responses}, {"securitySchemes", securitySchemes}} {
+		if len(section.Node.Content) > 0 {
+			appendMapping(components, section.Name, section.Node)
+		}
+	}
+	return components, nil
+}
+
+// paths upgrades the operations of every path. The body and form
+// parameters of a path go to each of its operations, as request bodies
+// belong to operations in OpenAPI 3.
+func (upgrade *swaggerUpgrade) paths(paths *yaml.Node) (*yaml.Node, error) {
+	upgraded := mappingNode()
+	for i := 0; i+1 < len(paths.Content); i += 2 {
+		path, item := paths.Content[i].Value, paths.Content[i+1]
+		if item.Kind != yaml.MappingNode {
+			appendMapping(upgraded, path, copyNode(item))
+			continue
+		}
+
+		var shared []*yaml.Node
+		parameters := sequenceNode()
+		if list := mappingValue(item, "parameters"); list != nil {
+			for _, parameter := range list.Content {
+				resolved, err := upgrade.resolveParameter(parameter)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", path, err)
+				}
+				if in := scalarValue(mappingValue(resolved, "in")); in == "body" || in == "formData" {
+					shared = append(shared, parameter)
+					continue
+				}
+				if parameter, err = upgradeParameterRef(parameter); err != nil {
+					return nil, fmt.Errorf("%s: %v", path, err)
+				}
+				parameters.Content = append(parameters.Content, parameter)
+			}
+		}
+
+		upgradedItem := mappingNode()
+		for j := 0; j+1 < len(item.Content); j += 2 {
+			key, value := item.Content[j].Value, item.Content[j+1]
+			switch {
+			case key == "parameters":
+				if len(parameters.Content) > 0 {
+					appendMapping(upgradedItem, key, parameters)
+				}
+			case containsString(httpMethods, key):
+				operation, err := upgrade.operation(value, shared)
+				if err != nil {
+					return nil, fmt.Errorf("%s %s: %v", strings.ToUpper(key), path, err)
+				}
+				appendMapping(upgradedItem, key, operation)
+			default:
+				appendMapping(upgradedItem, key, copyNode(value))
+			}
+		}
+		appendMapping(upgraded, path, upgradedItem)
+	}
+	return upgraded, nil
+}
+
+// operation moves the body or form parameters of an operation, and the
+// shared ones of its path, into its request body, and the schemas of its
+// responses into their content, by the media types it consumes and produces.
+func (upgrade *swaggerUpgrade) operation(operation *yaml.Node, shared []*yaml.Node) (*yaml.Node, error) {
+	consumes, produces := upgrade.consumes, upgrade.produces
+	if node := mappingValue(operation, "consumes"); node != nil {
+		consumes = scalarValues(node)
+	}
+	if node := mappingValue(operation, "produces"); node != nil {
+		produces = scalarValues(node)
+	}
+
+	var body *yaml.Node
+	var form []*yaml.Node
+	parameters := sequenceNode()
+	bodyOwn := false
+	var own []*yaml.Node
+	if list := mappingValue(operation, "parameters"); list != nil {
+		own = list.Content
+	}
+	for i, parameter := range append(shared[:len(shared):len(shared)], own...) {
+		resolved, err := upgrade.resolveParameter(parameter)
+		if err != nil {
+			return nil, err
+		}
+		switch scalarValue(mappingValue(resolved, "in")) {
+		case "body":
+			// the body of the operation replaces the one of the path
+			isOwn := i >= len(shared)
+			if body != nil && bodyOwn == isOwn {
+				return nil, fmt.Errorf("there's more than one body parameter")
+			}
+			body, bodyOwn = parameter, isOwn
+		case "formData":
+			form = append(form, resolved)
+		default:
+			if parameter, err = upgradeParameterRef(parameter); err != nil {
+				return nil, err
+			}
+			parameters.Content = append(parameters.Content, parameter)
+		}
+	}
+
+	var requestBody *yaml.Node
+	switch {
+	case body != nil && len(form) > 0:
+		return nil, fmt.Errorf("it has both body and form parameters")
+	case body != nil && mappingValue(body, "$ref") != nil:
+		requestBody = mappingNode("$ref", copyNode(mappingValue(body, "$ref")))
+	case body != nil:
+		requestBody = upgrade.requestBody(body, consumes)
+	case len(form) > 0:
+		requestBody = formRequestBody(form, consumes)
+	}
+
+	upgraded := mappingNode()
+	placeBody := func() {
+		if requestBody != nil {
+			appendMapping(upgraded, "requestBody", requestBody)
+			requestBody = nil
+		}
+	}
+	for i := 0; i+1 < len(operation.Content); i += 2 {
+		key, value := operation.Content[i].Value, operation.Content[i+1]
+		switch key {
+		case "consumes", "produces", "schemes":
+		case "parameters":
+			if len(parameters.Content) > 0 {
+				appendMapping(upgraded, key, parameters)
+			}
+			placeBody()
+		case "responses":
+			placeBody()
+			responses := mappingNode()
+			eachMapping(value, func(code *yaml.Node, response *yaml.Node) {
+				appendMapping(responses, code.Value, upgrade.response(response, produces))
+			})
+			appendMapping(upgraded, key, responses)
+		default:
+			appendMapping(upgraded, key, copyNode(value))
+		}
+	}
+	placeBody()
+	return upgraded, nil
+}
+
+// resolveParameter is the global parameter a parameter points to, or the
+// parameter itself. References to other files are taken as parameters in
+// the query, the path or a header, which they usually are.
+func (upgrade *swaggerUpgrade) resolveParameter(parameter *yaml.Node) (*yaml.Node, error) {
+	ref := scalarValue(mappingValue(parameter, "$ref"))
+	if !strings.HasPrefix(ref, "#/parameters/") {
+		return parameter, nil
+	}
+	global := mappingValue(upgrade.parameters, splitPointer(ref)[1])
+	if global == nil {
+		return nil, fmt.Errorf("the reference %s points to nothing", ref)
+	}
+	return global, nil
+}
+
+// requestBody is the request body of a body parameter, its schema under
+// every media type consumed.
+func (upgrade *swaggerUpgrade) requestBody(parameter *yaml.Node, consumes []string) *yaml.Node {
+	if len(consumes) == 0 {
+		consumes = []string{"application/json"}
+	}
+	schema := mappingNode()
+	if node := mappingValue(parameter, "schema"); node != nil {
+		schema = upgradeSchema(copyNode(node))
+	}
+	content := mappingNode()
+	for _, mediaType := range consumes {
+		appendMapping(content, mediaType, mappingNode("schema", copyNode(schema)))
+	}
+
+	body := mappingNode()
+	if description := mappingValue(parameter, "description"); description != nil {
+		appendMapping(body, "description", copyNode(description))
+	}
+	appendMapping(body, "content", content)
+	if required := mappingValue(parameter, "required"); required != nil {
+		appendMapping(body, "required", copyNode(required))
+	}
+	appendExtensions(body, parameter)
+	return body
+}
+
+// formRequestBody is the request body of form parameters: an object with a
+// property by parameter, sent as multipart when a file is among them or the
+// operation consumes it.
+func formRequestBody(parameters []*yaml.Node, consumes []string) *yaml.Node {
+	var mediaTypes []string
+	for _, mediaType := range consumes {
+		if mediaType == "multipart/form-data" || mediaType == "application/x-www-form-urlencoded" {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+	}
+	properties, required := mappingNode(), sequenceNode()
+	file := false
+	for _, parameter := range parameters {
+		name := scalarValue(mappingValue(parameter, "name"))
+		file = file || scalarValue(mappingValue(parameter, "type")) == "file"
+		property := swagger2Schema(parameter)
+		if description := mappingValue(parameter, "description"); description != nil {
+			appendMapping(property, "description", copyNode(description))
+		}
+		if value := mappingValue(properties, name); value != nil {
+			*value = *property
+		} else {
+			appendMapping(properties, name, property)
+		}
+		if scalarValue(mappingValue(parameter, "required")) == "true" && !containsString(scalarValues(required), name) {
+			required.Content = append(required.Content, scalarNode(name))
+		}
+	}
+	if len(mediaTypes) == 0 {
+		mediaTypes = []string{"application/x-www-form-urlencoded"}
+		if file {
+			mediaTypes = []string{"multipart/form-data"}
+		}
+	}
+
+	schema := mappingNode("type", scalarNode("object"), "properties", properties)
+	if len(required.Content) > 0 {
+		appendMapping(schema, "required", required)
+	}
+	content := mappingNode()
+	for _, mediaType := range mediaTypes {
+		appendMapping(content, mediaType, mappingNode("schema", copyNode(schema)))
+	}
+	return mappingNode("content", content)
+}
+
+// upgradeParameterRef upgrades a parameter, leaving references as they are.
+func upgradeParameterRef(parameter *yaml.Node) (*yaml.Node, error) {
+	if mappingValue(parameter, "$ref") != nil {
+		return copyNode(parameter), nil
+	}
+	return upgradeParameter(parameter)
+}
+
+// upgradeParameter moves the type of a query, path, header or cookie
+// parameter into its schema, and its collectionFormat into its style.
+func upgradeParameter(parameter *yaml.Node) (*yaml.Node, error) {
+	in := scalarValue(mappingValue(parameter, "in"))
+	collectionFormat := scalarValue(mappingValue(parameter, "collectionFormat"))
+	if collectionFormat == "" && scalarValue(mappingValue(parameter, "type")) == "array" {
+		collectionFormat = "csv"
+	}
+	var style []*yaml.Node
+	switch {
+	case collectionFormat == "" || collectionFormat == "csv" && in != "query":
+	case in != "query":
+		return nil, fmt.Errorf("%s parameters can't be %s in OpenAPI 3", in, collectionFormat)
+	case collectionFormat == "csv":
+		style = []*yaml.Node{scalarNode("explode"), &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "false"}}
+	case collectionFormat == "multi":
+	case collectionFormat == "ssv":
+		style = []*yaml.Node{scalarNode("style"), scalarNode("spaceDelimited")}
+	case collectionFormat == "pipes":
+		style = []*yaml.Node{scalarNode("style"), scalarNode("pipeDelimited")}
+	default:
+		return nil, fmt.Errorf("OpenAPI 3 has no collectionFormat %s", collectionFormat)
+	}
+
+	upgraded := mappingNode()
+	schema := swagger2Schema(parameter)
+	placed := false
+	for i := 0; i+1 < len(parameter.Content); i += 2 {
+		key, value := parameter.Content[i].Value, parameter.Content[i+1]
+		switch {
+		case key == "x-example":
+			appendMapping(upgraded, "example", copyNode(value))
+		case key == "collectionFormat" || containsString(parameterSchemaKeys, key):
+			if !placed {
+				placed = true
+				upgraded.Content = append(upgraded.Content, style...)
+				appendMapping(upgraded, "schema", schema)
+			}
+		default:
+			appendMapping(upgraded, key, copyNode(value))
+		}
+	}
+	return upgraded, nil
+}
+
+// swagger2Schema is the schema of a parameter, header or items object of
+// Swagger 2.0, made of its type and validations.
+func swagger2Schema(parameter *yaml.Node) *yaml.Node {
+	schema := mappingNode()
+	for i := 0; i+1 < len(parameter.Content); i += 2 {
+		key, value := parameter.Content[i].Value, parameter.Content[i+1]
+		switch {
+		case key == "items":
+			appendMapping(schema, key, swagger2Schema(value))
+		case containsString(parameterSchemaKeys, key):
+			appendMapping(schema, key, copyNode(value))
+		}
+	}
+	return upgradeSchema(schema)
+}
+
+// response moves the schema and examples of a response into its content,
+// by the media types produced, and the types of its headers into their
+// schemas.
+func (upgrade *swaggerUpgrade) response(response *yaml.Node, produces []string) *yaml.Node {
+	if mappingValue(response, "$ref") != nil || response.Kind != yaml.MappingNode {
+		return copyNode(response)
+	}
+	if len(produces) == 0 {
+		produces = []string{"application/json"}
+	}
+	schema := mappingValue(response, "schema")
+	examples := mappingValue(response, "examples")
+
+	content := mappingNode()
+	if schema != nil {
+		upgraded := upgradeSchema(copyNode(schema))
+		for _, mediaType := range produces {
+			appendMapping(content, mediaType, mappingNode("schema", copyNode(upgraded)))
+		}
+	}
+	eachMapping(examples, func(mediaType *yaml.Node, example *yaml.Node) {
+		if media := mappingValue(content, mediaType.Value); media != nil {
+			appendMapping(media, "example", copyNode(example))
+		} else {
+			appendMapping(content, mediaType.Value, mappingNode("example", copyNode(example)))
+		}
+	})
+
+	upgraded := mappingNode()
+	placed := false
+	for i := 0; i+1 < len(response.Content); i += 2 {
+		key, value := response.Content[i].Value, response.Content[i+1]
+		switch key {
+		case "schema", "examples":
+			if !placed && len(content.Content) > 0 {
+				placed = true
+				appendMapping(upgraded, "content", content)
+			}
+		case "headers":
+			headers := mappingNode()
+			eachMapping(value, func(name *yaml.Node, header *yaml.Node) {
+				upgradedHeader := mappingNode()
+				if description := mappingValue(header, "description"); description != nil {
+					appendMapping(upgradedHeader, "description", copyNode(description))
+				}
+				appendMapping(upgradedHeader, "schema", swagger2Schema(header))
+				appendExtensions(upgradedHeader, header)
+				appendMapping(headers, name.Value, upgradedHeader)
+			})
+			appendMapping(upgraded, key, headers)
+		default:
+			appendMapping(upgraded, key, copyNode(value))
+		}
+	}
+	return upgraded
+}
+
+// upgradeSchema changes in place what JSON Schema in OpenAPI 3 writes
+// differently: file types, x-nullable and discriminators.
+func upgradeSchema(schema *yaml.Node) *yaml.Node {
+	if schema == nil || schema.Kind != yaml.MappingNode {
+		return schema
+	}
+	file := false
+	for i := 0; i+1 < len(schema.Content); i += 2 {
+		key, value := schema.Content[i], schema.Content[i+1]
+		switch key.Value {
+		case "type":
+			if value.Value == "file" {
+				value.Value = "string"
+				file = true
+			}
+		case "x-nullable":
+			key.Value = "nullable"
+		case "discriminator":
+			if value.Kind == yaml.ScalarNode {
+				schema.Content[i+1] = mappingNode("propertyName", value)
+			}
+		case "properties":
+			eachMapping(value, func(name *yaml.Node, property *yaml.Node) {
+				upgradeSchema(property)
+			})
+		case "items", "additionalProperties", "not":
+			upgradeSchema(value)
+		case "allOf", "anyOf", "oneOf":
+			for _, item := range value.Content {
+				upgradeSchema(item)
+			}
+		}
+	}
+	if format := mappingValue(schema, "format"); file && format == nil {
+		appendMapping(schema, "format", scalarNode("binary"))
+	}
+	return schema
+}
+
+// upgradeOas31 writes an OpenAPI 3.0 document as 3.1.0, its schemas in
+// JSON Schema 2020-12: nullable becomes a "null" type, the boolean
+// exclusiveMinimum and exclusiveMaximum hold the bound, and example becomes
+// examples. The document itself is left untouched.
+func upgradeOas31(document *openApiDocument) *yaml.Node {
+	upgraded := &openApiDocument{Path: document.Path, Root: copyNode(document.Root)}
+	*mappingValue(upgraded.Root, "openapi") = *scalarNode("3.1.0")
+	for _, schema := range upgraded.schemas() {
+		node := schema.Node
+		if node.Kind != yaml.MappingNode {
+			continue
+		}
+		if nullable := removeMapping(node, "nullable"); scalarValue(nullable) == "true" {
+			switch types := mappingValue(node, "type"); {
+			case types == nil:
+			case types.Kind == yaml.SequenceNode && !containsString(scalarValues(types), "null"):
+				types.Content = append(types.Content, scalarNode("null"))
+			case types.Kind == yaml.ScalarNode:
+				*types = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle, Content: []*yaml.Node{scalarNode(types.Value), scalarNode("null")}}
+			}
+		}
+		for bound, exclusive := range map[string]string{"minimum": "exclusiveMinimum", "maximum": "exclusiveMaximum"} {
+			if flag := mappingValue(node, exclusive); flag != nil && flag.Tag == "!!bool" {
+				value := mappingValue(node, bound)
+				if flag.Value != "true" || value == nil {
+					removeMapping(node, exclusive)
+					continue
+				}
+				*flag = *value
+				removeMapping(node, bound)
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "example" && mappingValue(node, "examples") == nil {
+				node.Content[i] = scalarNode("examples")
+				node.Content[i+1] = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{node.Content[i+1]}}
+			}
+		}
+	}
+	return upgraded.Root
+}
+
+// upgradeSecurityScheme writes basic authentication as HTTP, and the flow
+// of OAuth2 as one of its flows.
+func upgradeSecurityScheme(scheme *yaml.Node) (*yaml.Node, error) {
+	upgraded := mappingNode()
+	switch scalarValue(mappingValue(scheme, "type")) {
+	case "basic":
+		appendMapping(upgraded, "type", scalarNode("http"))
+		appendMapping(upgraded, "scheme", scalarNode("basic"))
+	case "oauth2":
+		flow, ok := oauth2Flows[scalarValue(mappingValue(scheme, "flow"))]
+		if !ok {
+			return nil, fmt.Errorf("unknown OAuth2 flow %s", scalarValue(mappingValue(scheme, "flow")))
+		}
+		upgradedFlow := mappingNode()
+		for _, key := range []string{"authorizationUrl", "tokenUrl"} {
+			if value := mappingValue(scheme, key); value != nil {
+				appendMapping(upgradedFlow, key, copyNode(value))
+			}
+		}
+		scopes := mappingValue(scheme, "scopes")
+		if scopes == nil {
+			scopes = mappingNode()
+		}
+		appendMapping(upgradedFlow, "scopes", copyNode(scopes))
+		appendMapping(upgraded, "type", scalarNode("oauth2"))
+		appendMapping(upgraded, "flows", mappingNode(flow, upgradedFlow))
+	default:
+		return copyNode(scheme), nil
+	}
+	if description := mappingValue(scheme, "description"); description != nil {
+		appendMapping(upgraded, "description", copyNode(description))
+	}
+	appendExtensions(upgraded, scheme)
+	return upgraded, nil
+}
+
+// upgradeRefs points the references to definitions, parameters and
+// responses, of the definition or other files, to the components that
+// replace them. References to body parameters point to request bodies.
+func upgradeRefs(node *yaml.Node, bodies map[string]bool) {
+	if ref := mappingValue(node, "$ref"); ref != nil && ref.Kind == yaml.ScalarNode {
+		if i := strings.Index(ref.Value, "#/"); i >= 0 {
+			file, pointer := ref.Value[:i], ref.Value[i+1:]
+			switch {
+			case strings.HasPrefix(pointer, "/definitions/"):
+				pointer = "/components/schemas/" + strings.TrimPrefix(pointer, "/definitions/")
+			case strings.HasPrefix(pointer, "/parameters/") && file == "" && bodies[splitPointer("#" + pointer)[1]]:
+				pointer = "/components/requestBodies/" + strings.TrimPrefix(pointer, "/parameters/")
+			case strings.HasPrefix(pointer, "/parameters/"), strings.HasPrefix(pointer, "/responses/"):
+				pointer = "/components" + pointer
+			}
+			ref.Value = file + "#" + pointer
+		}
+	}
+	for _, child := range node.Content {
+		upgradeRefs(child, bodies)
+	}
+}
+
+func mappingNode(pairs ...interface{}) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		appendMapping(node, pairs[i].(string), pairs[i+1].(*yaml.Node))
+	}
+	return node
+}
+
+func sequenceNode() *yaml.Node {
+	return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+}
+
+func appendMapping(node *yaml.Node, key string, value *yaml.Node) {
+	node.Content = append(node.Content, scalarNode(key), value)
+}
+
+// appendExtensions copies the x- keys of a node.
+func appendExtensions(node *yaml.Node, from *yaml.Node) {
+	eachMapping(from, func(key *yaml.Node, value *yaml.Node) {
+		if strings.HasPrefix(key.Value, "x-") {
+			appendMapping(node, key.Value, copyNode(value))
+		}
+	})
+}
+
+// removeMapping removes a key of a mapping and returns its value.
+func removeMapping(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			value := node.Content[i+1]
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return value
+		}
+	}
+	return nil
+}
+
+func scalarValues(node *yaml.Node) []string {
+	var values []string
+	if node != nil {
+		for _, item := range node.Content {
+			values = append(values, item.Value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const swagger2Header = "swagger: \"2.0\"\ninfo: {title: Pets, version: \"1.0\"}\n"
+
+func TestUpgradeSwagger2(t *testing.T) {
+	tests := []struct {
+		name    string
+		swagger string
+		at      []string
+		want    string
+	}{
+		{
+			"body parameter",
+			"paths:\n  /pets:\n    post:\n      consumes: [application/json]\n      parameters:\n        - {name: pet, in: body, required: true, description: the pet, schema: {$ref: '#/definitions/Pet'}}\n      responses: {\"201\": {description: created}}\ndefinitions:\n  Pet: {type: object}\n",
+			[]string{"paths", "/pets", "post", "requestBody"},
+			"{description: the pet, content: {application/json: {schema: {$ref: '#/components/schemas/Pet'}}}, required: true}",
+		},
+		{
+			"body parameter of the path",
+			"paths:\n  /pets:\n    parameters:\n      - {name: pet, in: body, schema: {type: object}}\n    put:\n      responses: {\"204\": {description: updated}}\n",
+			[]string{"paths", "/pets", "put", "requestBody"},
+			"{content: {application/json: {schema: {type: object}}}}",
+		},
+		{
+			"form parameters",
+			"paths:\n  /pets:\n    post:\n      parameters:\n        - {name: name, in: formData, type: string, required: true}\n        - {name: age, in: formData, type: integer}\n      responses: {\"201\": {description: created}}\n",
+			[]string{"paths", "/pets", "post", "requestBody"},
+			"{content: {application/x-www-form-urlencoded: {schema: {type: object, properties: {name: {type: string}, age: {type: integer}}, required: [name]}}}}",
+		},
+		{
+			"file upload",
+			"paths:\n  /pets/photo:\n    post:\n      parameters:\n        - {name: photo, in: formData, type: file}\n      responses: {\"201\": {description: created}}\n",
+			[]string{"paths", "/pets/photo", "post", "requestBody"},
+			"{content: {multipart/form-data: {schema: {type: object, properties: {photo: {type: string, format: binary}}}}}}",
+		},
+		{
+			"consumes of the definition",
+			"consumes: [application/json, application/xml]\npaths:\n  /pets:\n    post:\n      parameters:\n        - {name: pet, in: body, schema: {type: object}}\n      responses: {\"201\": {description: created}}\n",
+			[]string{"paths", "/pets", "post", "requestBody", "content"},
+			"{application/json: {schema: {type: object}}, application/xml: {schema: {type: object}}}",
+		},
+		{
+			"produces of the definition",
+			"produces: [application/json, application/xml]\npaths:\n  /pets:\n    get:\n      responses: {\"200\": {description: ok, schema: {type: array, items: {type: string}}}}\n",
+			[]string{"paths", "/pets", "get", "responses", "200"},
+			"{description: ok, content: {application/json: {schema: {type: array, items: {type: string}}}, application/xml: {schema: {type: array, items: {type: string}}}}}",
+		},
+		{
+			"produces of the operation",
+			"produces: [application/json]\npaths:\n  /pets:\n    get:\n      produces: [text/csv]\n      responses: {\"200\": {description: ok, schema: {type: string}}}\n",
+			[]string{"paths", "/pets", "get", "responses", "200", "content"},
+			"{text/csv: {schema: {type: string}}}",
+		},
+		{
+			"response examples",
+			"paths:\n  /pets:\n    get:\n      responses: {\"200\": {description: ok, schema: {type: object}, examples: {application/json: {name: Rex}}}}\n",
+			[]string{"paths", "/pets", "get", "responses", "200", "content"},
+			"{application/json: {schema: {type: object}, example: {name: Rex}}}",
+		},
+		{
+			"query array",
+			"paths:\n  /pets:\n    get:\n      parameters:\n        - {name: tags, in: query, type: array, items: {type: string}, collectionFormat: pipes}\n      responses: {\"200\": {description: ok}}\n",
+			[]string{"paths", "/pets", "get", "parameters"},
+			"[{name: tags, in: query, style: pipeDelimited, schema: {type: array, items: {type: string}}}]",
+		},
+		{
+			"basic authentication",
+			"paths: {}\nsecurityDefinitions:\n  basic: {type: basic, description: users}\n",
+			[]string{"components", "securitySchemes", "basic"},
+			"{type: http, scheme: basic, description: users}",
+		},
+		{
+			"oauth2 access code",
+			"paths: {}\nsecurityDefinitions:\n  oauth: {type: oauth2, flow: accessCode, authorizationUrl: 'https://example.com/authorize', tokenUrl: 'https://example.com/token', scopes: {read: read the pets}}\n",
+			[]string{"components", "securitySchemes", "oauth"},
+			"{type: oauth2, flows: {authorizationCode: {authorizationUrl: 'https://example.com/authorize', tokenUrl: 'https://example.com/token', scopes: {read: read the pets}}}}",
+		},
+		{
+			"oauth2 application",
+			"paths: {}\nsecurityDefinitions:\n  oauth: {type: oauth2, flow: application, tokenUrl: 'https://example.com/token'}\n",
+			[]string{"components", "securitySchemes", "oauth", "flows"},
+			"{clientCredentials: {tokenUrl: 'https://example.com/token', scopes: {}}}",
+		},
+		{
+			"api key",
+			"paths: {}\nsecurityDefinitions:\n  key: {type: apiKey, name: X-Api-Key, in: header}\n",
+			[]string{"components", "securitySchemes", "key"},
+			"{type: apiKey, name: X-Api-Key, in: header}",
+		},
+		{
+			"reference to a definition",
+			"paths: {}\ndefinitions:\n  Pet: {type: object, properties: {owner: {$ref: '#/definitions/Owner'}}}\n  Owner: {type: object}\n",
+			[]string{"components", "schemas", "Pet", "properties", "owner"},
+			"{$ref: '#/components/schemas/Owner'}",
+		},
+		{
+			"reference to another file",
+			"paths:\n  /pets:\n    get:\n      responses: {\"200\": {description: ok, schema: {$ref: 'common.yaml#/definitions/Pet'}}}\n",
+			[]string{"paths", "/pets", "get", "responses", "200", "content", "application/json", "schema"},
+			"{$ref: 'common.yaml#/components/schemas/Pet'}",
+		},
+		{
+			"reference to a global body parameter",
+			"paths:\n  /pets:\n    post:\n      parameters: [{$ref: '#/parameters/Pet'}]\n      responses: {\"201\": {description: created}}\nparameters:\n  Pet: {name: pet, in: body, schema: {type: object}}\n",
+			[]string{"paths", "/pets", "post", "requestBody"},
+			"{$ref: '#/components/requestBodies/Pet'}",
+		},
+		{
+			"reference to a global parameter",
+			"paths:\n  /pets:\n    get:\n      parameters: [{$ref: '#/parameters/Limit'}]\n      responses: {\"200\": {description: ok}}\nparameters:\n  Limit: {name: limit, in: query, type: integer}\n",
+			[]string{"paths", "/pets", "get", "parameters"},
+			"[{$ref: '#/components/parameters/Limit'}]",
+		},
+		{
+			"reference to a global response",
+			"paths:\n  /pets:\n    get:\n      responses: {\"404\": {$ref: '#/responses/NotFound'}}\nresponses:\n  NotFound: {description: not found}\n",
+			[]string{"paths", "/pets", "get", "responses", "404"},
+			"{$ref: '#/components/responses/NotFound'}",
+		},
+		{
+			"servers",
+			"host: api.example.com\nbasePath: /v1\nschemes: [https, http]\npaths: {}\n",
+			[]string{"servers"},
+			"[{url: 'https://api.example.com/v1'}, {url: 'http://api.example.com/v1'}]",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document := lintTestDocument(t, swagger2Header+test.swagger)
+			upgraded, err := upgradeSwagger2(document.Root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want yaml.Node
+			if err := yaml.Unmarshal([]byte(test.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			got := (&openApiDocument{Root: upgraded}).lookup(test.at...)
+			if got == nil {
+				t.Fatalf("there is nothing at %s", strings.Join(test.at, "."))
+			}
+			if fingerprint(nodeValue(got)) != fingerprint(nodeValue(want.Content[0])) {
+				t.Errorf("got %s, want %s", fingerprint(nodeValue(got)), fingerprint(nodeValue(want.Content[0])))
+			}
+			if openapi := scalarValue(mappingValue(upgraded, "openapi")); openapi != "3.0.3" {
+				t.Errorf("got openapi %s", openapi)
+			}
+		})
+	}
+}
+
+func TestUpgradeSwagger2Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		swagger string
+		err     string
+	}{
+		{"body and form", "paths:\n  /pets:\n    post:\n      parameters:\n        - {name: pet, in: body, schema: {type: object}}\n        - {name: name, in: formData, type: string}\n      responses: {}\n", "both body and form parameters"},
+		{"two bodies", "paths:\n  /pets:\n    post:\n      parameters:\n        - {name: a, in: body, schema: {type: object}}\n        - {name: b, in: body, schema: {type: object}}\n      responses: {}\n", "more than one body parameter"},
+		{"unknown flow", "paths: {}\nsecurityDefinitions:\n  oauth: {type: oauth2, flow: device}\n", "unknown OAuth2 flow device"},
+		{"tsv", "paths:\n  /pets:\n    get:\n      parameters:\n        - {name: tags, in: query, type: array, items: {type: string}, collectionFormat: tsv}\n      responses: {}\n", "no collectionFormat tsv"},
+		{"missing parameter", "paths:\n  /pets:\n    get:\n      parameters: [{$ref: '#/parameters/Limit'}]\n      responses: {}\n", "points to nothing"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document := lintTestDocument(t, swagger2Header+test.swagger)
+			if _, err := upgradeSwagger2(document.Root); err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got %v, want an error with %q", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// validationRules check the structure the specification requires, so a
+// malformed definition is caught locally instead of by SwaggerHub. They run
+// before every publication and with the validate command.
+var validationRules = []lintRule{
+	{Name: "oas-required-fields", Severity: "error", Check: checkRequiredFields},
+	{Name: "oas-paths", Severity: "error", Check: checkPathsObject},
+	{Name: "oas-responses", Severity: "error", Check: checkOperationResponses},
+	{Name: "oas-parameters", Severity: "error", Check: checkParameters},
+	{Name: "oas-refs", Severity: "error", Check: checkRefs},
+	{Name: "schema-nullable", Severity: "warning", Check: checkSchemaNullable},
+}
+
+var responseCodePattern = regexp.MustCompile(`^([1-5][0-9]{2}|[1-5]XX|default)$`)
+
+var parameterLocations = map[bool][]string{
+	true:  {"query", "header", "path", "formData", "body"},
+	false: {"query", "header", "path", "cookie"},
+}
+
+type validateOptions struct {
+	Report     string `flag:"report"`
+	ReportFile string `flag:"report-file"`
+	Output     string `flag:"output" default:"text"`
+}
+
+// validateCommand checks that definitions are valid, without publishing
+// them. --report junit also writes the results for CI test views.
+func validateCommand(args []string) {
+	options := validateOptions{}
+	paths := parseArgs(&options, args)
+	if len(paths) == 0 {
+		exitAndError("validate needs the path to the OpenAPI definition")
+	}
+	checkReportFormat(options.Report)
+	useOutput(options.Output, "text", "github", "json")
+
+	var results []definitionFindings
+	invalid := 0
+	for _, path := range paths {
+		document, err := readOpenApiDocument(path)
+		var handler specHandler
+		if err == nil {
+			handler, err = detectSpecHandler(document, "")
+		}
+		if err != nil {
+			log.Print(err)
+			results = append(results, definitionFindings{Path: path, Err: err})
+			invalid++
+			continue
+		}
+		findings := lintDocument(document, handler.validation(), &commandLineOptions{})
+		results = append(results, definitionFindings{Path: path, Findings: findings})
+		if reportFindings(document, findings) > 0 {
+			invalid++
+			continue
+		}
+		log.Printf("%s is valid", path)
+	}
+	writeFindingsReport(options.Report, options.ReportFile, results)
+	if options.Output == "github" {
+		writeFindingsReport("github", "", results)
+	}
+	if outputJson {
+		printCheckResult(results)
+		return
+	}
+	if invalid > 0 {
+		exitWithCode(exitInvalid, fmt.Sprintf("%d of %d definitions are invalid", invalid, len(paths)))
+	}
+}
+
+func readOpenApiDocument(path string) (*openApiDocument, error) {
+	content, err := readDefinition(path, "")
+	if err != nil {
+		return nil, err
+	}
+	return parseOpenApiDocument(path, content)
+}
+
+func checkRequiredFields(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	var findings []lintFinding
+	missing := func(node *yaml.Node, pointer string, line int, fields ...string) {
+		for _, field := range fields {
+			if mappingValue(node, field) == nil {
+				findings = append(findings, lintFinding{
+					Message: fmt.Sprintf("%s is missing", strings.TrimPrefix(strings.ReplaceAll(pointer, "/", ".")+"."+field, "#.")),
+					Pointer: pointer,
+					Line:    line,
+				})
+			}
+		}
+	}
+
+	switch {
+	case document.isSwagger2():
+		missing(document.Root, "#", document.Root.Line, "info", "paths")
+	case document.is31():
+		missing(document.Root, "#", document.Root.Line, "info")
+		if document.lookup("paths") == nil && document.lookup("components") == nil && document.lookup("webhooks") == nil {
+			findings = append(findings, lintFinding{Message: "one of paths, components or webhooks is needed", Pointer: "#", Line: document.Root.Line})
+		}
+	default:
+		missing(document.Root, "#", document.Root.Line, "info", "paths")
+	}
+	if info := document.lookup("info"); info != nil {
+		missing(info, "#/info", info.Line, "title", "version")
+	}
+	return findings
+}
+
+func checkPathsObject(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	var findings []lintFinding
+	paths := document.lookup("paths")
+	if paths == nil {
+		return nil
+	}
+	if paths.Kind != yaml.MappingNode {
+		return []lintFinding{{Message: "paths is not an object", Pointer: "#/paths", Line: paths.Line}}
+	}
+	eachMapping(paths, func(path *yaml.Node, item *yaml.Node) {
+		if !strings.HasPrefix(path.Value, "/") && !strings.HasPrefix(path.Value, "x-") {
+			findings = append(findings, lintFinding{
+				Message: fmt.Sprintf("the path %s doesn't start with /", path.Value),
+				Pointer: joinPointer("paths", path.Value),
+				Line:    path.Line,
+			})
+		}
+	})
+	return findings
+}
+
+func checkOperationResponses(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	var findings []lintFinding
+	for _, operation := range append(document.operations(), document.webhooks()...) {
+		responses := mappingValue(operation.Node, "responses")
+		if responses == nil && document.is31() {
+			// responses are optional since OpenAPI 3.1
+			continue
+		}
+		if responses == nil || len(responses.Content) == 0 {
+			findings = append(findings, lintFinding{
+				Message: fmt.Sprintf("%s has no responses", operation),
+				Pointer: operation.pointer(),
+				Line:    operation.Node.Line,
+			})
+			continue
+		}
+		eachMapping(responses, func(code *yaml.Node, response *yaml.Node) {
+			if strings.HasPrefix(code.Value, "x-") {
+				return
+			}
+			if !responseCodePattern.MatchString(code.Value) {
+				findings = append(findings, lintFinding{
+					Message: fmt.Sprintf("%s has the invalid response code %s", operation, code.Value),
+					Pointer: operation.pointer("responses", code.Value),
+					Line:    code.Line,
+				})
+			}
+			if resolved := document.resolve(response); resolved != nil && mappingValue(resolved, "description") == nil {
+				findings = append(findings, lintFinding{
+					Message: fmt.Sprintf("the %s response of %s has no description", code.Value, operation),
+					Pointer: operation.pointer("responses", code.Value),
+					Line:    code.Line,
+				})
+			}
+		})
+	}
+	return findings
+}
+
+func checkParameters(document *openApiDocument, options *commandLineOptions) []lintFinding {
+	var findings []lintFinding
+	locations := parameterLocations[document.isSwagger2()]
+	check := func(parameters *yaml.Node, keys ...string) {
+		if parameters == nil {
+			return
+		}
+		seen := map[string]bool{}
+		for i, parameter := range parameters.Content {
+			pointer := joinPointer(append(keys, fmt.Sprint(i))...)
+			resolved := document.resolve(parameter)
+			if resolved == nil {
+				continue
+			}
+			name := scalarValue(mappingValue(resolved, "name"))
+			in := scalarValue(mappingValue(resolved, "in"))
+			report := func(format string, args ...interface{}) {
+				findings = append(findings, lintFinding{Message: fmt.Sprintf(format, args...), Pointer: pointer, Line: parameter.Line})
+			}
+
+			if name == "" || in == "" {
+				report("the parameter %s needs a name and a location (in)", strings.TrimPrefix(pointer, "#/"))
+				continue
+			}
+			if !containsString(locations, in) {
+				report("the parameter %s has the invalid location %s, use one of %s", name, in, strings.Join(locations, ", "))
+			}
+			if in == "path" && scalarValue(mappingValue(resolved, "required")) != "true" {
+				report("the path parameter %s must be required", name)
+			}
+			if !document.isSwagger2() && mappingValue(resolved, "schema") == nil && mappingValue(resolved, "content") == nil {
+				report("the parameter %s needs a schema or a content", name)
+			}
+			if seen[in+" "+name] {
+				report("the parameter %s in %s is defined twice", name, in)
+			}
+			seen[in+" "+name] = true
+		}
+	}
+
+	for _, section := range []string{"paths", "webhooks"} {
+		eachMapping(document.lookup(section), func(path *yaml.Node, item *yaml.Node) {
+			check(mappingValue(item, "parameters"), section, path.Value, "parameters")
+		})
+	}
+	for _, operation := range append(document.operations(), document.webhooks()...) {
+		check(mappingValue(operation.Node, "parameters"), append(operation.keys(), "parameters")...)
+	}
+	return findings
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxVerboseBody is how much of a response body --verbose logs.
+const maxVerboseBody = 4096
+
+// secretHeaders are masked in the --verbose logs, whatever the request.
+var secretHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Amz-Security-Token"}
+
+// verboseTransport logs the requests made with --verbose and their answers:
+// method, URL, headers, timing and the response body, for troubleshooting
+// the 401, 403 and 415 of SwaggerHub. The credentials are always masked.
+type verboseTransport struct {
+	transport http.RoundTripper
+}
+
+func (verbose verboseTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	log.Printf("> %s %s", request.Method, request.URL.Redacted())
+	logHeaders(">", request.Header)
+	if request.ContentLength > 0 {
+		log.Printf("> (%d bytes)", request.ContentLength)
+	}
+
+	start := time.Now()
+	response, err := verbose.transport.RoundTrip(request)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		log.Printf("< %s %s failed after %s: %v", request.Method, request.URL.Redacted(), elapsed, err)
+		return nil, err
+	}
+	log.Printf("< %s %s %s in %s", response.Proto, response.Status, request.URL.Redacted(), elapsed)
+	logHeaders("<", response.Header)
+
+	body, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	shown := body
+	if len(shown) > maxVerboseBody {
+		shown = shown[:maxVerboseBody]
+	}
+	if text := strings.TrimSpace(string(shown)); text != "" {
+		log.Printf("< %s", text)
+		if len(body) > maxVerboseBody {
+			log.Printf("< (%d more bytes)", len(body)-maxVerboseBody)
+		}
+	}
+	return response, nil
+}
+
+func logHeaders(direction string, headers http.Header) {
+	var names []string
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range headers[name] {
+			if containsString(secretHeaders, http.CanonicalHeaderKey(name)) {
+				value = maskCredentials(value)
+			}
+			log.Printf("%s %s: %s", direction, name, value)
+		}
+	}
+}
+
+// maskCredentials keeps the scheme of an Authorization header, as Bearer,
+// which tells which kind of credentials were sent.
+func maskCredentials(value string) string {
+	if parts := strings.SplitN(value, " ", 2); len(parts) == 2 && !strings.Contains(parts[0], "=") {
+		return parts[0] + " " + maskSecret(parts[1])
+	}
+	return maskSecret(value)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const swaggerHubMockServer = "https://virtserver.swaggerhub.com/"
+
+// maxReportedDifferences keeps the output readable when everything differs,
+// for example when the wrong file is given.
+const maxReportedDifferences = 20
+
+type verifyOptions struct {
+	SwaggerHubApi string `flag:"api" env:"SWAGGERHUB_API" required:"true"`
+	ApiVersion    string `flag:"api-version"`
+	Output        string `flag:"output" default:"text"`
+	connectionOptions
+}
+
+// verifyResult is what verify prints with --output json.
+type verifyResult struct {
+	Status      string   `json:"status"`
+	Path        string   `json:"path"`
+	Api         string   `json:"api"`
+	Version     string   `json:"version"`
+	Differences []string `json:"differences"`
+}
+
+// verifyCommand fetches a published version and compares it with the local
+// definition. The comparison is on the parsed values, so the reformatting
+// SwaggerHub applies doesn't count, and neither does the auto mocking server
+// it may add.
+func verifyCommand(args []string) {
+	options := verifyOptions{}
+	positional := parseArgs(&options, args)
+	useOutput(options.Output, "text", "json")
+	options.useConnection(&options.SwaggerHubApi)
+	if len(positional) != 1 {
+		exitAndError("verify needs the path to the OpenAPI definition")
+	}
+	if len(strings.Split(options.SwaggerHubApi, "/")) != 2 {
+		exitAndError("api is in the wrong format")
+	}
+
+	openApiPath := positional[0]
+	openApi, err := ioutil.ReadFile(openApiPath)
+	if err != nil {
+		exitAndError(fmt.Sprintf("can't read the file %s", openApiPath))
+	}
+	local, err := parseOpenApiDocument(openApiPath, openApi)
+	if err != nil {
+		exitAndError(err)
+	}
+	if options.ApiVersion == "" {
+		options.ApiVersion = definitionVersion(openApi)
+	}
+
+	// a cached copy would hide what SwaggerHub stores now
+	swaggerHubCache = nil
+	published, err := getFromSwaggerHub(fmt.Sprintf("%s/%s/swagger.yaml", options.SwaggerHubApi, options.ApiVersion), options.SwaggerHubAccessToken)
+	if err != nil {
+		exitAndError(fmt.Errorf("can't fetch %s %s: %w", options.SwaggerHubApi, options.ApiVersion, err))
+	}
+	remote, err := parseOpenApiDocument(fmt.Sprintf("%s %s", options.SwaggerHubApi, options.ApiVersion), published)
+	if err != nil {
+		exitAndError(err)
+	}
+
+	differences := semanticDifferences(withoutMockServers(nodeValue(local.Root)), withoutMockServers(nodeValue(remote.Root)), "")
+	result := verifyResult{Status: "matches", Path: openApiPath, Api: options.SwaggerHubApi, Version: options.ApiVersion, Differences: []string{}}
+	if len(differences) == 0 {
+		log.Printf("%s %s matches %s", options.SwaggerHubApi, options.ApiVersion, openApiPath)
+		if outputJson {
+			printJson(result)
+		}
+		return
+	}
+
+	for i, difference := range differences {
+		if i == maxReportedDifferences {
+			log.Printf("and %d more differences", len(differences)-i)
+			break
+		}
+		log.Print(difference)
+	}
+	message := fmt.Sprintf("%s %s doesn't match %s", options.SwaggerHubApi, options.ApiVersion, openApiPath)
+	if outputJson {
+		result.Status, result.Differences = "differs", differences
+		printJsonResult(result, exitFailure, message)
+	}
+	reportFailure(message)
+	fmt.Printf("%s: %s\n", commandLineName, message)
+	os.Exit(exitFailure)
+}
+
+// semanticDifferences describes where two decoded documents differ, by JSON
+// pointer, local first.
+func semanticDifferences(local interface{}, published interface{}, pointer string) []string {
+	var differences []string
+	switch localValue := local.(type) {
+	case map[string]interface{}:
+		publishedValue, ok := published.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := map[string]bool{}
+		for key := range localValue {
+			keys[key] = true
+		}
+		for key := range publishedValue {
+			keys[key] = true
+		}
+		var sorted []string
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+
+		for _, key := range sorted {
+			child := pointer + "/" + escapePointer(key)
+			localChild, inLocal := localValue[key]
+			publishedChild, inPublished := publishedValue[key]
+			switch {
+			case !inPublished:
+				differences = append(differences, fmt.Sprintf("%s is missing from the published version", child))
+			case !inLocal:
+				differences = append(differences, fmt.Sprintf("%s was added in the published version", child))
+			default:
+				differences = append(differences, semanticDifferences(localChild, publishedChild, child)...)
+			}
+		}
+		return differences
+	case []interface{}:
+		publishedValue, ok := published.([]interface{})
+		if !ok {
+			break
+		}
+		if len(localValue) != len(publishedValue) {
+			return []string{fmt.Sprintf("%s has %d items locally and %d published", pointerOrRoot(pointer), len(localValue), len(publishedValue))}
+		}
+		for i := range localValue {
+			differences = append(differences, semanticDifferences(localValue[i], publishedValue[i], fmt.Sprintf("%s/%d", pointer, i))...)
+		}
+		return differences
+	}
+
+	if fmt.Sprintf("%T %v", local, local) != fmt.Sprintf("%T %v", published, published) {
+		differences = append(differences, fmt.Sprintf("%s is %v locally and %v published", pointerOrRoot(pointer), local, published))
+	}
+	return differences
+}
+
+func withoutMockServers(document interface{}) interface{} {
+	root, ok := document.(map[string]interface{})
+	if !ok {
+		return document
+	}
+	servers, ok := root["servers"].([]interface{})
+	if !ok {
+		return document
+	}
+
+	var kept []interface{}
+	for _, server := range servers {
+		object, _ := server.(map[string]interface{})
+		if url, _ := object["url"].(string); !strings.HasPrefix(url, swaggerHubMockServer) {
+			kept = append(kept, server)
+		}
+	}
+	if len(kept) == 0 {
+		delete(root, "servers")
+	} else {
+		root["servers"] = kept
+	}
+	return root
+}
+
+// publishedValue is the value of a definition as SwaggerHub stores it: the
+// mock servers it adds left out and, when version isn't empty, the version
+// given with --api-version in info.version.
+func publishedValue(root *yaml.Node, version string) interface{} {
+	value := withoutMockServers(nodeValue(root))
+	if object, ok := value.(map[string]interface{}); ok && version != "" {
+		if info, ok := object["info"].(map[string]interface{}); ok {
+			info["version"] = version
+		}
+	}
+	return value
+}
+
+func pointerOrRoot(pointer string) string {
+	if pointer == "" {
+		return "the document"
+	}
+	return pointer
+}
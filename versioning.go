@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ciTagVariables hold the tag being built on the CI services that set one,
+// GitHub Actions aside as its GITHUB_REF_NAME is a branch too.
+var ciTagVariables = []string{"CI_COMMIT_TAG", "CIRCLE_TAG", "BITBUCKET_TAG", "BUILDKITE_TAG", "TRAVIS_TAG"}
+
+// useVersionFrom applies --version-from: with git the version published is
+// the tag being built, or else the one git describe --tags gives, and it
+// replaces info.version in the definition uploaded.
+func useVersionFrom(versionFrom string, openApiPath string, openApi []byte, options *commandLineOptions) ([]byte, error) {
+	switch versionFrom {
+	case "", "spec":
+		return openApi, nil
+	case "git":
+	default:
+		return nil, fmt.Errorf("unknown version source %s, use spec or git", versionFrom)
+	}
+	if options.ApiVersion != "" {
+		return nil, errors.New("use either --api-version or --version-from git")
+	}
+
+	version, err := gitVersion(openApiPath)
+	if err != nil {
+		return nil, err
+	}
+	if openApi, err = replaceDefinitionVersion(openApiPath, openApi, version); err != nil {
+		return nil, err
+	}
+	options.ApiVersion = version
+	return openApi, nil
+}
+
+// gitVersion is the tag of the CI build, or the description git gives of
+// the commit of the definition, as 1.4.0 on a tag and 1.4.0-3-g2f1c9e0
+// after it. The v of tags as v1.4.0 is left out.
+func gitVersion(openApiPath string) (string, error) {
+	tag := ""
+	if os.Getenv("GITHUB_REF_TYPE") == "tag" {
+		tag = os.Getenv("GITHUB_REF_NAME")
+	}
+	if tag == "" {
+		tag = firstVariable(ciTagVariables)
+	}
+
+	if tag == "" {
+		var err error
+		if tag, err = runGit(openApiPath, "describe", "--tags"); err != nil {
+			return "", fmt.Errorf("can't take the version from git: %v", err)
+		}
+	}
+
+	if len(tag) > 1 && (tag[0] == 'v' || tag[0] == 'V') && tag[1] >= '0' && tag[1] <= '9' {
+		tag = tag[1:]
+	}
+	return tag, nil
+}
+
+// replaceDefinitionVersion writes version in place of info.version, leaving
+// the rest of the definition as it was written. A plain YAML version stays
+// plain when it can, otherwise the new one is double quoted, which JSON and
+// YAML read the same way.
+func replaceDefinitionVersion(openApiPath string, openApi []byte, version string) ([]byte, error) {
+	document, err := parseOpenApiDocument(openApiPath, openApi)
+	if err != nil {
+		return nil, err
+	}
+	node := document.lookup("info", "version")
+	if node == nil || node.Line == 0 {
+		return nil, fmt.Errorf("%s has no info.version to replace", openApiPath)
+	}
+
+	// the position of the node is its line and column, in characters
+	start := 0
+	for line := 1; line < node.Line; line++ {
+		end := bytes.IndexByte(openApi[start:], '\n')
+		if end < 0 {
+			return nil, fmt.Errorf("can't find info.version in %s", openApiPath)
+		}
+		start += end + 1
+	}
+	for column := 1; column < node.Column && start < len(openApi); column++ {
+		_, size := utf8.DecodeRune(openApi[start:])
+		start += size
+	}
+
+	end := start + len(node.Value)
+	switch {
+	case start < len(openApi) && openApi[start] == '"':
+		end = start + 1
+		for end < len(openApi) && openApi[end] != '"' {
+			if openApi[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		end++
+	case start < len(openApi) && openApi[start] == '\'':
+		end = start + 1
+		for end < len(openApi) && (openApi[end] != '\'' || (end+1 < len(openApi) && openApi[end+1] == '\'')) {
+			if openApi[end] == '\'' {
+				end++
+			}
+			end++
+		}
+		end++
+	}
+	if end > len(openApi) {
+		return nil, fmt.Errorf("can't find info.version in %s", openApiPath)
+	}
+
+	value, _ := json.Marshal(version)
+	if node.Style == 0 && plainYamlString(version) {
+		value = []byte(version)
+	}
+	replaced := replaceBytes(openApi, start, end, value)
+	if definitionVersion(replaced) != version {
+		return nil, fmt.Errorf("can't replace info.version in %s", openApiPath)
+	}
+	return replaced, nil
+}
+
+// plainYamlString tells whether YAML reads the value unquoted as the same
+// string, even in a flow mapping, as 1.4.0 but not 2 or 1.0.
+func plainYamlString(value string) bool {
+	var document yaml.Node
+	if strings.ContainsAny(value, "#:{}[],'\"") || yaml.Unmarshal([]byte(value), &document) != nil || len(document.Content) == 0 {
+		return false
+	}
+	scalar := document.Content[0]
+	return scalar.Kind == yaml.ScalarNode && scalar.Style == 0 && scalar.ShortTag() == "!!str" && scalar.Value == value
+}
+
+func replaceBytes(content []byte, start int, end int, replacement []byte) []byte {
+	return append(append(append([]byte{}, content[:start]...), replacement...), content[end:]...)
+}
+
+// runGit runs git in the directory of the definition, or the working one
+// for the standard input and URLs, and returns its output. The error is what
+// git said.
+func runGit(openApiPath string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	command := exec.Command("git", args...)
+	if openApiPath != stdinPath && !isDefinitionUrl(openApiPath) {
+		command.Dir = filepath.Dir(openApiPath)
+	}
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", errors.New(message)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// The commit, number and branch of the build on the CI services swaggergo
+// knows, for --version-suffix.
+var (
+	ciCommitVariables = []string{"GITHUB_SHA", "CI_COMMIT_SHA", "CIRCLE_SHA1", "BITBUCKET_COMMIT", "BUILDKITE_COMMIT", "TRAVIS_COMMIT", "GIT_COMMIT"}
+	ciBuildVariables  = []string{"GITHUB_RUN_NUMBER", "CI_PIPELINE_IID", "CIRCLE_BUILD_NUM", "BITBUCKET_BUILD_NUMBER", "BUILDKITE_BUILD_NUMBER", "TRAVIS_BUILD_NUMBER", "BUILD_NUMBER"}
+	ciBranchVariables = []string{"GITHUB_HEAD_REF", "CI_COMMIT_REF_NAME", "CIRCLE_BRANCH", "BITBUCKET_BRANCH", "BUILDKITE_BRANCH", "TRAVIS_BRANCH", "BRANCH_NAME"}
+)
+
+// useVersionSuffix applies --version-suffix, a template appended to the
+// version published, as +{{.GitSHA}} or -dev.{{.Build}}, so builds of
+// branches don't take the version of a release. Like --version-from git it
+// replaces info.version in the definition uploaded.
+func useVersionSuffix(suffix string, openApiPath string, openApi []byte, options *commandLineOptions) ([]byte, error) {
+	if suffix == "" {
+		return openApi, nil
+	}
+	suffixTemplate, err := template.New("version-suffix").Parse(suffix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version suffix %s: %v", suffix, err)
+	}
+	var rendered strings.Builder
+	if err := suffixTemplate.Execute(&rendered, versionSuffixData{openApiPath}); err != nil {
+		return nil, fmt.Errorf("can't render the version suffix %s: %v", suffix, err)
+	}
+
+	version := publicationVersion(openApi, options) + rendered.String()
+	if openApi, err = replaceDefinitionVersion(openApiPath, openApi, version); err != nil {
+		return nil, err
+	}
+	options.ApiVersion = version
+	return openApi, nil
+}
+
+// versionSuffixData is what the template of --version-suffix can use. The
+// values are looked up only when used, so a suffix without the commit
+// doesn't need git.
+type versionSuffixData struct {
+	openApiPath string
+}
+
+// GitSHA is the short hash of the commit built, or of the working copy.
+func (data versionSuffixData) GitSHA() (string, error) {
+	if commit := firstVariable(ciCommitVariables); commit != "" {
+		if len(commit) > 7 {
+			commit = commit[:7]
+		}
+		return commit, nil
+	}
+	return runGit(data.openApiPath, "rev-parse", "--short=7", "HEAD")
+}
+
+// Build is the number of the CI build.
+func (data versionSuffixData) Build() (string, error) {
+	if build := firstVariable(ciBuildVariables); build != "" {
+		return build, nil
+	}
+	return "", errors.New("there is no build number, it's only known on CI")
+}
+
+// Branch is the branch built, or the one checked out, with dashes for what
+// a version can't have, as feature-orders for feature/orders.
+func (data versionSuffixData) Branch() (string, error) {
+	branch := firstVariable(ciBranchVariables)
+	if branch == "" && os.Getenv("GITHUB_REF_TYPE") == "branch" {
+		branch = os.Getenv("GITHUB_REF_NAME")
+	}
+	if branch == "" {
+		var err error
+		if branch, err = runGit(data.openApiPath, "rev-parse", "--abbrev-ref", "HEAD"); err != nil {
+			return "", err
+		}
+	}
+	return strings.Map(func(char rune) rune {
+		if (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9') || char == '.' || char == '-' {
+			return char
+		}
+		return '-'
+	}, branch), nil
+}
+
+// firstVariable is the value of the first of the variables that is set.
+func firstVariable(names []string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// semanticVersion is a version as major.minor.patch, with an optional
+// pre-release as 1.4.0-rc.1. Build metadata is left out, it doesn't order
+// versions.
+type semanticVersion struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+// parseSemanticVersion reads a version as 1.4.0, v1.4.0 or 1.4.0-rc.1+abc1234.
+// 1.4 is read as 1.4.0.
+func parseSemanticVersion(version string) (semanticVersion, bool) {
+	version = strings.TrimPrefix(strings.TrimPrefix(version, "v"), "V")
+	if i := strings.Index(version, "+"); i >= 0 {
+		version = version[:i]
+	}
+	parsed := semanticVersion{}
+	if i := strings.Index(version, "-"); i >= 0 {
+		parsed.Prerelease = version[i+1:]
+		version = version[:i]
+	}
+	parts := strings.Split(version, ".")
+	if len(parts) == 2 {
+		parts = append(parts, "0")
+	}
+	if len(parts) != 3 {
+		return parsed, false
+	}
+	numbers := []*int{&parsed.Major, &parsed.Minor, &parsed.Patch}
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return parsed, false
+		}
+		*numbers[i] = number
+	}
+	return parsed, true
+}
+
+func (version semanticVersion) String() string {
+	if version.Prerelease != "" {
+		return fmt.Sprintf("%d.%d.%d-%s", version.Major, version.Minor, version.Patch, version.Prerelease)
+	}
+	return fmt.Sprintf("%d.%d.%d", version.Major, version.Minor, version.Patch)
+}
+
+// less orders versions as semver does, a pre-release before its release.
+// Pre-releases of the same version are compared as text.
+func (version semanticVersion) less(other semanticVersion) bool {
+	if version.Major != other.Major {
+		return version.Major < other.Major
+	}
+	if version.Minor != other.Minor {
+		return version.Minor < other.Minor
+	}
+	if version.Patch != other.Patch {
+		return version.Patch < other.Patch
+	}
+	if version.Prerelease == "" || other.Prerelease == "" {
+		return version.Prerelease != "" && other.Prerelease == ""
+	}
+	return version.Prerelease < other.Prerelease
+}
+
+// bump is the next version at the level, patch, minor or major. The release
+// of a pre-release is the next version when it's of that level, as 1.4.0
+// for a minor bump of 1.4.0-rc.1.
+func (version semanticVersion) bump(level string) (semanticVersion, error) {
+	prerelease := version.Prerelease != ""
+	switch level {
+	case "patch":
+		if !prerelease {
+			version.Patch++
+		}
+	case "minor":
+		if !prerelease || version.Patch != 0 {
+			version.Minor++
+			version.Patch = 0
+		}
+	case "major":
+		if !prerelease || version.Minor != 0 || version.Patch != 0 {
+			version.Major++
+			version.Minor, version.Patch = 0, 0
+		}
+	default:
+		return version, fmt.Errorf("unknown level %s, use patch, minor or major", level)
+	}
+	version.Prerelease = ""
+	return version, nil
+}
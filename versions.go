@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type versionsOptions struct {
+	SwaggerHubApi string `flag:"api" env:"SWAGGERHUB_API" required:"true"`
+	Output        string `flag:"output" default:"text"`
+	Format        string `flag:"format"`
+	connectionOptions
+}
+
+// versionsEntry is a version of the API as printed by versions.
+type versionsEntry struct {
+	Version   string `json:"version"`
+	Oas       string `json:"oas"`
+	Lifecycle string `json:"lifecycle"`
+	Default   bool   `json:"default"`
+}
+
+// versionsCommand prints every version of an API with its OAS level,
+// lifecycle and whether it's the default one, as a table or JSON.
+func versionsCommand(args []string) {
+	options := versionsOptions{}
+	parseArgs(&options, args)
+	useFormat(options.Format, &options.Output)
+	useOutput(options.Output, "text", "json")
+	options.useConnection(&options.SwaggerHubApi)
+	if len(strings.Split(options.SwaggerHubApi, "/")) != 2 {
+		exitAndError("api is in the wrong format")
+	}
+
+	versions, err := listVersions(swaggerHubUrls, options.SwaggerHubApi, options.SwaggerHubAccessToken)
+	if err != nil {
+		exitAndError(fmt.Errorf("can't list the versions of %s: %w", options.SwaggerHubApi, err))
+	}
+	entries := []versionsEntry{}
+	for _, version := range versions {
+		lifecycle := "unpublished"
+		if version.Published {
+			lifecycle = "published"
+		}
+		entries = append(entries, versionsEntry{Version: version.Version, Oas: version.Oas, Lifecycle: lifecycle, Default: version.Default})
+	}
+
+	if outputJson {
+		printJson(entries)
+		return
+	}
+	rows := [][]string{{"VERSION", "OAS", "LIFECYCLE", "DEFAULT"}}
+	for _, entry := range entries {
+		rows = append(rows, []string{entry.Version, entry.Oas, entry.Lifecycle, strconv.FormatBool(entry.Default)})
+	}
+	printTable(rows)
+}
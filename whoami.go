@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mijailr/swaggergo/pkg/swaggerhub"
+)
+
+type whoamiOptions struct {
+	SwaggerHubApi string `flag:"api" env:"SWAGGERHUB_API"`
+	Owner         string `flag:"owner"`
+	Output        string `flag:"output" default:"text"`
+	Format        string `flag:"format"`
+	connectionOptions
+}
+
+// whoamiResult is what whoami prints. CanPublish is yes when the owner is
+// one of the organizations, and unknown otherwise, as it can still be the
+// user of the token.
+type whoamiResult struct {
+	Registry      string   `json:"registry"`
+	Token         string   `json:"token"`
+	Organizations []string `json:"organizations"`
+	Owner         string   `json:"owner,omitempty"`
+	CanPublish    string   `json:"canPublish,omitempty"`
+}
+
+// whoamiCommand checks the token with an authenticated call to the User
+// Management API, listing the organizations it belongs to, and tells whether
+// it can publish to the owner of --owner or --api, as a member of the
+// organization. SwaggerHub doesn't tell the role of a member, consumers
+// can't publish.
+func whoamiCommand(args []string) {
+	options := whoamiOptions{}
+	parseArgs(&options, args)
+	useFormat(options.Format, &options.Output)
+	useOutput(options.Output, "text", "json")
+	environment := options.useConnection(&options.SwaggerHubApi)
+	if options.SwaggerHubAccessToken == "" {
+		exitAndError("missing access-token")
+	}
+	if options.Owner == "" && strings.Contains(options.SwaggerHubApi, "/") {
+		options.Owner = strings.Split(options.SwaggerHubApi, "/")[0]
+	}
+	if options.Owner == "" {
+		options.Owner = environment.Owner
+	}
+
+	organizations, err := swaggerHub(swaggerHubUrls, options.SwaggerHubAccessToken).Organizations(runContext)
+	var statusError *swaggerhub.StatusError
+	switch {
+	case errors.As(err, &statusError) && (statusError.StatusCode == http.StatusUnauthorized || statusError.StatusCode == http.StatusForbidden):
+		exitWithCode(exitAuth, fmt.Sprintf("the access token %s isn't valid for %s: %s", maskSecret(options.SwaggerHubAccessToken), swaggerHubUrls[0], statusError.Status))
+	case err != nil:
+		exitAndError(fmt.Errorf("can't check the access token: %w", err))
+	}
+
+	result := whoamiResult{Registry: swaggerHubUrls[0], Token: maskSecret(options.SwaggerHubAccessToken), Organizations: []string{}, Owner: options.Owner}
+	if options.Owner != "" {
+		result.CanPublish = "unknown"
+	}
+	for _, organization := range organizations {
+		result.Organizations = append(result.Organizations, organization.Name)
+		if strings.EqualFold(organization.Name, options.Owner) {
+			result.CanPublish = "yes"
+		}
+	}
+
+	if outputJson {
+		printJson(result)
+		return
+	}
+	fmt.Printf("registry: %s\n", result.Registry)
+	fmt.Printf("token: %s (valid)\n", result.Token)
+	fmt.Printf("organizations: %s\n", strings.Join(result.Organizations, ", "))
+	switch result.CanPublish {
+	case "yes":
+		fmt.Printf("publish to %s: yes, as a member of the organization (not with the consumer role)\n", options.Owner)
+	case "unknown":
+		fmt.Printf("publish to %s: only if it's the user of the token, it isn't one of its organizations\n", options.Owner)
+	}
+}